@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/infratest/infratest/internal/flow"
+	"github.com/infratest/infratest/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var resumeCmd = &cobra.Command{
+	Use:   "resume <checkpoint>",
+	Short: "Resume an interrupted flow run from its checkpoint",
+	Long: `Continue a flow run from the first step that didn't pass.
+Steps that passed are not run again; their results and captured outputs are
+restored from the checkpoint. The flow file must not have changed since the
+checkpoint was written. --var values aren't recorded: pass them again.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return resumeFlow(args[0])
+	},
+}
+
+var cleanupCmd = &cobra.Command{
+	Use:   "cleanup <checkpoint>",
+	Short: "Run the cleanup steps of an interrupted flow run",
+	Long: `Run only the 'when: always' steps of a flow run recorded in a checkpoint,
+against the recorded working directory (and workspace, with isolation),
+even if the flow file changed since the checkpoint was written. --var values aren't recorded: pass them again.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cleanupFlow(args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(resumeCmd)
+	rootCmd.AddCommand(cleanupCmd)
+	addExecutionFlags(resumeCmd)
+	addExecutionFlags(cleanupCmd)
+	resumeCmd.Flags().StringArrayVar(&varOverrides, "var", nil, "Set a terraform variable (name=value), as in the interrupted run; can be repeated")
+	cleanupCmd.Flags().StringArrayVar(&varOverrides, "var", nil, "Set a terraform variable (name=value), as in the interrupted run; can be repeated")
+}
+
+// restoreExecutor loads a checkpoint and returns an executor for the recorded
+// run with its progress restored and checkpoints written back to path. For
+// cleanup, the run uses the recorded working directory even if the flow changed.
+func restoreExecutor(path string, cleanup bool) (*flow.Executor, error) {
+	// Early terraform binary check
+	if err := checkTerraformBinary(); err != nil {
+		return nil, err
+	}
+
+	// Check if output is a TTY, disable colors if not
+	if !isTerminal(os.Stdout) {
+		ui.DisableColors()
+	}
+
+	cp, err := flow.LoadCheckpoint(path)
+	if err != nil {
+		return nil, err
+	}
+	var f *flow.Flow
+	if cleanup {
+		f, err = cp.CleanupFlow()
+	} else {
+		f, err = cp.Flow()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := applyVarOverrides(f); err != nil {
+		return nil, err
+	}
+
+	ui.PrintInfo(fmt.Sprintf("📋 Flow: %s", f.Name))
+	ui.PrintInfo(fmt.Sprintf("📁 Working directory: %s", f.WorkingDir))
+	ui.PrintInfo(fmt.Sprintf("💾 Checkpoint from %s: %d step result(s)", cp.Updated.Format("2006-01-02 15:04:05"), len(cp.Results)))
+	fmt.Println()
+
+	// Setup LocalStack environment if enabled
+	if localstack {
+		endpoint := localstackEndpoint
+		if f.Environment.Endpoint != "" {
+			endpoint = f.Environment.Endpoint
+			ui.PrintInfo(fmt.Sprintf("🔧 Using endpoint from YAML: %s", endpoint))
+		}
+		if err := setupLocalStack(endpoint); err != nil {
+			return nil, err
+		}
+	}
+
+	executor, err := flow.NewExecutor(f, debug)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create executor: %w", err)
+	}
+	executor.Restore(cp)
+	if err := executor.EnableCheckpoint(path); err != nil {
+		return nil, err
+	}
+	return executor, nil
+}
+
+// resumeFlow continues the run recorded in a checkpoint
+func resumeFlow(path string) error {
+	executor, err := restoreExecutor(path, false)
+	if err != nil {
+		return err
+	}
+	_, err = runExecutor(executor)
+	return err
+}
+
+// cleanupFlow runs the 'when: always' steps of the run recorded in a checkpoint
+func cleanupFlow(path string) error {
+	executor, err := restoreExecutor(path, true)
+	if err != nil {
+		return err
+	}
+	executor.SetKeepWorkspace(keepWorkspace)
+
+	cleanupMgr := flow.NewCleanupManager(executor, cleanupTimeout, debug)
+	cleanupMgr.Start()
+	defer cleanupMgr.Stop()
+
+	if err := cleanupMgr.RunCleanup(); err != nil {
+		// Manual destroy instructions are already shown in RunCleanup
+		return err
+	}
+	if err := executor.ReleaseWorkspace(context.Background()); err != nil {
+		return err
+	}
+	removeCheckpoint(executor)
+
+	ui.PrintSuccess("\n✅ Cleanup completed")
+	return nil
+}
+
+// removeCheckpoint deletes a checkpoint that's no longer needed
+func removeCheckpoint(executor *flow.Executor) {
+	if err := executor.RemoveCheckpoint(); err != nil {
+		ui.PrintWarning(fmt.Sprintf("⚠️  Failed to remove checkpoint: %v", err))
+	}
+}
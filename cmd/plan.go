@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/infratest/infratest/internal/flow"
+	"github.com/infratest/infratest/internal/flow/interpolator"
+	"github.com/infratest/infratest/internal/terraform"
+	"github.com/infratest/infratest/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var planCmd = &cobra.Command{
+	Use:   "plan [flow.yaml]",
+	Short: "Preview a flow without running it",
+	Long: `Parse a flow and print what a run would do, without touching infrastructure:
+the terraform commands, URLs and inventory patterns of every step, the
+execution order and the cleanup steps. Interpolations are resolved from the
+current terraform outputs where available; placeholders that can't be
+resolved yet are highlighted.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return planFlow(args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(planCmd)
+	planCmd.Flags().StringArrayVar(&varOverrides, "var", nil, "Set a terraform variable (name=value), overriding the flow; can be repeated")
+	planCmd.Flags().BoolVar(&strict, "strict", false, "Reject unknown fields and invalid steps (see validate)")
+}
+
+// planFlow prints the plan of every run of a flow
+func planFlow(flowPath string) error {
+	// Check if output is a TTY, disable colors if not
+	if !isTerminal(os.Stdout) {
+		ui.DisableColors()
+	}
+
+	f, err := parseFlowFile(flowPath)
+	if err != nil {
+		return fmt.Errorf("failed to parse flow: %w", err)
+	}
+	if err := applyVarOverrides(f); err != nil {
+		return err
+	}
+
+	ui.PrintInfo(fmt.Sprintf("📋 Flow: %s", f.Name))
+	if f.Description != "" {
+		ui.PrintInfo(fmt.Sprintf("   %s", f.Description))
+	}
+	ui.PrintInfo(fmt.Sprintf("📁 Working directory: %s", f.WorkingDir))
+	outputs := planOutputs(f)
+
+	for _, run := range f.ExpandMatrix() {
+		fmt.Println()
+		if run.MatrixValues != nil {
+			ui.PrintInfo(fmt.Sprintf("🧮 Matrix run: %s (%s)", run.Name, run.MatrixLabel()))
+		}
+		steps, err := flow.Plan(run, outputs)
+		if err != nil {
+			return err
+		}
+		showPlan(steps, run.MaxParallel > 1)
+	}
+	return nil
+}
+
+// planOutputs reads the current terraform outputs of the flow, if there are any
+func planOutputs(f *flow.Flow) map[string]interface{} {
+	if f.Isolation == flow.IsolationWorkspace {
+		ui.PrintInfo("🔌 Terraform outputs: none yet (each run gets a new workspace)")
+		return nil
+	}
+	if err := checkTerraformBinary(); err != nil {
+		ui.PrintWarning("⚠️  Terraform outputs: unavailable (terraform not found)")
+		return nil
+	}
+	outputs, err := terraform.GetOutputsWithContext(context.Background(), f.WorkingDir)
+	if err != nil {
+		ui.PrintWarning(fmt.Sprintf("⚠️  Terraform outputs: unavailable (%v)", err))
+		return nil
+	}
+	ui.PrintInfo(fmt.Sprintf("🔌 Terraform outputs: %d available", len(outputs)))
+	return outputs
+}
+
+// showPlan prints the steps of a plan in execution order. Steps sharing a
+// stage are marked [parallel] if the flow runs steps concurrently.
+func showPlan(steps []flow.StepPlan, parallel bool) {
+	// Steps run by stage; within a stage they keep their order in the file
+	ordered := append([]flow.StepPlan(nil), steps...)
+	sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].Stage < ordered[j].Stage })
+	perStage := make(map[int]int)
+	for _, step := range ordered {
+		perStage[step.Stage]++
+	}
+
+	color.New(color.FgCyan, color.Bold).Printf("Execution order:\n")
+	var cleanup []string
+	unresolved, runtime := 0, 0
+	for _, step := range ordered {
+		color.New(color.FgWhite, color.Bold).Printf("  %d. %s", step.Stage, step.Name)
+		color.New(color.FgHiBlack).Printf(" (%s)", step.Type)
+		if parallel && perStage[step.Stage] > 1 {
+			color.New(color.FgHiBlack).Printf(" [parallel]")
+		}
+		if step.Cleanup {
+			cleanup = append(cleanup, step.Name)
+			color.New(color.FgMagenta).Printf(" [cleanup]")
+		} else if step.When != "" {
+			color.New(color.FgHiBlack).Printf(" when: %s", step.When)
+		}
+		fmt.Println()
+
+		for _, cmd := range step.Commands {
+			fmt.Printf("       $ %s\n", highlightPlaceholders(cmd))
+		}
+		names := make([]string, 0, len(step.Variables))
+		for name := range step.Variables {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if len(names) > 0 {
+			fmt.Printf("       %s:\n", terraform.GeneratedVarFile)
+		}
+		for _, name := range names {
+			fmt.Printf("         %q: %s\n", name, highlightPlaceholders(step.Variables[name]))
+		}
+		if step.URL != "" {
+			fmt.Printf("       GET %s\n", highlightPlaceholders(step.URL))
+		}
+		if step.Address != "" {
+			fmt.Printf("       %s %s\n", step.Type, highlightPlaceholders(step.Address))
+		}
+		if step.Condition != "" {
+			fmt.Printf("       until %s\n", highlightPlaceholders(step.Condition))
+		}
+		for _, pattern := range step.Patterns {
+			fmt.Printf("       resource %s\n", pattern)
+		}
+		for _, path := range step.Outputs {
+			fmt.Printf("       output %s\n", path)
+		}
+		unresolved += len(step.Unresolved)
+		runtime += len(step.Runtime)
+	}
+
+	fmt.Println()
+	if len(cleanup) > 0 {
+		ui.PrintInfo(fmt.Sprintf("🧹 Cleanup steps: %s", strings.Join(cleanup, ", ")))
+	} else {
+		ui.PrintInfo("🧹 Cleanup steps: none")
+	}
+	if runtime > 0 {
+		ui.PrintInfo(fmt.Sprintf("ℹ️  %d value(s) captured at run time:", runtime))
+		for _, step := range steps {
+			for _, placeholder := range step.Runtime {
+				ui.PrintInfo(fmt.Sprintf("   %s: %s", step.Name, placeholder))
+			}
+		}
+	}
+	if unresolved == 0 {
+		ui.PrintSuccess("✅ All placeholders resolved")
+		return
+	}
+	ui.PrintWarning(fmt.Sprintf("⚠️  %d unresolved placeholder(s):", unresolved))
+	for _, step := range steps {
+		for _, placeholder := range step.Unresolved {
+			ui.PrintWarning(fmt.Sprintf("   %s: %s", step.Name, placeholder))
+		}
+	}
+}
+
+// highlightPlaceholders colors the ${...} placeholders left in a string
+func highlightPlaceholders(s string) string {
+	for _, placeholder := range interpolator.Unresolved(s) {
+		s = strings.ReplaceAll(s, placeholder, color.New(color.FgYellow, color.Bold).Sprint(placeholder))
+	}
+	return s
+}
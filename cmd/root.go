@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os"
@@ -22,6 +23,9 @@ var (
 	localstack     bool
 	localstackEndpoint string
 	cleanupTimeout time.Duration
+	varOverrides   []string
+	keepWorkspace  bool
+	strict         bool
 )
 
 var rootCmd = &cobra.Command{
@@ -32,22 +36,37 @@ that validates infrastructure deployments using Terraform.`,
 }
 
 var runCmd = &cobra.Command{
-	Use:   "run [flow.yaml]",
+	Use:   "run [flow.yaml | directory | glob]...",
 	Short: "Run an infrastructure test flow",
-	Long:  "Execute a test flow defined in a YAML file",
-	Args:  cobra.ExactArgs(1),
+	Long: `Execute a test flow defined in a YAML file.
+
+Given a directory, a glob pattern or several files, every flow found is run
+as a suite with an aggregated summary and combined reports.`,
+	Args: cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		flowPath := args[0]
-		return executeFlow(flowPath)
+		if len(args) == 1 && isFile(args[0]) {
+			return executeFlow(args[0])
+		}
+		return runSuite(args)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(runCmd)
-	runCmd.Flags().BoolVar(&debug, "debug", false, "Enable debug output")
-	runCmd.Flags().BoolVar(&localstack, "localstack", false, "Use LocalStack for AWS (development)")
-	runCmd.Flags().StringVar(&localstackEndpoint, "localstack-endpoint", "http://localhost:4566", "LocalStack endpoint URL (only used with --localstack)")
-	runCmd.Flags().DurationVar(&cleanupTimeout, "cleanup-timeout", 300*time.Second, "Timeout for cleanup operations")
+	addExecutionFlags(runCmd)
+	runCmd.Flags().BoolVar(&strict, "strict", false, "Reject unknown fields and invalid steps before running (see validate)")
+	runCmd.Flags().StringArrayVar(&varOverrides, "var", nil, "Set a terraform variable (name=value), overriding the flow; can be repeated")
+	runCmd.Flags().IntVar(&concurrency, "concurrency", 1, "Number of flows to run at once (suites only)")
+	runCmd.Flags().StringVar(&suiteReport, "suite-report", "", "Path of the combined suite report (default: ./reports/suite-<timestamp>.html)")
+}
+
+// addExecutionFlags registers the flags shared by commands that execute flow steps
+func addExecutionFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&debug, "debug", false, "Enable debug output")
+	cmd.Flags().BoolVar(&localstack, "localstack", false, "Use LocalStack for AWS (development)")
+	cmd.Flags().StringVar(&localstackEndpoint, "localstack-endpoint", "http://localhost:4566", "LocalStack endpoint URL (only used with --localstack)")
+	cmd.Flags().DurationVar(&cleanupTimeout, "cleanup-timeout", 300*time.Second, "Timeout for cleanup operations")
+	cmd.Flags().BoolVar(&keepWorkspace, "keep-workspace", false, "Keep the isolated terraform workspace after the run (isolation: workspace)")
 }
 
 func Execute() error {
@@ -66,17 +85,25 @@ func executeFlow(flowPath string) error {
 	}
 
 	// Parse flow
-	f, err := flow.ParseFlow(flowPath)
+	f, err := parseFlowFile(flowPath)
 	if err != nil {
 		return fmt.Errorf("failed to parse flow: %w", err)
 	}
 
+	if err := applyVarOverrides(f); err != nil {
+		return err
+	}
+
 	ui.PrintInfo(fmt.Sprintf("📋 Flow: %s", f.Name))
 	if f.Description != "" {
 		ui.PrintInfo(fmt.Sprintf("   %s", f.Description))
 	}
 	ui.PrintInfo(fmt.Sprintf("📁 Working directory: %s", f.WorkingDir))
 	ui.PrintInfo(fmt.Sprintf("📊 Steps: %d", len(f.Steps)))
+	runs := f.ExpandMatrix()
+	if f.Matrix != nil {
+		ui.PrintInfo(fmt.Sprintf("🧮 Matrix: %d runs", len(runs)))
+	}
 	fmt.Println()
 
 	// Setup LocalStack environment if enabled
@@ -87,16 +114,9 @@ func executeFlow(flowPath string) error {
 			endpoint = f.Environment.Endpoint
 			ui.PrintInfo(fmt.Sprintf("🔧 Using endpoint from YAML: %s", endpoint))
 		}
-		
-		// Check if LocalStack is reachable
-		if err := checkLocalStackAvailability(endpoint); err != nil {
-			ui.PrintWarning(fmt.Sprintf("⚠️  LocalStack not detected at %s", endpoint))
-			showLocalStackStartInstructions(endpoint)
-			return fmt.Errorf("LocalStack not available: %w", err)
+		if err := setupLocalStack(endpoint); err != nil {
+			return err
 		}
-		
-		setupLocalStackEnv(endpoint)
-		ui.PrintInfo(fmt.Sprintf("🔧 LocalStack mode enabled (endpoint: %s)", endpoint))
 	}
 	
 	// Show debug information at startup
@@ -108,6 +128,9 @@ func executeFlow(flowPath string) error {
 		color.New(color.FgCyan).Printf("Flow File: %s\n", flowPath)
 		color.New(color.FgCyan).Printf("Working Directory: %s\n", f.WorkingDir)
 		color.New(color.FgCyan).Printf("Total Steps: %d\n", len(f.Steps))
+		if f.Matrix != nil {
+			color.New(color.FgCyan).Printf("Matrix Runs: %d\n", len(runs))
+		}
 		if localstack {
 			color.New(color.FgCyan).Printf("LocalStack: enabled\n")
 		}
@@ -116,12 +139,107 @@ func executeFlow(flowPath string) error {
 		fmt.Println()
 	}
 
+	if f.Matrix == nil {
+		_, err := runFlow(f)
+		return err
+	}
+	return runMatrix(runs)
+}
+
+// parseFlowFile parses a flow file, with strict checks if --strict is set
+func parseFlowFile(path string) (*flow.Flow, error) {
+	if strict {
+		return flow.ParseFlowStrict(path)
+	}
+	return flow.ParseFlow(path)
+}
+
+// applyVarOverrides sets the --var flags on a parsed flow
+func applyVarOverrides(f *flow.Flow) error {
+	for _, v := range varOverrides {
+		name, value, err := flow.ParseVarOverride(v)
+		if err != nil {
+			return err
+		}
+		if f.VarOverrides == nil {
+			f.VarOverrides = make(map[string]interface{})
+		}
+		f.VarOverrides[name] = value
+	}
+	return nil
+}
+
+// setupLocalStack checks that LocalStack is reachable and points AWS at it
+func setupLocalStack(endpoint string) error {
+	// Check if LocalStack is reachable
+	if err := checkLocalStackAvailability(endpoint); err != nil {
+		ui.PrintWarning(fmt.Sprintf("⚠️  LocalStack not detected at %s", endpoint))
+		showLocalStackStartInstructions(endpoint)
+		return fmt.Errorf("LocalStack not available: %w", err)
+	}
+
+	setupLocalStackEnv(endpoint)
+	ui.PrintInfo(fmt.Sprintf("🔧 LocalStack mode enabled (endpoint: %s)", endpoint))
+	return nil
+}
+
+// isFile reports whether path is an existing regular file
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// runMatrix executes each matrix run in turn and summarizes the result of every cell.
+// Runs share the working directory, so they are not executed in parallel.
+func runMatrix(runs []*flow.Flow) error {
+	errs := make([]error, len(runs))
+	for i, run := range runs {
+		fmt.Println()
+		ui.PrintInfo(fmt.Sprintf("🧮 Matrix run %d/%d: %s (%s)", i+1, len(runs), run.Name, run.MatrixLabel()))
+		fmt.Println()
+		_, errs[i] = runFlow(run)
+	}
+
+	failed := showMatrixSummary(runs, errs)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d matrix runs failed", failed, len(runs))
+	}
+	return nil
+}
+
+// runFlow executes a single flow (or matrix run) with cleanup and reporting
+func runFlow(f *flow.Flow) ([]flow.StepResult, error) {
 	// Create executor
 	executor, err := flow.NewExecutor(f, debug)
 	if err != nil {
-		return fmt.Errorf("failed to create executor: %w", err)
+		return nil, fmt.Errorf("failed to create executor: %w", err)
+	}
+
+	// Save progress after every step, so a killed run can be resumed
+	if err := executor.EnableCheckpoint(flow.CheckpointPath(f, executor.Workspace())); err != nil {
+		ui.PrintWarning(fmt.Sprintf("⚠️  Checkpoints disabled: %v", err))
 	}
 
+	return runExecutor(executor)
+}
+
+// runExecutor runs a prepared executor with cleanup, reporting and checkpoint handling
+func runExecutor(executor *flow.Executor) ([]flow.StepResult, error) {
+	executor.SetKeepWorkspace(keepWorkspace)
+	if path := executor.CheckpointFile(); path != "" {
+		ui.PrintInfo(fmt.Sprintf("💾 Checkpoint: %s", path))
+	}
+	if ws := executor.Workspace(); ws != "" {
+		ui.PrintInfo(fmt.Sprintf("🗂  Workspace: %s", ws))
+	}
+
+	// Release the isolated workspace once cleanup is done (deferred first, so it runs last)
+	defer func() {
+		if err := executor.ReleaseWorkspace(context.Background()); err != nil {
+			ui.PrintWarning(fmt.Sprintf("⚠️  %v", err))
+		}
+	}()
+
 	// Setup cleanup manager with panic recovery
 	cleanupMgr := flow.NewCleanupManager(executor, cleanupTimeout, debug)
 	cleanupMgr.Start()
@@ -154,18 +272,48 @@ func executeFlow(flowPath string) error {
 			// Manual destroy instructions are already shown in RunCleanup
 			// Just return the error
 		}
+
+		// Keep the checkpoint unless cleanup tore the run down
+		if executor.CleanedUp() {
+			removeCheckpoint(executor)
+		} else if path := executor.CheckpointFile(); path != "" {
+			ui.PrintInfo(fmt.Sprintf("💾 Checkpoint kept; continue with 'infratest resume %s' or clean up with 'infratest cleanup %s'", path, path))
+		}
 		
-		return err
+		return executor.GetResults(), err
 	}
+	removeCheckpoint(executor)
 
 	// Generate report
 	ui.PrintInfo("\n📄 Generating reports...")
 	if err := generateReport(executor); err != nil {
-		return fmt.Errorf("failed to generate report: %w", err)
+		return executor.GetResults(), fmt.Errorf("failed to generate report: %w", err)
 	}
 
 	ui.PrintSuccess("\n✅ Flow executed successfully!")
-	return nil
+	return executor.GetResults(), nil
+}
+
+// showMatrixSummary prints the pass/fail result of each matrix cell and returns the number of failed cells
+func showMatrixSummary(runs []*flow.Flow, errs []error) int {
+	failed := 0
+	fmt.Println()
+	color.New(color.FgCyan, color.Bold).Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	color.New(color.FgCyan, color.Bold).Printf("  MATRIX SUMMARY\n")
+	color.New(color.FgCyan, color.Bold).Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	for i, run := range runs {
+		if errs[i] != nil {
+			failed++
+			color.New(color.FgRed, color.Bold).Printf("  ✗ FAIL %s", run.Name)
+			color.New(color.FgHiBlack).Printf(" [%s]: %v\n", run.MatrixLabel(), errs[i])
+		} else {
+			color.New(color.FgGreen).Printf("  ✓ PASS %s", run.Name)
+			color.New(color.FgHiBlack).Printf(" [%s]\n", run.MatrixLabel())
+		}
+	}
+	fmt.Println()
+	color.New(color.FgCyan).Printf("%d passed, %d failed\n", len(runs)-failed, failed)
+	return failed
 }
 
 // checkTerraformBinary checks if terraform is available in PATH
@@ -227,7 +375,15 @@ func showErrorDetails(executor *flow.Executor, err error) {
 			}
 		}
 		
-		if result != nil && result.Success {
+		if result != nil && result.Skipped {
+			// Show skipped step with the reason it didn't run
+			color.New(color.FgYellow).Printf("  ⊘ Step %d/%d: %s", stepNum, len(f.Steps), step.Name)
+			color.New(color.FgHiBlack).Printf(" [skipped: %s]\n", result.SkipReason)
+		} else if result != nil && result.TimedOut {
+			// Show timed-out step separately from ordinary failures
+			color.New(color.FgRed, color.Bold).Printf("  ⏱ Step %d/%d: %s", stepNum, len(f.Steps), step.Name)
+			color.New(color.FgHiBlack).Printf(" [timed out after %s]\n", result.Duration.Round(time.Second))
+		} else if result != nil && result.Success {
 			// Show successful step with green checkmark
 			color.New(color.FgGreen).Printf("  ✓ Step %d/%d: %s", stepNum, len(f.Steps), step.Name)
 			color.New(color.FgHiBlack).Printf(" [%s]\n", result.Duration.Round(time.Second))
@@ -250,10 +406,18 @@ func showErrorDetails(executor *flow.Executor, err error) {
 	
 	// Show failing step details
 	for _, r := range results {
-		if !r.Success {
-			color.New(color.FgRed, color.Bold).Printf("Failed Step: %s\n", r.StepName)
+		if !r.Success && !r.Skipped {
+			label := "Failed Step"
+			if r.TimedOut {
+				label = "Timed Out Step"
+			}
+			color.New(color.FgRed, color.Bold).Printf("%s: %s\n", label, r.StepName)
 			color.New(color.FgYellow).Printf("Type: %s\n", r.StepType)
 			
+			if len(r.Attempts) > 1 {
+				color.New(color.FgYellow).Printf("Attempts: %d\n", len(r.Attempts))
+			}
+
 			if r.Error != nil {
 				fmt.Println()
 				color.New(color.FgRed).Printf("Error: %v\n", r.Error)
@@ -376,7 +540,13 @@ func generateReport(executor *flow.Executor) error {
 	}
 
 	// Interpolate report output path
-	outputPath := interpolator.Interpolate(f.Reporting.Output, outputs)
+	outputPath := interpolator.InterpolateScope(f.Reporting.Output, interpolator.Scope{"output": outputs, "matrix": f.MatrixValues})
+
+	// Give each matrix run its own report unless the path already varies per run
+	if f.MatrixValues != nil && !strings.Contains(f.Reporting.Output, "${name}") && !strings.Contains(f.Reporting.Output, "${matrix.") {
+		ext := filepath.Ext(outputPath)
+		outputPath = strings.TrimSuffix(outputPath, ext) + "-" + f.Name + ext
+	}
 	
 	// Replace ${name} with flow name
 	outputPath = strings.ReplaceAll(outputPath, "${name}", f.Name)
@@ -399,6 +569,36 @@ func generateReport(executor *flow.Executor) error {
 		Name:        f.Name,
 		Description: f.Description,
 		WorkingDir:  f.WorkingDir,
+		Matrix:      f.MatrixLabel(),
+	}
+
+	stepResults := stepResultInfos(f, results)
+
+	for _, format := range f.Reporting.Formats {
+		var err error
+		switch format {
+		case "html":
+			err = reporting.GenerateHTMLReport(flowInfo, stepResults, outputPath, outputs)
+		case "json":
+			jsonPath := strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + ".json"
+			err = reporting.GenerateJSONReport(flowInfo, stepResults, jsonPath)
+		default:
+			return fmt.Errorf("unsupported report format: %s", format)
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to generate %s report: %w", format, err)
+		}
+	}
+
+	return nil
+}
+
+// stepResultInfos converts step results to their reporting form, masking sensitive captures
+func stepResultInfos(f *flow.Flow, results []flow.StepResult) []reporting.StepResultInfo {
+	stepConfigs := make(map[string]flow.Step, len(f.Steps))
+	for _, s := range f.Steps {
+		stepConfigs[s.Name] = s
 	}
 
 	stepResults := make([]reporting.StepResultInfo, len(results))
@@ -410,6 +610,15 @@ func generateReport(executor *flow.Executor) error {
 				ID:   res.ID,
 			}
 		}
+		attempts := make([]reporting.AttemptInfo, len(r.Attempts))
+		for j, a := range r.Attempts {
+			attempts[j] = reporting.AttemptInfo{
+				Number:   a.Number,
+				Success:  a.Success,
+				Error:    a.Error,
+				Duration: a.Duration,
+			}
+		}
 		stepResults[i] = reporting.StepResultInfo{
 			StepName:   r.StepName,
 			StepType:   r.StepType,
@@ -419,27 +628,16 @@ func generateReport(executor *flow.Executor) error {
 			Duration:   r.Duration,
 			Resources:  resources,
 			HTTPStatus: r.HTTPStatus,
+			Latency:    r.Latency,
+			Records:    r.Records,
+			Attempts:   attempts,
+			TimedOut:   r.TimedOut,
+			Skipped:    r.Skipped,
+			SkipReason: r.SkipReason,
+			Captured:   stepConfigs[r.StepName].MaskCaptured(r.Captured),
 		}
 	}
-
-	for _, format := range f.Reporting.Formats {
-		var err error
-		switch format {
-		case "html":
-			err = reporting.GenerateHTMLReport(flowInfo, stepResults, outputPath, outputs)
-		case "json":
-			jsonPath := strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + ".json"
-			err = reporting.GenerateJSONReport(flowInfo, stepResults, jsonPath)
-		default:
-			return fmt.Errorf("unsupported report format: %s", format)
-		}
-
-		if err != nil {
-			return fmt.Errorf("failed to generate %s report: %w", format, err)
-		}
-	}
-
-	return nil
+	return stepResults
 }
 
 // extractModuleName extracts the module name from the working directory path
@@ -0,0 +1,223 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/infratest/infratest/internal/flow"
+	"github.com/infratest/infratest/internal/reporting"
+	"github.com/infratest/infratest/internal/ui"
+)
+
+var (
+	concurrency int
+	suiteReport string
+)
+
+// suiteRun is one run of a suite. A matrix flow contributes a run per cell.
+type suiteRun struct {
+	path     string
+	flow     *flow.Flow // nil if the flow file couldn't be parsed
+	results  []flow.StepResult
+	duration time.Duration
+	err      error
+}
+
+// runSuite runs every flow found in the given files, directories and globs,
+// then prints an aggregated summary and writes combined reports
+func runSuite(args []string) error {
+	// Early terraform binary check
+	if err := checkTerraformBinary(); err != nil {
+		return err
+	}
+
+	// Check if output is a TTY, disable colors if not
+	if !isTerminal(os.Stdout) {
+		ui.DisableColors()
+	}
+
+	paths, err := flow.Discover(args)
+	if err != nil {
+		return err
+	}
+
+	// Parse every flow up front; a flow that doesn't parse counts as a failed run
+	var runs []*suiteRun
+	endpoints := make(map[string]bool)
+	for _, path := range paths {
+		f, err := parseFlowFile(path)
+		if err == nil {
+			err = applyVarOverrides(f)
+		}
+		if err != nil {
+			runs = append(runs, &suiteRun{path: path, err: fmt.Errorf("failed to parse flow: %w", err)})
+			continue
+		}
+		if f.Environment.Endpoint != "" {
+			endpoints[f.Environment.Endpoint] = true
+		}
+		for _, run := range f.ExpandMatrix() {
+			runs = append(runs, &suiteRun{path: path, flow: run})
+		}
+	}
+
+	ui.PrintInfo(fmt.Sprintf("📚 Suite: %d flow file(s), %d run(s)", len(paths), len(runs)))
+	for _, path := range paths {
+		ui.PrintInfo(fmt.Sprintf("   %s", path))
+	}
+	fmt.Println()
+
+	// Setup LocalStack environment if enabled; it applies to the whole process
+	if localstack {
+		endpoint := localstackEndpoint
+		if len(endpoints) > 1 {
+			return fmt.Errorf("flows in the suite use different LocalStack endpoints")
+		}
+		for e := range endpoints {
+			endpoint = e
+			ui.PrintInfo(fmt.Sprintf("🔧 Using endpoint from YAML: %s", endpoint))
+		}
+		if err := setupLocalStack(endpoint); err != nil {
+			return err
+		}
+	}
+
+	executeSuite(runs)
+
+	failed := showSuiteSummary(runs)
+	if err := generateSuiteReport(runs); err != nil {
+		ui.PrintError("Failed to generate suite report: %v", err)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d runs failed", failed, len(runs))
+	}
+	return nil
+}
+
+// executeSuite runs the suite with at most --concurrency runs at a time.
+// Runs that share a working directory without workspace isolation would
+// share state, so they never overlap.
+func executeSuite(runs []*suiteRun) {
+	limit := concurrency
+	if limit < 1 {
+		limit = 1
+	}
+	slots := make(chan struct{}, limit)
+
+	var dirLocks sync.Map // working dir -> *sync.Mutex
+	var wg sync.WaitGroup
+	for i, run := range runs {
+		if run.flow == nil {
+			continue
+		}
+
+		slots <- struct{}{}
+		if flow.Interrupted() {
+			<-slots
+			run.err = fmt.Errorf("not run: interrupted")
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, run *suiteRun) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			if run.flow.Isolation != flow.IsolationWorkspace {
+				lock, _ := dirLocks.LoadOrStore(run.flow.WorkingDir, &sync.Mutex{})
+				lock.(*sync.Mutex).Lock()
+				defer lock.(*sync.Mutex).Unlock()
+			}
+
+			fmt.Println()
+			ui.PrintInfo(fmt.Sprintf("▶ Run %d/%d: %s (%s)", i+1, len(runs), run.flow.Name, run.path))
+			start := time.Now()
+			run.results, run.err = runFlow(run.flow)
+			run.duration = time.Since(start)
+		}(i, run)
+	}
+	wg.Wait()
+}
+
+// runName returns the name of a run for summaries and reports
+func (r *suiteRun) runName() string {
+	if r.flow == nil {
+		return filepath.Base(r.path)
+	}
+	return r.flow.Name
+}
+
+// showSuiteSummary prints the pass/fail result of every run and returns the number of failed runs
+func showSuiteSummary(runs []*suiteRun) int {
+	failed := 0
+	var total time.Duration
+	fmt.Println()
+	color.New(color.FgCyan, color.Bold).Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	color.New(color.FgCyan, color.Bold).Printf("  SUITE SUMMARY\n")
+	color.New(color.FgCyan, color.Bold).Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	for _, run := range runs {
+		total += run.duration
+		label := run.path
+		if run.flow != nil && run.flow.MatrixValues != nil {
+			label += ", " + run.flow.MatrixLabel()
+		}
+		if run.err != nil {
+			failed++
+			color.New(color.FgRed, color.Bold).Printf("  ✗ FAIL %s", run.runName())
+			color.New(color.FgHiBlack).Printf(" [%s] %s: %v\n", label, run.duration.Round(time.Second), run.err)
+		} else {
+			color.New(color.FgGreen).Printf("  ✓ PASS %s", run.runName())
+			color.New(color.FgHiBlack).Printf(" [%s] %s\n", label, run.duration.Round(time.Second))
+		}
+	}
+	fmt.Println()
+	color.New(color.FgCyan).Printf("%d passed, %d failed (%s total)\n", len(runs)-failed, failed, total.Round(time.Second))
+	return failed
+}
+
+// generateSuiteReport writes the combined HTML and JSON reports of a suite
+func generateSuiteReport(runs []*suiteRun) error {
+	outputPath := suiteReport
+	if outputPath == "" {
+		outputPath = filepath.Join("reports", "suite-"+time.Now().Format("20060102-150405")+".html")
+	}
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return fmt.Errorf("failed to create report directory: %w", err)
+	}
+
+	infos := make([]reporting.SuiteRunInfo, len(runs))
+	for i, run := range runs {
+		info := reporting.SuiteRunInfo{
+			Flow:     reporting.FlowInfo{Name: run.runName()},
+			Path:     run.path,
+			Success:  run.err == nil,
+			Error:    run.err,
+			Duration: run.duration,
+		}
+		if run.flow != nil {
+			info.Flow = reporting.FlowInfo{
+				Name:        run.flow.Name,
+				Description: run.flow.Description,
+				WorkingDir:  run.flow.WorkingDir,
+				Matrix:      run.flow.MatrixLabel(),
+			}
+			info.Steps = stepResultInfos(run.flow, run.results)
+		}
+		infos[i] = info
+	}
+
+	jsonPath := strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + ".json"
+	if err := reporting.GenerateSuiteHTMLReport(infos, outputPath); err != nil {
+		return fmt.Errorf("failed to generate html report: %w", err)
+	}
+	if err := reporting.GenerateSuiteJSONReport(infos, jsonPath); err != nil {
+		return fmt.Errorf("failed to generate json report: %w", err)
+	}
+	ui.PrintInfo(fmt.Sprintf("📄 Suite reports: %s, %s", outputPath, jsonPath))
+	return nil
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/infratest/infratest/internal/flow"
+	"github.com/infratest/infratest/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate [flow.yaml | directory | glob]...",
+	Short: "Check flow files without running them",
+	Long: `Parse flow files in strict mode and report every problem with its line and
+column: unknown or misspelled fields, values of the wrong type, steps missing
+the fields their type requires, 'after' references to unknown or later steps,
+duplicate step names and durations that don't parse.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return validateFlows(args)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+}
+
+// validateFlows strictly parses every flow found in the arguments
+func validateFlows(args []string) error {
+	// Check if output is a TTY, disable colors if not
+	if !isTerminal(os.Stdout) {
+		ui.DisableColors()
+	}
+
+	paths, err := flow.Discover(args)
+	if err != nil {
+		return err
+	}
+
+	invalid := 0
+	for _, path := range paths {
+		_, err := flow.ParseFlowStrict(path)
+		if err == nil {
+			color.New(color.FgGreen).Printf("  ✓ %s\n", path)
+			continue
+		}
+
+		invalid++
+		color.New(color.FgRed, color.Bold).Printf("  ✗ %s\n", path)
+		var errs flow.ValidationErrors
+		if !errors.As(err, &errs) {
+			color.New(color.FgRed).Printf("      %v\n", err)
+			continue
+		}
+		for _, e := range errs {
+			color.New(color.FgRed).Printf("      %s:%d:%d: ", e.File, e.Line, e.Column)
+			fmt.Printf("%v\n", e.Err)
+		}
+	}
+
+	fmt.Println()
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d flow files are invalid", invalid, len(paths))
+	}
+	ui.PrintSuccess(fmt.Sprintf("✅ %d flow file(s) valid", len(paths)))
+	return nil
+}
@@ -0,0 +1,90 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// waitDelay bounds how long a cancelled command may keep its output open,
+// e.g. through child processes that outlive it
+const waitDelay = 5 * time.Second
+
+// Command is a process to run
+type Command struct {
+	Name string
+	Args []string
+	Dir  string   // working directory; the current one if empty
+	Env  []string // extra KEY=VALUE entries on top of the current environment
+}
+
+// Result is the outcome of a command that ran to completion
+type Result struct {
+	ExitCode int
+	Stdout   []byte
+	Stderr   []byte
+	Output   []byte // stdout and stderr interleaved as they were written
+}
+
+// String renders the command line, e.g. for logs and previews
+func (c Command) String() string {
+	return Join(append([]string{c.Name}, c.Args...))
+}
+
+// RunContext runs the command and waits for it to exit. A non-zero exit code
+// is not an error; it's reported in the result. The process is killed when
+// the context is done.
+func (c Command) RunContext(ctx context.Context) (Result, error) {
+	cmd := exec.CommandContext(ctx, c.Name, c.Args...)
+	cmd.Dir = c.Dir
+	cmd.Env = append(os.Environ(), c.Env...)
+	cmd.WaitDelay = waitDelay
+
+	var stdout, stderr bytes.Buffer
+	combined := &lockedBuffer{}
+	cmd.Stdout = io.MultiWriter(&stdout, combined)
+	cmd.Stderr = io.MultiWriter(&stderr, combined)
+
+	err := cmd.Run()
+	result := Result{
+		ExitCode: cmd.ProcessState.ExitCode(),
+		Stdout:   stdout.Bytes(),
+		Stderr:   stderr.Bytes(),
+		Output:   combined.Bytes(),
+	}
+
+	// Check if context was cancelled
+	if ctx.Err() != nil {
+		return result, fmt.Errorf("command cancelled: %w", ctx.Err())
+	}
+
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return result, fmt.Errorf("failed to run %s: %w", c.Name, err)
+	}
+	return result, nil
+}
+
+// lockedBuffer is a buffer that stdout and stderr can be copied into at once
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Bytes()
+}
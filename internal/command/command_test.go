@@ -0,0 +1,77 @@
+package command
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunContext(t *testing.T) {
+	tests := []struct {
+		name       string
+		cmd        Command
+		wantCode   int
+		wantStdout string
+		wantStderr string
+		wantErr    bool
+	}{
+		{
+			name:       "stdout and stderr",
+			cmd:        Command{Name: "sh", Args: []string{"-c", "echo out; echo err >&2"}},
+			wantStdout: "out\n",
+			wantStderr: "err\n",
+		},
+		{
+			name:     "non-zero exit code is not an error",
+			cmd:      Command{Name: "sh", Args: []string{"-c", "exit 3"}},
+			wantCode: 3,
+		},
+		{
+			name:       "env and dir",
+			cmd:        Command{Name: "sh", Args: []string{"-c", "echo $GREETING; pwd"}, Env: []string{"GREETING=hello"}, Dir: "/"},
+			wantStdout: "hello\n/\n",
+		},
+		{
+			name:     "missing binary",
+			cmd:      Command{Name: "infratest-no-such-binary"},
+			wantCode: -1,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := tt.cmd.RunContext(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RunContext() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if res.ExitCode != tt.wantCode {
+				t.Errorf("ExitCode = %d, want %d", res.ExitCode, tt.wantCode)
+			}
+			if string(res.Stdout) != tt.wantStdout {
+				t.Errorf("Stdout = %q, want %q", res.Stdout, tt.wantStdout)
+			}
+			if string(res.Stderr) != tt.wantStderr {
+				t.Errorf("Stderr = %q, want %q", res.Stderr, tt.wantStderr)
+			}
+			if len(res.Output) != len(res.Stdout)+len(res.Stderr) {
+				t.Errorf("Output = %q, want stdout and stderr combined", res.Output)
+			}
+		})
+	}
+}
+
+func TestRunContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := Command{Name: "sleep", Args: []string{"10"}}.RunContext(ctx)
+	if err == nil || !strings.Contains(err.Error(), "cancelled") {
+		t.Errorf("RunContext() error = %v, want cancellation", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("RunContext() took %v after cancellation", elapsed)
+	}
+}
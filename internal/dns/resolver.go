@@ -0,0 +1,174 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+// dialTimeout bounds a single connection to the DNS server
+const dialTimeout = 5 * time.Second
+
+// RecordTypes lists the record types a query can look up
+var RecordTypes = []string{"A", "AAAA", "CNAME", "TXT"}
+
+// Query describes a DNS lookup and what its records must look like
+type Query struct {
+	Name    string   // host name to resolve
+	Type    string   // A (default), AAAA, CNAME or TXT
+	Server  string   // host:port of the DNS server; the system resolver if empty
+	Records []string // values that must all be resolved
+	Allowed []string // if set, every resolved value must be one of these
+}
+
+// LookupWithRetryContext resolves the query until its expectations hold,
+// retrying like http.CheckWithRetryContext, and returns the last records
+func LookupWithRetryContext(ctx context.Context, q Query, retries int, delay time.Duration, debug bool) ([]string, error) {
+	var last []string
+	var lastErr error
+
+	for i := 0; i <= retries; i++ {
+		if debug && i > 0 {
+			fmt.Printf("[DEBUG] DNS lookup retry %d/%d for %s %s\n", i, retries, q.recordType(), q.Name)
+		}
+
+		records, err := LookupContext(ctx, q)
+		if ctx.Err() != nil {
+			return last, fmt.Errorf("DNS lookup cancelled: %w", ctx.Err())
+		}
+		if err == nil {
+			last = records
+			err = q.verify(records)
+		}
+		if err == nil {
+			return records, nil
+		}
+		lastErr = err
+
+		if i < retries {
+			if err := sleep(ctx, delay); err != nil {
+				return last, err
+			}
+		}
+	}
+
+	if retries > 0 {
+		return last, fmt.Errorf("DNS lookup failed after %d retries: %w", retries, lastErr)
+	}
+	return last, lastErr
+}
+
+// LookupContext resolves the query once and returns its records, sorted
+func LookupContext(ctx context.Context, q Query) ([]string, error) {
+	resolver := net.DefaultResolver
+	if q.Server != "" {
+		resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				dialer := net.Dialer{Timeout: dialTimeout}
+				return dialer.DialContext(ctx, network, q.Server)
+			},
+		}
+	}
+
+	var records []string
+	switch q.recordType() {
+	case "A", "AAAA":
+		network := "ip4"
+		if q.recordType() == "AAAA" {
+			network = "ip6"
+		}
+		ips, err := resolver.LookupIP(ctx, network, q.Name)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			records = append(records, ip.String())
+		}
+	case "CNAME":
+		cname, err := resolver.LookupCNAME(ctx, q.Name)
+		if err != nil {
+			return nil, err
+		}
+		records = []string{cname}
+	case "TXT":
+		txts, err := resolver.LookupTXT(ctx, q.Name)
+		if err != nil {
+			return nil, err
+		}
+		records = txts
+	default:
+		return nil, fmt.Errorf("unsupported record type %s", q.Type)
+	}
+
+	sort.Strings(records)
+	return records, nil
+}
+
+// verify checks resolved records against the query's expectations
+func (q Query) verify(records []string) error {
+	if len(records) == 0 {
+		return fmt.Errorf("%s %s: no records", q.recordType(), q.Name)
+	}
+	for _, want := range q.Records {
+		if !q.contains(records, want) {
+			return fmt.Errorf("%s %s: expected %s, got %s", q.recordType(), q.Name, want, strings.Join(records, ", "))
+		}
+	}
+	if len(q.Allowed) > 0 {
+		for _, record := range records {
+			if !q.contains(q.Allowed, record) {
+				return fmt.Errorf("%s %s: unexpected record %s (expected one of %s)", q.recordType(), q.Name, record, strings.Join(q.Allowed, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// contains reports whether a record value is in a list, comparing IPs by
+// value and names without case or trailing dot
+func (q Query) contains(values []string, record string) bool {
+	for _, v := range values {
+		if q.normalize(v) == q.normalize(record) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalize returns the canonical form of a record value
+func (q Query) normalize(value string) string {
+	switch q.recordType() {
+	case "A", "AAAA":
+		if ip := net.ParseIP(value); ip != nil {
+			return ip.String()
+		}
+	case "CNAME":
+		return strings.ToLower(strings.TrimSuffix(value, "."))
+	}
+	return value
+}
+
+// recordType returns the query's record type, A by default
+func (q Query) recordType() string {
+	if q.Type == "" {
+		return "A"
+	}
+	return strings.ToUpper(q.Type)
+}
+
+// sleep waits for the retry delay unless the context is done first
+func sleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("DNS lookup cancelled: %w", ctx.Err())
+	case <-timer.C:
+		return nil
+	}
+}
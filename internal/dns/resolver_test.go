@@ -0,0 +1,176 @@
+package dns
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Record types served by the test server
+const (
+	typeA     = 1
+	typeCNAME = 5
+	typeTXT   = 16
+	typeAAAA  = 28
+)
+
+// fakeServer is a minimal DNS server answering from a fixed zone. Names
+// resolve only after `delay` queries, to simulate propagation.
+type fakeServer struct {
+	conn    net.PacketConn
+	zone    map[uint16]map[string][][]byte // type -> name -> rdata
+	delay   int32
+	queries int32
+}
+
+func newFakeServer(t *testing.T, delay int32) *fakeServer {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket() error: %v", err)
+	}
+	s := &fakeServer{
+		conn: conn,
+		zone: map[uint16]map[string][][]byte{
+			typeA: {"api.example.com.": {
+				net.ParseIP("10.0.0.1").To4(),
+				net.ParseIP("10.0.0.2").To4(),
+			}},
+			typeAAAA:  {"api.example.com.": {net.ParseIP("2001:db8::1")}},
+			typeCNAME: {"www.example.com.": {encodeName("api.example.com.")}},
+			typeTXT:   {"example.com.": {append([]byte{14}, "v=spf1 -all ok"...)}},
+		},
+		delay: delay,
+	}
+	t.Cleanup(func() { conn.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *fakeServer) addr() string {
+	return s.conn.LocalAddr().String()
+}
+
+func (s *fakeServer) serve() {
+	buf := make([]byte, 512)
+	for {
+		n, addr, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		if resp := s.answer(buf[:n]); resp != nil {
+			s.conn.WriteTo(resp, addr)
+		}
+	}
+}
+
+// answer builds the response to a query with a single question
+func (s *fakeServer) answer(query []byte) []byte {
+	if len(query) < 12 {
+		return nil
+	}
+	// Read the question name, then its type and class
+	var labels []string
+	off := 12
+	for off < len(query) && query[off] != 0 {
+		l := int(query[off])
+		if off+1+l > len(query) {
+			return nil
+		}
+		labels = append(labels, string(query[off+1:off+1+l]))
+		off += 1 + l
+	}
+	if off+5 > len(query) {
+		return nil
+	}
+	qtype := binary.BigEndian.Uint16(query[off+1:])
+	question := query[12 : off+5]
+	name := strings.ToLower(strings.Join(labels, ".")) + "."
+
+	var records [][]byte
+	if atomic.AddInt32(&s.queries, 1) > s.delay {
+		records = s.zone[qtype][name]
+	}
+
+	resp := make([]byte, 12, 512)
+	copy(resp, query[:2])
+	binary.BigEndian.PutUint16(resp[2:], 0x8180) // response, recursion desired and available
+	binary.BigEndian.PutUint16(resp[4:], 1)
+	binary.BigEndian.PutUint16(resp[6:], uint16(len(records)))
+	resp = append(resp, question...)
+	for _, rdata := range records {
+		resp = append(resp, 0xc0, 12) // pointer to the question name
+		resp = binary.BigEndian.AppendUint16(resp, qtype)
+		resp = binary.BigEndian.AppendUint16(resp, 1)
+		resp = binary.BigEndian.AppendUint32(resp, 60)
+		resp = binary.BigEndian.AppendUint16(resp, uint16(len(rdata)))
+		resp = append(resp, rdata...)
+	}
+	return resp
+}
+
+// encodeName encodes a domain name in DNS wire format
+func encodeName(name string) []byte {
+	var out []byte
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		out = append(out, byte(len(label)))
+		out = append(out, label...)
+	}
+	return append(out, 0)
+}
+
+func TestLookupWithRetryContext(t *testing.T) {
+	server := newFakeServer(t, 0)
+
+	tests := []struct {
+		name    string
+		query   Query
+		want    string
+		wantErr string
+	}{
+		{name: "A", query: Query{Name: "api.example.com.", Records: []string{"10.0.0.2"}}, want: "10.0.0.1,10.0.0.2"},
+		{name: "AAAA", query: Query{Name: "api.example.com.", Type: "aaaa", Records: []string{"2001:db8:0::1"}}, want: "2001:db8::1"},
+		{name: "CNAME", query: Query{Name: "www.example.com.", Type: "CNAME", Records: []string{"API.example.com"}}, want: "api.example.com."},
+		{name: "TXT", query: Query{Name: "example.com.", Type: "TXT", Records: []string{"v=spf1 -all ok"}}, want: "v=spf1 -all ok"},
+		{name: "allowed", query: Query{Name: "api.example.com.", Allowed: []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}}, want: "10.0.0.1,10.0.0.2"},
+		{name: "not allowed", query: Query{Name: "api.example.com.", Allowed: []string{"10.0.0.1"}}, wantErr: "unexpected record 10.0.0.2"},
+		{name: "missing record", query: Query{Name: "api.example.com.", Records: []string{"10.0.0.9"}}, wantErr: "expected 10.0.0.9, got 10.0.0.1, 10.0.0.2"},
+		{name: "unknown name", query: Query{Name: "nope.example.com."}, wantErr: "DNS lookup failed after 1 retries"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.query.Server = server.addr()
+			records, err := LookupWithRetryContext(context.Background(), tt.query, 1, time.Millisecond, false)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("LookupWithRetryContext() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LookupWithRetryContext() error: %v", err)
+			}
+			if got := strings.Join(records, ","); got != tt.want {
+				t.Errorf("LookupWithRetryContext() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookupWithRetryContextPropagation(t *testing.T) {
+	server := newFakeServer(t, 2)
+
+	q := Query{Name: "api.example.com.", Server: server.addr(), Records: []string{"10.0.0.1"}}
+	records, err := LookupWithRetryContext(context.Background(), q, 3, time.Millisecond, false)
+	if err != nil {
+		t.Fatalf("LookupWithRetryContext() error: %v", err)
+	}
+	if len(records) != 2 || atomic.LoadInt32(&server.queries) != 3 {
+		t.Errorf("Expected records after 3 queries, got %v after %d", records, server.queries)
+	}
+}
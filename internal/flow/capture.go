@@ -0,0 +1,327 @@
+package flow
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/infratest/infratest/internal/command"
+	"github.com/infratest/infratest/internal/flow/interpolator"
+	"github.com/infratest/infratest/internal/inventory"
+	"github.com/infratest/infratest/internal/jsonpath"
+)
+
+// captureNameRegex restricts capture names to identifiers usable in ${steps.<name>.<var>}
+var captureNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// stepRefRegex finds ${steps.<name>.<var>} references in step templates
+var stepRefRegex = regexp.MustCompile(`\$\{steps\.([^.}]+)\.([^}]+)\}`)
+
+// maskedValue replaces sensitive captured values in logs and reports
+const maskedValue = "(sensitive)"
+
+// validate checks that a capture uses exactly one source and that the source parses
+func (c CaptureSpec) validate(step Step) error {
+	sources := 0
+	if c.JSONPath != "" {
+		sources++
+		if _, err := jsonpath.Parse(c.JSONPath); err != nil {
+			return err
+		}
+	}
+	if c.Regex != "" {
+		sources++
+		if _, err := regexp.Compile(c.Regex); err != nil {
+			return fmt.Errorf("invalid regex %q: %w", c.Regex, err)
+		}
+	}
+	if c.Resource != "" || c.Attribute != "" {
+		sources++
+		if step.Type != "terraform-inventory" {
+			return fmt.Errorf("resource/attribute captures are only supported on terraform-inventory steps")
+		}
+		if c.Resource != "" {
+			if _, _, _, err := inventory.ParsePattern(c.Resource); err != nil {
+				return err
+			}
+		}
+		if c.Attribute != "" {
+			if _, err := jsonpath.Parse(c.Attribute); err != nil {
+				return err
+			}
+		}
+	}
+
+	if sources != 1 {
+		return fmt.Errorf("exactly one of jsonpath, regex or resource/attribute is required")
+	}
+	return nil
+}
+
+// validateCaptures checks the capture block of a step
+func validateCaptures(step Step) error {
+	for name, spec := range step.Capture {
+		if !captureNameRegex.MatchString(name) {
+			return fmt.Errorf("step %s: invalid capture name %q (use letters, digits and underscores)", step.Name, name)
+		}
+		if stepFields[name] {
+			return fmt.Errorf("step %s: capture name %q clashes with the built-in steps.%s.%s field", step.Name, name, step.Name, name)
+		}
+		if err := spec.validate(step); err != nil {
+			return fmt.Errorf("step %s: capture %s: %w", step.Name, name, err)
+		}
+	}
+	return nil
+}
+
+// templates returns the step fields that are interpolated before the step runs
+func (s Step) templates() []string {
+	templates := append([]string{s.Command, s.URL, s.Workdir, s.Address, s.Send, s.Banner, s.Hostname, s.Resolver, s.RecordsInOutput}, s.Commands...)
+	templates = append(templates, s.Records...)
+	templates = append(templates, s.Args...)
+	templates = append(templates, s.Until.templates()...)
+	for _, path := range sortedOutputPaths(s.Outputs) {
+		templates = append(templates, s.Outputs[path].templates()...)
+	}
+	for _, name := range sortedNames(s.Env) {
+		templates = append(templates, s.Env[name])
+	}
+	return append(templates, stringValues(s.Variables)...)
+}
+
+// templateSteps returns the steps whose captured values a step interpolates.
+// Those steps must finish before the step can run.
+func templateSteps(step Step) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, tmpl := range step.templates() {
+		for _, m := range stepRefRegex.FindAllStringSubmatch(tmpl, -1) {
+			if !seen[m[1]] {
+				seen[m[1]] = true
+				names = append(names, m[1])
+			}
+		}
+	}
+	return names
+}
+
+// validateTemplateRefs checks that every ${steps.<name>.<var>} reference
+// names another step and a variable that step captures
+func validateTemplateRefs(step Step, byName map[string]Step) error {
+	for _, tmpl := range step.templates() {
+		for _, m := range stepRefRegex.FindAllStringSubmatch(tmpl, -1) {
+			name := m[1]
+			variable, _, _ := strings.Cut(strings.SplitN(m[2], "[", 2)[0], ".")
+
+			ref, ok := byName[name]
+			if !ok {
+				return fmt.Errorf("step %s references unknown step %s in %s", step.Name, name, m[0])
+			}
+			if name == step.Name {
+				return fmt.Errorf("step %s references its own captured value %s", step.Name, m[0])
+			}
+			if _, ok := ref.Capture[variable]; !ok {
+				return fmt.Errorf("step %s references %s, but step %s doesn't capture %q", step.Name, m[0], name, variable)
+			}
+		}
+	}
+	return nil
+}
+
+// captureValues extracts every value of a step's capture block from its result
+func captureValues(step Step, result *StepResult) (map[string]interface{}, error) {
+	if len(step.Capture) == 0 {
+		return nil, nil
+	}
+
+	// Capture in name order so errors are deterministic
+	names := make([]string, 0, len(step.Capture))
+	for name := range step.Capture {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	values := make(map[string]interface{}, len(names))
+	for _, name := range names {
+		val, err := captureValue(step.Capture[name], result)
+		if err != nil {
+			return nil, fmt.Errorf("failed to capture %s: %w", name, err)
+		}
+		values[name] = val
+	}
+	return values, nil
+}
+
+// captureValue extracts a single value from a step result
+func captureValue(spec CaptureSpec, result *StepResult) (interface{}, error) {
+	// HTTP and exec steps capture from the response body or stdout, others from their output
+	text := result.Output
+	if result.Body != nil {
+		text = string(result.Body)
+	}
+
+	switch {
+	case spec.JSONPath != "":
+		var doc interface{}
+		if err := json.Unmarshal([]byte(text), &doc); err != nil {
+			return nil, fmt.Errorf("response is not valid JSON: %w", err)
+		}
+		return jsonpath.Get(doc, spec.JSONPath)
+
+	case spec.Regex != "":
+		re, err := regexp.Compile(spec.Regex)
+		if err != nil {
+			return nil, err
+		}
+		m := re.FindStringSubmatch(text)
+		if m == nil {
+			return nil, fmt.Errorf("regex %q did not match", spec.Regex)
+		}
+		if len(m) > 1 {
+			return m[1], nil
+		}
+		return m[0], nil
+
+	default:
+		return captureResourceAttribute(spec, result.Resources)
+	}
+}
+
+// captureResourceAttribute reads an attribute from the inventory resources a
+// step matched. Wildcard patterns (or no pattern at all) capture a list with
+// one value per resource; exact patterns must match a single resource.
+// Without an attribute path, the resource ID is captured.
+func captureResourceAttribute(spec CaptureSpec, resources []Resource) (interface{}, error) {
+	var values []interface{}
+	add := func(address, id string, attrs map[string]interface{}) error {
+		if spec.Attribute == "" {
+			values = append(values, id)
+			return nil
+		}
+		val, err := jsonpath.Get(attrs, spec.Attribute)
+		if err != nil {
+			return fmt.Errorf("resource %s: %w", address, err)
+		}
+		values = append(values, val)
+		return nil
+	}
+
+	if spec.Resource == "" {
+		for _, r := range resources {
+			if err := add(r.Address, r.ID, r.Attributes); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	}
+
+	candidates := make([]inventory.Resource, len(resources))
+	for i, r := range resources {
+		candidates[i] = inventory.Resource{
+			Type:       r.Type,
+			Name:       r.Name,
+			Address:    r.Address,
+			Module:     r.Module,
+			Mode:       r.Mode,
+			ID:         r.ID,
+			Attributes: r.Attributes,
+		}
+	}
+
+	module, resourceType, resourceName, err := inventory.ParsePattern(spec.Resource)
+	if err != nil {
+		return nil, err
+	}
+	results, _ := inventory.NewMatcher(candidates).Match(map[string]inventory.ResourceMatch{
+		spec.Resource: {Module: module, Type: resourceType, Name: resourceName},
+	})
+	for _, r := range results[spec.Resource].Resources {
+		if err := add(r.Address, r.ID, r.Attributes); err != nil {
+			return nil, err
+		}
+	}
+
+	if strings.Contains(spec.Resource, "*") {
+		return values, nil
+	}
+	if len(values) != 1 {
+		return nil, fmt.Errorf("resource pattern %s matched %d resources, expected exactly 1", spec.Resource, len(values))
+	}
+	return values[0], nil
+}
+
+// MaskCaptured returns a copy of captured values with sensitive ones masked
+func (s Step) MaskCaptured(values map[string]interface{}) map[string]interface{} {
+	if values == nil {
+		return nil
+	}
+	masked := make(map[string]interface{}, len(values))
+	for name, val := range values {
+		if s.Capture[name].Sensitive {
+			val = maskedValue
+		}
+		masked[name] = val
+	}
+	return masked
+}
+
+// capturedValues returns the values captured so far, keyed by step name
+func (e *Executor) capturedValues() map[string]interface{} {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	steps := make(map[string]interface{})
+	for _, r := range e.results {
+		if r.Captured != nil {
+			steps[r.StepName] = r.Captured
+		}
+	}
+	return steps
+}
+
+// maskedCapturedValues returns the values captured so far, with sensitive
+// ones masked
+func (e *Executor) maskedCapturedValues() map[string]interface{} {
+	steps := e.capturedValues()
+	for _, step := range e.flow.Steps {
+		if captured, ok := steps[step.Name].(map[string]interface{}); ok {
+			steps[step.Name] = step.MaskCaptured(captured)
+		}
+	}
+	return steps
+}
+
+// interpolate resolves ${output.*}, ${steps.<name>.<var>} and ${matrix.*} references
+func (e *Executor) interpolate(template string) string {
+	return e.interpolateSteps(template, e.capturedValues())
+}
+
+// interpolateCommand interpolates every argument of a command line on its own
+// and quotes them again, so values with spaces or quotes stay single arguments
+func (e *Executor) interpolateCommand(line string) (string, error) {
+	args, err := command.Split(line)
+	if err != nil {
+		return "", err
+	}
+	for i, arg := range args {
+		args[i] = e.interpolate(arg)
+	}
+	return command.Join(args), nil
+}
+
+// interpolateMasked resolves references like interpolate, with sensitive
+// captured values masked, for text that is logged
+func (e *Executor) interpolateMasked(template string) string {
+	return e.interpolateSteps(template, e.maskedCapturedValues())
+}
+
+// interpolateSteps resolves references against the given captured values
+func (e *Executor) interpolateSteps(template string, steps map[string]interface{}) string {
+	return interpolator.InterpolateScope(template, interpolator.Scope{
+		"output": e.currentOutputs(),
+		"steps":  steps,
+		"matrix": e.flow.MatrixValues,
+	})
+}
@@ -0,0 +1,248 @@
+package flow
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCaptureValue(t *testing.T) {
+	resources := []Resource{
+		{Type: "aws_vpc", Name: "main", Address: "aws_vpc.main", ID: "vpc-123", Attributes: map[string]interface{}{"cidr_block": "10.0.0.0/16"}},
+		{Type: "aws_subnet", Name: "public_a", Address: "aws_subnet.public_a", ID: "subnet-a", Attributes: map[string]interface{}{"tags": map[string]interface{}{"Name": "a"}}},
+		{Type: "aws_subnet", Name: "public_b", Address: "aws_subnet.public_b", ID: "subnet-b", Attributes: map[string]interface{}{"tags": map[string]interface{}{"Name": "b"}}},
+	}
+
+	tests := []struct {
+		name    string
+		spec    CaptureSpec
+		result  StepResult
+		want    interface{}
+		wantErr bool
+	}{
+		{
+			name:   "jsonpath over response body",
+			spec:   CaptureSpec{JSONPath: "$.data.token"},
+			result: StepResult{Body: []byte(`{"data": {"token": "abc123"}}`)},
+			want:   "abc123",
+		},
+		{
+			name:   "jsonpath over step output",
+			spec:   CaptureSpec{JSONPath: "ids[1]"},
+			result: StepResult{Output: `{"ids": ["a", "b"]}`},
+			want:   "b",
+		},
+		{
+			name:    "jsonpath over non-JSON body",
+			spec:    CaptureSpec{JSONPath: "$.token"},
+			result:  StepResult{Body: []byte("<html>")},
+			wantErr: true,
+		},
+		{
+			name:   "regex with group",
+			spec:   CaptureSpec{Regex: `vpc_id = "(vpc-[a-z0-9]+)"`},
+			result: StepResult{Output: "Outputs:\n\nvpc_id = \"vpc-0abc\"\n"},
+			want:   "vpc-0abc",
+		},
+		{
+			name:   "regex without group",
+			spec:   CaptureSpec{Regex: `vpc-[a-z0-9]+`},
+			result: StepResult{Output: "created vpc-0abc"},
+			want:   "vpc-0abc",
+		},
+		{
+			name:    "regex without match",
+			spec:    CaptureSpec{Regex: `sg-[a-z0-9]+`},
+			result:  StepResult{Output: "created vpc-0abc"},
+			wantErr: true,
+		},
+		{
+			name:   "exact resource attribute",
+			spec:   CaptureSpec{Resource: "aws_vpc.main", Attribute: "cidr_block"},
+			result: StepResult{Resources: resources},
+			want:   "10.0.0.0/16",
+		},
+		{
+			name:   "wildcard resource attribute",
+			spec:   CaptureSpec{Resource: "aws_subnet.*", Attribute: "tags.Name"},
+			result: StepResult{Resources: resources},
+			want:   []interface{}{"a", "b"},
+		},
+		{
+			name:   "resource id",
+			spec:   CaptureSpec{Resource: "aws_subnet.public_b"},
+			result: StepResult{Resources: resources},
+			want:   "subnet-b",
+		},
+		{
+			name:    "exact resource not matched",
+			spec:    CaptureSpec{Resource: "aws_vpc.other", Attribute: "cidr_block"},
+			result:  StepResult{Resources: resources},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := captureValue(tt.spec, &tt.result)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("captureValue() expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("captureValue() error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("captureValue() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateCaptures(t *testing.T) {
+	tests := []struct {
+		name    string
+		steps   []Step
+		wantErr string
+	}{
+		{
+			name: "valid capture and reference",
+			steps: []Step{
+				{Name: "login", Type: "http", Capture: map[string]CaptureSpec{"token": {JSONPath: "$.token"}}},
+				{Name: "call", Type: "http", URL: "http://api/?t=${steps.login.token}"},
+			},
+		},
+		{
+			name: "no source",
+			steps: []Step{
+				{Name: "login", Type: "http", Capture: map[string]CaptureSpec{"token": {}}},
+			},
+			wantErr: "exactly one of",
+		},
+		{
+			name: "two sources",
+			steps: []Step{
+				{Name: "login", Type: "http", Capture: map[string]CaptureSpec{"token": {JSONPath: "$.token", Regex: "x"}}},
+			},
+			wantErr: "exactly one of",
+		},
+		{
+			name: "invalid regex",
+			steps: []Step{
+				{Name: "apply", Type: "terraform", Capture: map[string]CaptureSpec{"id": {Regex: "("}}},
+			},
+			wantErr: "invalid regex",
+		},
+		{
+			name: "attribute on non-inventory step",
+			steps: []Step{
+				{Name: "apply", Type: "terraform", Capture: map[string]CaptureSpec{"id": {Resource: "aws_vpc.main"}}},
+			},
+			wantErr: "only supported on terraform-inventory",
+		},
+		{
+			name: "reserved name",
+			steps: []Step{
+				{Name: "login", Type: "http", Capture: map[string]CaptureSpec{"status": {JSONPath: "$.status"}}},
+			},
+			wantErr: "clashes with the built-in",
+		},
+		{
+			name: "reference to unknown step",
+			steps: []Step{
+				{Name: "call", Type: "http", URL: "http://api/?t=${steps.login.token}"},
+			},
+			wantErr: "unknown step login",
+		},
+		{
+			name: "reference to variable that isn't captured",
+			steps: []Step{
+				{Name: "login", Type: "http", Capture: map[string]CaptureSpec{"token": {JSONPath: "$.token"}}},
+				{Name: "call", Type: "http", URL: "http://api/?t=${steps.login.session}"},
+			},
+			wantErr: `doesn't capture "session"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateDependencies(tt.steps)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateDependencies() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateDependencies() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExecuteWithContextCapture(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			w.Write([]byte(`{"token": "abc123", "user": {"id": 7}}`))
+		case "/api/abc123/7":
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	f := &Flow{
+		Name:        "capture",
+		WorkingDir:  t.TempDir(),
+		MaxParallel: 2,
+		Steps: []Step{
+			{Name: "login", Type: "http", URL: server.URL + "/token", ExpectedStatus: 200, Retries: 1, Delay: "10ms",
+				Capture: map[string]CaptureSpec{
+					"token":   {JSONPath: "$.token", Sensitive: true},
+					"user_id": {JSONPath: "$.user.id"},
+				}},
+			{Name: "call", Type: "http", URL: server.URL + "/api/${steps.login.token}/${steps.login.user_id}", ExpectedStatus: 200, Retries: 1, Delay: "10ms"},
+			{Name: "check", Type: "http", When: `steps.login.token == "abc123"`, URL: server.URL + "/token", ExpectedStatus: 200, Retries: 1, Delay: "10ms"},
+		},
+	}
+	if err := validateFlow(f); err != nil {
+		t.Fatalf("validateFlow() error: %v", err)
+	}
+
+	e := &Executor{flow: f, outputs: map[string]interface{}{}}
+	if err := e.ExecuteWithContext(context.Background()); err != nil {
+		t.Fatalf("ExecuteWithContext() error: %v", err)
+	}
+
+	byName := make(map[string]StepResult)
+	for _, r := range e.GetResults() {
+		byName[r.StepName] = r
+	}
+
+	if got := byName["login"].Captured["token"]; got != "abc123" {
+		t.Errorf("Expected captured token abc123, got %v", got)
+	}
+	if r := byName["call"]; !r.Success {
+		t.Errorf("Expected call to use the captured values, got %+v", r)
+	}
+	if r := byName["check"]; r.Skipped || !r.Success {
+		t.Errorf("Expected check to run based on the captured token, got %+v", r)
+	}
+
+	masked := f.Steps[0].MaskCaptured(byName["login"].Captured)
+	if masked["token"] != maskedValue || masked["user_id"] != float64(7) {
+		t.Errorf("MaskCaptured() = %v", masked)
+	}
+
+	// Logged text masks sensitive captures
+	if got, want := e.interpolateMasked("/api/${steps.login.token}/${steps.login.user_id}"), "/api/(sensitive)/7"; got != want {
+		t.Errorf("interpolateMasked() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,200 @@
+package flow
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/infratest/infratest/internal/command"
+	"github.com/infratest/infratest/internal/terraform"
+)
+
+// defaultPlanCommand is what terraform-plan steps run without a command
+const defaultPlanCommand = "terraform plan"
+
+// planActions are the actions terraform can plan for a resource
+var planActions = []string{"create", "update", "delete", "replace", "read", "no-op"}
+
+// validatePlanChanges checks the command and changes of a terraform-plan step,
+// and that other steps don't use changes
+func validatePlanChanges(step Step) error {
+	if step.Type != "terraform-plan" {
+		if step.Changes != nil {
+			return fmt.Errorf("changes are only supported on terraform-plan steps")
+		}
+		return nil
+	}
+	if err := validatePlanCommand(step, "-out"); err != nil {
+		return err
+	}
+
+	c := step.Changes
+	if c == nil {
+		return nil
+	}
+	for _, n := range []*int{c.Creates, c.Updates, c.Deletes, c.Replaces} {
+		if n != nil && *n < 0 {
+			return fmt.Errorf("changes: counts must not be negative")
+		}
+	}
+	for address, actions := range c.Actions {
+		for _, action := range actions {
+			if !containsString(planActions, action) {
+				return fmt.Errorf("changes: unknown action %q for %s (expected %s)", action, address, strings.Join(planActions, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// validatePlanCommand checks that a step runs a single terraform plan command
+// without the flags the step sets itself
+func validatePlanCommand(step Step, flags ...string) error {
+	if len(step.Commands) > 0 {
+		return fmt.Errorf("%s steps run a single command", step.Type)
+	}
+	if step.Command == "" {
+		return nil
+	}
+	parts, err := command.Split(step.Command)
+	if err != nil {
+		return fmt.Errorf("invalid command: %w", err)
+	}
+	if len(parts) > 0 && parts[0] == "terraform" {
+		parts = parts[1:]
+	}
+	if len(parts) == 0 || parts[0] != "plan" {
+		return fmt.Errorf("%s steps run terraform plan, got %q", step.Type, step.Command)
+	}
+	for _, arg := range parts[1:] {
+		for _, flag := range flags {
+			if strings.HasPrefix(arg, flag) {
+				return fmt.Errorf("%s is set by the step, remove it from the command", flag)
+			}
+		}
+	}
+	return nil
+}
+
+// planCommand returns the command a terraform-plan or terraform-idempotency step runs, before interpolation
+func planCommand(step Step) string {
+	if step.Command == "" {
+		return defaultPlanCommand
+	}
+	return step.Command
+}
+
+// executePlanStep plans the changes of a terraform-plan step and checks them
+// against its changes and expected_resources
+func (e *Executor) executePlanStep(ctx context.Context, step Step) (*terraform.Plan, string, error) {
+	// Refresh outputs so the command sees the latest values
+	e.refreshOutputs(ctx)
+
+	// Variables reach terraform through TF_VAR_* and -var-file
+	executor, err := e.stepExecutor(step)
+	if err != nil {
+		return nil, "", err
+	}
+
+	cmd, err := e.interpolateCommand(planCommand(step))
+	if err != nil {
+		return nil, "", err
+	}
+	plan, output, err := executor.PlanWithContext(ctx, cmd)
+	if err != nil {
+		return nil, output, err
+	}
+	if err := checkPlanChanges(step.Changes, plan); err != nil {
+		return plan, output, err
+	}
+	if len(step.ExpectedResources) > 0 {
+		if _, err := e.executeAdvancedInventory(step, plannedResources(plan)); err != nil {
+			return plan, output, fmt.Errorf("planned resources: %w", err)
+		}
+	}
+	return plan, output, nil
+}
+
+// checkPlanChanges checks a plan against the expected changes, reporting every problem at once
+func checkPlanChanges(changes *PlanChanges, plan *terraform.Plan) error {
+	if changes == nil {
+		return nil
+	}
+
+	var issues []string
+	summary := plan.Summary()
+	for _, count := range []struct {
+		name     string
+		expected *int
+		actual   int
+	}{
+		{"creates", changes.Creates, summary.Creates},
+		{"updates", changes.Updates, summary.Updates},
+		{"deletes", changes.Deletes, summary.Deletes},
+		{"replaces", changes.Replaces, summary.Replaces},
+	} {
+		if count.expected != nil && *count.expected != count.actual {
+			issues = append(issues, fmt.Sprintf("expected %d %s, plan has %d", *count.expected, count.name, count.actual))
+		}
+	}
+
+	byAddress := make(map[string]terraform.ResourceChange, len(plan.ResourceChanges))
+	for _, rc := range plan.ResourceChanges {
+		byAddress[rc.Address] = rc
+		if !changes.NoDestroy || rc.Mode != "managed" {
+			continue
+		}
+		switch rc.Action() {
+		case "delete":
+			issues = append(issues, fmt.Sprintf("%s would be destroyed", rc.Address))
+		case "replace":
+			issues = append(issues, fmt.Sprintf("%s would be replaced", rc.Address))
+		}
+	}
+
+	addresses := make([]string, 0, len(changes.Actions))
+	for address := range changes.Actions {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	for _, address := range addresses {
+		expected := changes.Actions[address]
+		rc, ok := byAddress[address]
+		if !ok {
+			issues = append(issues, fmt.Sprintf("%s: not in plan, expected %s", address, strings.Join(expected, " or ")))
+			continue
+		}
+		if !containsString(expected, rc.Action()) {
+			issues = append(issues, fmt.Sprintf("%s: planned %s, expected %s", address, rc.Action(), strings.Join(expected, " or ")))
+		}
+	}
+
+	if len(issues) > 0 {
+		return fmt.Errorf("plan check failed (%s):\n%s", summary, strings.Join(issues, "\n"))
+	}
+	return nil
+}
+
+// plannedResources returns the managed resources that exist after the plan is
+// applied, with their planned attribute values. Values only known after apply
+// are missing.
+func plannedResources(plan *terraform.Plan) []terraform.Resource {
+	var resources []terraform.Resource
+	for _, rc := range plan.ResourceChanges {
+		if rc.Mode != "managed" || rc.Action() == "delete" {
+			continue
+		}
+		id, _ := rc.Change.After["id"].(string)
+		resources = append(resources, terraform.Resource{
+			Type:       rc.Type,
+			ID:         id,
+			Name:       rc.Name,
+			Address:    rc.Address,
+			Module:     rc.Module,
+			Mode:       rc.Mode,
+			Attributes: rc.Change.After,
+		})
+	}
+	return resources
+}
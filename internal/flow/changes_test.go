@@ -0,0 +1,113 @@
+package flow
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/infratest/infratest/internal/terraform"
+)
+
+func TestValidatePlanChanges(t *testing.T) {
+	count := func(n int) *int { return &n }
+	tests := []struct {
+		name    string
+		step    Step
+		wantErr string
+	}{
+		{name: "default command", step: Step{Type: "terraform-plan", Changes: &PlanChanges{Deletes: count(0), Actions: map[string]StringList{"aws_instance.web": {"update", "no-op"}}}}},
+		{name: "plan command", step: Step{Type: "terraform-plan", Command: "terraform plan -refresh=false"}},
+		{name: "not a plan", step: Step{Type: "terraform-plan", Command: "terraform apply"}, wantErr: "terraform-plan steps run terraform plan"},
+		{name: "out flag", step: Step{Type: "terraform-plan", Command: "plan -out=my.tfplan"}, wantErr: "-out is set by the step"},
+		{name: "commands", step: Step{Type: "terraform-plan", Commands: []string{"plan", "plan"}}, wantErr: "single command"},
+		{name: "negative count", step: Step{Type: "terraform-plan", Changes: &PlanChanges{Creates: count(-1)}}, wantErr: "must not be negative"},
+		{name: "unknown action", step: Step{Type: "terraform-plan", Changes: &PlanChanges{Actions: map[string]StringList{"aws_instance.web": {"destroy"}}}}, wantErr: `unknown action "destroy"`},
+		{name: "changes on other step", step: Step{Type: "terraform", Command: "plan", Changes: &PlanChanges{}}, wantErr: "only supported on terraform-plan steps"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePlanChanges(tt.step)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validatePlanChanges() error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validatePlanChanges() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckPlanChanges(t *testing.T) {
+	count := func(n int) *int { return &n }
+	change := func(address string, actions ...string) terraform.ResourceChange {
+		typ, name, _ := strings.Cut(address, ".")
+		return terraform.ResourceChange{Address: address, Mode: "managed", Type: typ, Name: name, Change: terraform.Change{Actions: actions}}
+	}
+	plan := &terraform.Plan{ResourceChanges: []terraform.ResourceChange{
+		change("aws_vpc.main", "no-op"),
+		change("aws_subnet.a", "create"),
+		change("aws_instance.web", "update"),
+		change("aws_db_instance.main", "delete", "create"),
+	}}
+
+	tests := []struct {
+		name    string
+		changes *PlanChanges
+		wantErr []string
+	}{
+		{name: "no assertions"},
+		{name: "counts", changes: &PlanChanges{Creates: count(2), Updates: count(1), Deletes: count(1), Replaces: count(1)}},
+		{name: "actions", changes: &PlanChanges{Actions: map[string]StringList{"aws_vpc.main": {"no-op"}, "aws_instance.web": {"update", "no-op"}}}},
+		{name: "count mismatch", changes: &PlanChanges{Deletes: count(0)}, wantErr: []string{"expected 0 deletes, plan has 1"}},
+		{name: "no destroy", changes: &PlanChanges{NoDestroy: true}, wantErr: []string{"aws_db_instance.main would be replaced"}},
+		{name: "action mismatch", changes: &PlanChanges{Actions: map[string]StringList{"aws_instance.web": {"no-op"}, "aws_s3_bucket.logs": {"create"}}},
+			wantErr: []string{"aws_instance.web: planned update, expected no-op", "aws_s3_bucket.logs: not in plan, expected create"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkPlanChanges(tt.changes, plan)
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Errorf("checkPlanChanges() error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("checkPlanChanges() succeeded, want %q", tt.wantErr)
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("checkPlanChanges() error = %v, want %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestPlannedResources(t *testing.T) {
+	plan := &terraform.Plan{ResourceChanges: []terraform.ResourceChange{
+		{Address: "aws_instance.web", Mode: "managed", Type: "aws_instance", Name: "web",
+			Change: terraform.Change{Actions: []string{"create"}, After: map[string]interface{}{"instance_type": "t3.micro", "tags": map[string]interface{}{"Name": "web"}}}},
+		{Address: "aws_instance.old", Mode: "managed", Type: "aws_instance", Name: "old",
+			Change: terraform.Change{Actions: []string{"delete"}, Before: map[string]interface{}{"instance_type": "t2.micro"}}},
+		{Address: "data.aws_ami.ubuntu", Mode: "data", Type: "aws_ami", Name: "ubuntu", Change: terraform.Change{Actions: []string{"read"}}},
+	}}
+
+	one := 1
+	step := Step{Name: "plan", Type: "terraform-plan", ExpectedResources: map[string]ResourceMatchConfig{
+		"aws_instance.*": {Count: &one, Attributes: map[string]interface{}{"instance_type": "t3.micro", "tags.Name": "web"}},
+	}}
+	e := &Executor{flow: &Flow{}}
+	if _, err := e.executeAdvancedInventory(step, plannedResources(plan)); err != nil {
+		t.Errorf("executeAdvancedInventory() error: %v", err)
+	}
+
+	step.ExpectedResources["aws_instance.*"] = ResourceMatchConfig{Attributes: map[string]interface{}{"instance_type": "t3.large"}}
+	if _, err := e.executeAdvancedInventory(step, plannedResources(plan)); err == nil || !strings.Contains(err.Error(), "expected t3.large, got t3.micro") {
+		t.Errorf("executeAdvancedInventory() error = %v, want an attribute mismatch", err)
+	}
+}
@@ -0,0 +1,358 @@
+package flow
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/infratest/infratest/internal/ui"
+)
+
+// Checkpoint records the progress of a flow run, so that a run whose process
+// was killed can be resumed or cleaned up later. Sensitive outputs and
+// captured values, and --var values, are left out.
+type Checkpoint struct {
+	FlowPath   string                 `json:"flow_path"`
+	FlowName   string                 `json:"flow_name"` // run name, which differs per matrix cell
+	FlowHash   string                 `json:"flow_hash"`
+	WorkingDir string                 `json:"working_dir"`
+	Workspace  string                 `json:"workspace,omitempty"`
+	Results    []CheckpointResult     `json:"results"`
+	Outputs    map[string]interface{} `json:"outputs,omitempty"`
+	Updated    time.Time              `json:"updated"`
+}
+
+// CheckpointResult is a step result in a checkpoint
+type CheckpointResult struct {
+	StepName   string                 `json:"step_name"`
+	StepType   string                 `json:"step_type"`
+	Success    bool                   `json:"success"`
+	Output     string                 `json:"output,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+	Duration   time.Duration          `json:"duration"`
+	Resources  []Resource             `json:"resources,omitempty"`
+	HTTPStatus int                    `json:"http_status,omitempty"`
+	Latency    time.Duration          `json:"latency,omitempty"`
+	Records    []string               `json:"records,omitempty"`
+	TimedOut   bool                   `json:"timed_out,omitempty"`
+	Skipped    bool                   `json:"skipped,omitempty"`
+	SkipReason string                 `json:"skip_reason,omitempty"`
+	Captured   map[string]interface{} `json:"captured,omitempty"`
+	Redacted   bool                   `json:"redacted,omitempty"` // sensitive captured values were left out
+}
+
+// Hash returns a fingerprint of the flow definition, used to detect flow
+// files that changed between a checkpoint and its resume. --var values are
+// left out, as they may hold secrets.
+func (f *Flow) Hash() (string, error) {
+	def := *f
+	def.VarOverrides = nil
+	data, err := json.Marshal(&def)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// CheckpointPath returns where the checkpoint of a flow run is written. Runs
+// in an isolated workspace are named after it, so concurrent runs of a flow
+// keep separate checkpoints.
+func CheckpointPath(f *Flow, workspace string) string {
+	name := sanitizeRunName(f.Name)
+	if workspace != "" {
+		name = workspace
+	}
+	return filepath.Join(f.WorkingDir, ".infratest", name+".checkpoint.json")
+}
+
+// LoadCheckpoint reads a checkpoint file
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+	var cp Checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint: %w", err)
+	}
+	return &cp, nil
+}
+
+// Flow parses the checkpoint's flow file again and returns the run it
+// recorded, without its --var values. It fails if the flow changed since the
+// checkpoint was written.
+func (cp *Checkpoint) Flow() (*Flow, error) {
+	run, changed, err := cp.run()
+	if err != nil {
+		return nil, err
+	}
+	if changed {
+		return nil, fmt.Errorf("flow %s changed since the checkpoint was written", cp.FlowPath)
+	}
+	return run, nil
+}
+
+// CleanupFlow returns the recorded run like Flow, set to the recorded working
+// directory. Cleanup is most needed after the flow was edited, so a changed
+// flow only gets a warning.
+func (cp *Checkpoint) CleanupFlow() (*Flow, error) {
+	run, changed, err := cp.run()
+	if err != nil {
+		return nil, err
+	}
+	if changed {
+		ui.PrintWarning(fmt.Sprintf("⚠️  Flow %s changed since the checkpoint was written; running its current cleanup steps", cp.FlowPath))
+	}
+	run.WorkingDir = cp.WorkingDir
+	return run, nil
+}
+
+// run parses the checkpoint's flow file again and returns the run it recorded,
+// and whether its definition changed since
+func (cp *Checkpoint) run() (*Flow, bool, error) {
+	f, err := ParseFlow(cp.FlowPath)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to parse flow: %w", err)
+	}
+
+	for _, run := range f.ExpandMatrix() {
+		if run.Name != cp.FlowName {
+			continue
+		}
+		hash, err := run.Hash()
+		if err != nil {
+			return nil, false, err
+		}
+		return run, hash != cp.FlowHash, nil
+	}
+	return nil, false, fmt.Errorf("flow %s has no run named %s", cp.FlowPath, cp.FlowName)
+}
+
+// EnableCheckpoint makes the executor write a checkpoint to path after every step
+func (e *Executor) EnableCheckpoint(path string) error {
+	hash, err := e.flow.Hash()
+	if err != nil {
+		return fmt.Errorf("failed to hash flow: %w", err)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.checkpointPath = path
+	e.flowHash = hash
+	return e.writeCheckpoint()
+}
+
+// CheckpointFile returns the checkpoint path, or "" if checkpoints are disabled
+func (e *Executor) CheckpointFile() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.checkpointPath
+}
+
+// RemoveCheckpoint deletes the checkpoint once it's no longer needed
+func (e *Executor) RemoveCheckpoint() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.checkpointPath == "" {
+		return nil
+	}
+	if err := os.Remove(e.checkpointPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	ui.PrintDebug(e.debug, "Removed checkpoint %s", e.checkpointPath)
+	e.checkpointPath = ""
+	return nil
+}
+
+// CleanedUp reports whether the flow has cleanup (when: always) steps and all of them passed
+func (e *Executor) CleanedUp() bool {
+	passed := make(map[string]bool)
+	for _, r := range e.GetResults() {
+		passed[r.StepName] = r.Success && !r.Skipped
+	}
+	cleanup := false
+	for _, step := range e.flow.Steps {
+		if step.When != "always" {
+			continue
+		}
+		if !passed[step.Name] {
+			return false
+		}
+		cleanup = true
+	}
+	return cleanup
+}
+
+// Restore loads the passed step results, outputs and workspace recorded in a
+// checkpoint. Those steps aren't run again; steps that failed are, and so are
+// steps whose sensitive captured values weren't recorded.
+func (e *Executor) Restore(cp *Checkpoint) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.results = e.results[:0]
+	for _, r := range cp.Results {
+		if !r.Success || r.Redacted {
+			continue
+		}
+		result := StepResult{
+			StepName:   r.StepName,
+			StepType:   r.StepType,
+			Success:    r.Success,
+			Output:     r.Output,
+			Duration:   r.Duration,
+			Resources:  r.Resources,
+			HTTPStatus: r.HTTPStatus,
+			Latency:    r.Latency,
+			Records:    r.Records,
+			TimedOut:   r.TimedOut,
+			Skipped:    r.Skipped,
+			SkipReason: r.SkipReason,
+			Captured:   r.Captured,
+			restored:   true,
+		}
+		e.results = append(e.results, result)
+	}
+	if cp.Outputs != nil {
+		e.outputs = cp.Outputs
+	}
+	if cp.Workspace != "" && e.executor != nil {
+		e.executor.ResumeWorkspace(cp.Workspace)
+	}
+}
+
+// writeCheckpoint saves the current progress; e.mu must be held
+func (e *Executor) writeCheckpoint() error {
+	if e.checkpointPath == "" {
+		return nil
+	}
+
+	cp := Checkpoint{
+		FlowPath:   e.flow.Path,
+		FlowName:   e.flow.Name,
+		FlowHash:   e.flowHash,
+		WorkingDir: e.flow.WorkingDir,
+		Workspace:  e.Workspace(),
+		Results:    make([]CheckpointResult, len(e.results)),
+		Outputs:    make(map[string]interface{}, len(e.outputs)),
+		Updated:    time.Now(),
+	}
+	for name, value := range e.outputs {
+		if !e.sensitiveOutputs[name] {
+			cp.Outputs[name] = value
+		}
+	}
+	steps := make(map[string]Step, len(e.flow.Steps))
+	for _, step := range e.flow.Steps {
+		steps[step.Name] = step
+	}
+	mask := e.secretMasker()
+	for i, r := range e.results {
+		cp.Results[i] = CheckpointResult{
+			StepName:   r.StepName,
+			StepType:   r.StepType,
+			Success:    r.Success,
+			Output:     mask.Replace(r.Output),
+			Duration:   r.Duration,
+			Resources:  r.Resources,
+			HTTPStatus: r.HTTPStatus,
+			Latency:    r.Latency,
+			Records:    r.Records,
+			TimedOut:   r.TimedOut,
+			Skipped:    r.Skipped,
+			SkipReason: r.SkipReason,
+		}
+		for name, value := range r.Captured {
+			if steps[r.StepName].Capture[name].Sensitive {
+				cp.Results[i].Redacted = true
+				continue
+			}
+			if cp.Results[i].Captured == nil {
+				cp.Results[i].Captured = make(map[string]interface{})
+			}
+			cp.Results[i].Captured[name] = value
+		}
+		if r.Error != nil {
+			cp.Results[i].Error = mask.Replace(r.Error.Error())
+		}
+	}
+
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(e.checkpointPath), 0755); err != nil {
+		return fmt.Errorf("failed to create checkpoint directory: %w", err)
+	}
+
+	// Write to a temporary file first, so a kill mid-write never leaves a torn
+	// checkpoint. Outputs may still hold secrets, so only the user can read it.
+	tmp := e.checkpointPath + ".tmp"
+	os.Remove(tmp) // a leftover file would keep its permissions
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp, e.checkpointPath); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return nil
+}
+
+// secretMasker returns a replacer that masks the sensitive captured values and
+// terraform outputs in text, such as step output; e.mu must be held
+func (e *Executor) secretMasker() *strings.Replacer {
+	var secrets []string
+	steps := make(map[string]Step, len(e.flow.Steps))
+	for _, step := range e.flow.Steps {
+		steps[step.Name] = step
+	}
+	for _, r := range e.results {
+		for name, value := range r.Captured {
+			if s, ok := value.(string); ok && s != "" && steps[r.StepName].Capture[name].Sensitive {
+				secrets = append(secrets, s)
+			}
+		}
+	}
+	for name := range e.sensitiveOutputs {
+		if s, ok := e.outputs[name].(string); ok && s != "" {
+			secrets = append(secrets, s)
+		}
+	}
+
+	// Longer secrets first, so one containing another is masked whole
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+	pairs := make([]string, 0, 2*len(secrets))
+	for _, s := range secrets {
+		pairs = append(pairs, s, maskedValue)
+	}
+	return strings.NewReplacer(pairs...)
+}
+
+// saveCheckpoint writes the checkpoint after a step finished, warning on
+// failure instead of failing the flow; e.mu must be held
+func (e *Executor) saveCheckpoint() {
+	if err := e.writeCheckpoint(); err != nil {
+		ui.PrintWarning(fmt.Sprintf("⚠️  %v", err))
+	}
+}
+
+// restoredSteps returns the steps that passed before a resume
+func (e *Executor) restoredSteps() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var passed []string
+	for _, r := range e.results {
+		if r.restored {
+			passed = append(passed, r.StepName)
+		}
+	}
+	return passed
+}
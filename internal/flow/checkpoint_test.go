@@ -0,0 +1,210 @@
+package flow
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/infratest/infratest/internal/terraform"
+)
+
+func TestCheckpointResume(t *testing.T) {
+	var mu sync.Mutex
+	hits := make(map[string]int)
+	healthy := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		hits[r.URL.Path]++
+		if r.URL.Path == "/flaky" && !healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	flowYAML := `name: resume
+working_dir: .
+steps:
+  - name: first
+    type: http
+    url: ` + server.URL + `/ok
+    expected_status: 200
+  - name: second
+    type: http
+    url: ` + server.URL + `/flaky
+    expected_status: 200
+    retries: 1
+    delay: 10ms
+`
+	writeFiles(t, dir, map[string]string{"flow.yaml": flowYAML})
+	f, err := ParseFlow(filepath.Join(dir, "flow.yaml"))
+	if err != nil {
+		t.Fatalf("ParseFlow() error: %v", err)
+	}
+
+	path := CheckpointPath(f, "")
+	e := &Executor{flow: f, outputs: map[string]interface{}{}}
+	if err := e.EnableCheckpoint(path); err != nil {
+		t.Fatalf("EnableCheckpoint() error: %v", err)
+	}
+	if err := e.ExecuteWithContext(context.Background()); err == nil {
+		t.Fatal("ExecuteWithContext() expected error from flaky step")
+	}
+
+	cp, err := LoadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("LoadCheckpoint() error: %v", err)
+	}
+	if len(cp.Results) != 2 || !cp.Results[0].Success || cp.Results[1].Success || cp.Results[1].Error == "" {
+		t.Fatalf("Unexpected checkpoint results: %+v", cp.Results)
+	}
+
+	// Resume once the flaky endpoint recovered; the passed step must not run again
+	mu.Lock()
+	healthy = true
+	mu.Unlock()
+
+	run, err := cp.Flow()
+	if err != nil {
+		t.Fatalf("Checkpoint.Flow() error: %v", err)
+	}
+	resumed := &Executor{flow: run, outputs: map[string]interface{}{}}
+	resumed.Restore(cp)
+	if err := resumed.EnableCheckpoint(path); err != nil {
+		t.Fatalf("EnableCheckpoint() error: %v", err)
+	}
+	if err := resumed.ExecuteWithContext(context.Background()); err != nil {
+		t.Fatalf("ExecuteWithContext() after resume error: %v", err)
+	}
+
+	if hits["/ok"] != 1 {
+		t.Errorf("Expected passed step to run once, got %d requests", hits["/ok"])
+	}
+	results := resumed.GetResults()
+	if len(results) != 2 || !results[0].Success || !results[1].Success {
+		t.Errorf("Unexpected results after resume: %+v", results)
+	}
+
+	if err := resumed.RemoveCheckpoint(); err != nil {
+		t.Fatalf("RemoveCheckpoint() error: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected checkpoint to be removed, stat error: %v", err)
+	}
+}
+
+func TestCheckpointLeavesOutSecrets(t *testing.T) {
+	f := &Flow{Name: "secrets", WorkingDir: t.TempDir(), Steps: []Step{
+		{Name: "login", Type: "http", Capture: map[string]CaptureSpec{
+			"token":   {JSONPath: "$.token", Sensitive: true},
+			"user_id": {JSONPath: "$.user.id"},
+		}},
+		{Name: "check", Type: "http"},
+	}, VarOverrides: map[string]interface{}{"db_password": "s3cret"}}
+	e := &Executor{flow: f, outputs: map[string]interface{}{}}
+	e.setOutputs(map[string]terraform.Output{
+		"vpc_id":      {Value: "vpc-1"},
+		"db_password": {Value: "hunter2", Sensitive: true},
+	})
+
+	path := CheckpointPath(f, "")
+	if err := e.EnableCheckpoint(path); err != nil {
+		t.Fatalf("EnableCheckpoint() error: %v", err)
+	}
+	e.recordResult(StepResult{StepName: "login", StepType: "http", Success: true, Output: `{"token": "abc123"}`,
+		Captured: map[string]interface{}{"token": "abc123", "user_id": float64(7)}})
+	e.recordResult(StepResult{StepName: "check", StepType: "http", Success: true, Output: "password=hunter2 token=abc123"})
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat() error: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("Checkpoint permissions = %o, want 600", perm)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error: %v", err)
+	}
+	if strings.Contains(string(data), "hunter2") || strings.Contains(string(data), "abc123") || strings.Contains(string(data), "s3cret") {
+		t.Errorf("Checkpoint contains sensitive values:\n%s", data)
+	}
+
+	cp, err := LoadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("LoadCheckpoint() error: %v", err)
+	}
+	if cp.Outputs["vpc_id"] != "vpc-1" || !cp.Results[0].Redacted || cp.Results[0].Captured["user_id"] != float64(7) {
+		t.Errorf("Unexpected checkpoint: %+v", cp)
+	}
+	if got := cp.Results[1].Output; got != "password=(sensitive) token=(sensitive)" {
+		t.Errorf("Checkpoint output = %q, want secrets masked", got)
+	}
+
+	// The step with sensitive captures runs again on resume, to capture them again
+	resumed := &Executor{flow: f, outputs: map[string]interface{}{}}
+	resumed.Restore(cp)
+	if got := resumed.restoredSteps(); len(got) != 1 || got[0] != "check" {
+		t.Errorf("restoredSteps() = %v, want [check]", got)
+	}
+}
+
+func TestCheckpointPath(t *testing.T) {
+	f := &Flow{Name: "simple vpc [us-east-1]", WorkingDir: "/tf"}
+	if got, want := CheckpointPath(f, ""), filepath.Join("/tf", ".infratest", "simple_vpc_us-east-1.checkpoint.json"); got != want {
+		t.Errorf("CheckpointPath() = %s, want %s", got, want)
+	}
+
+	// Isolated runs of the same flow don't share a checkpoint
+	a := CheckpointPath(f, "infratest-simple-vpc-20240101-120000-1a2b3c")
+	b := CheckpointPath(f, "infratest-simple-vpc-20240101-120000-4d5e6f")
+	if a == b || filepath.Base(a) != "infratest-simple-vpc-20240101-120000-1a2b3c.checkpoint.json" {
+		t.Errorf("CheckpointPath() = %s and %s, want one per workspace", a, b)
+	}
+}
+
+func TestCheckpointFlowChanged(t *testing.T) {
+	dir := t.TempDir()
+	flowYAML := "name: changed\nworking_dir: .\nsteps:\n  - name: check\n    type: http\n    url: http://localhost/\n    expected_status: 200\n"
+	writeFiles(t, dir, map[string]string{"flow.yaml": flowYAML})
+	f, err := ParseFlow(filepath.Join(dir, "flow.yaml"))
+	if err != nil {
+		t.Fatalf("ParseFlow() error: %v", err)
+	}
+
+	path := CheckpointPath(f, "")
+	e := &Executor{flow: f, outputs: map[string]interface{}{}}
+	if err := e.EnableCheckpoint(path); err != nil {
+		t.Fatalf("EnableCheckpoint() error: %v", err)
+	}
+	cp, err := LoadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("LoadCheckpoint() error: %v", err)
+	}
+	if _, err := cp.Flow(); err != nil {
+		t.Fatalf("Checkpoint.Flow() error on unchanged flow: %v", err)
+	}
+
+	writeFiles(t, dir, map[string]string{"flow.yaml": strings.Replace(flowYAML, "200", "204", 1)})
+	if _, err := cp.Flow(); err == nil || !strings.Contains(err.Error(), "changed since the checkpoint") {
+		t.Errorf("Checkpoint.Flow() expected changed flow error, got %v", err)
+	}
+
+	// Cleanup still runs, against the recorded working directory
+	cp.WorkingDir = "/recorded/terraform"
+	run, err := cp.CleanupFlow()
+	if err != nil {
+		t.Fatalf("Checkpoint.CleanupFlow() error on changed flow: %v", err)
+	}
+	if run.WorkingDir != "/recorded/terraform" {
+		t.Errorf("CleanupFlow() working dir = %s, want /recorded/terraform", run.WorkingDir)
+	}
+}
@@ -6,12 +6,33 @@ import (
 	"os"
 	"os/signal"
 	"runtime"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/infratest/infratest/internal/ui"
 )
 
+// active counts running cleanup managers. Every flow of a suite has its own
+// manager; on interrupt the process only exits once all of them are done.
+var active = struct {
+	sync.Mutex
+	done        *sync.Cond
+	count       int
+	interrupted bool
+}{}
+
+func init() {
+	active.done = sync.NewCond(&active.Mutex)
+}
+
+// Interrupted reports whether a SIGINT or SIGTERM has been received
+func Interrupted() bool {
+	active.Lock()
+	defer active.Unlock()
+	return active.interrupted
+}
+
 // CleanupManager handles cleanup operations with signal handling
 type CleanupManager struct {
 	executor   *Executor
@@ -21,6 +42,8 @@ type CleanupManager struct {
 	timeout    time.Duration
 	debug      bool
 	interrupted bool
+	cleanupMu  sync.Mutex // serializes RunCleanup between the signal handler and the caller
+	finishOnce sync.Once
 }
 
 // NewCleanupManager creates a new cleanup manager
@@ -47,6 +70,10 @@ func (cm *CleanupManager) Start() {
 	// Setup panic recovery
 	defer cm.recoverPanic()
 	
+	active.Lock()
+	active.count++
+	active.Unlock()
+	
 	// Monitor for signals in a goroutine
 	go cm.monitorSignals()
 }
@@ -55,6 +82,17 @@ func (cm *CleanupManager) Start() {
 func (cm *CleanupManager) Stop() {
 	cm.cancel()
 	signal.Stop(cm.cleanupCh)
+	cm.finish()
+}
+
+// finish marks the manager as done with its flow and cleanup
+func (cm *CleanupManager) finish() {
+	cm.finishOnce.Do(func() {
+		active.Lock()
+		active.count--
+		active.done.Broadcast()
+		active.Unlock()
+	})
 }
 
 // Context returns the context
@@ -64,6 +102,9 @@ func (cm *CleanupManager) Context() context.Context {
 
 // RunCleanup runs cleanup steps (steps with when: always)
 func (cm *CleanupManager) RunCleanup() error {
+	cm.cleanupMu.Lock()
+	defer cm.cleanupMu.Unlock()
+	
 	if cm.interrupted {
 		ui.PrintWarning("\n⚠️  Cleanup triggered by interrupt (SIGINT/SIGTERM) — attempting destroy...")
 		ui.PrintWarning(fmt.Sprintf("   Cleanup timeout: %v", cm.timeout))
@@ -83,7 +124,14 @@ func (cm *CleanupManager) RunCleanup() error {
 		stepMap[flow.Steps[i].Name] = &flow.Steps[i]
 	}
 	
+	// Cleanup runs whatever happened before it: steps that failed, never ran
+	// or were killed mid-run count as finished for `after`
 	executed := make(map[string]bool)
+	for _, step := range flow.Steps {
+		if step.When != "always" {
+			executed[step.Name] = true
+		}
+	}
 	results := cm.executor.GetResults()
 	for _, result := range results {
 		executed[result.StepName] = true
@@ -164,14 +212,20 @@ func (cm *CleanupManager) showManualDestroyInstructions(workingDir string, error
 		fmt.Println()
 	}
 	
+	// Resources of an isolated run live in its own workspace
+	prefix := ""
+	if ws := cm.executor.Workspace(); ws != "" {
+		prefix = "TF_WORKSPACE=" + ws + " "
+	}
+	
 	ui.PrintInfo("To manually destroy resources, run:")
 	fmt.Printf("  cd %s\n", workingDir)
-	fmt.Printf("  terraform destroy -auto-approve\n")
+	fmt.Printf("  %sterraform destroy -auto-approve\n", prefix)
 	fmt.Println()
 	
 	ui.PrintInfo("Or if using LocalStack:")
 	fmt.Printf("  cd %s\n", workingDir)
-	fmt.Printf("  AWS_ENDPOINT_URL=http://localhost:4566 terraform destroy -auto-approve\n")
+	fmt.Printf("  %sAWS_ENDPOINT_URL=http://localhost:4566 terraform destroy -auto-approve\n", prefix)
 	fmt.Println()
 	
 	ui.PrintWarning("═══════════════════════════════════════════════════════════")
@@ -181,6 +235,9 @@ func (cm *CleanupManager) monitorSignals() {
 	select {
 	case sig := <-cm.cleanupCh:
 		cm.interrupted = true
+		active.Lock()
+		active.interrupted = true
+		active.Unlock()
 		sigName := "SIGINT"
 		if sig == syscall.SIGTERM {
 			sigName = "SIGTERM"
@@ -194,6 +251,17 @@ func (cm *CleanupManager) monitorSignals() {
 			ui.PrintError("Cleanup failed: %v", err)
 			// Manual instructions already shown in RunCleanup
 		}
+		if err := cm.executor.ReleaseWorkspace(context.Background()); err != nil {
+			ui.PrintWarning(fmt.Sprintf("⚠️  %v", err))
+		}
+		
+		// Wait for the cleanup of other flows running in the same suite
+		cm.finish()
+		active.Lock()
+		for active.count > 0 {
+			active.done.Wait()
+		}
+		active.Unlock()
 		
 		os.Exit(130) // Standard exit code for SIGINT
 	case <-cm.ctx.Done():
@@ -0,0 +1,47 @@
+package flow
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunCleanupAfterUnfinishedSteps(t *testing.T) {
+	var destroyed int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/destroy" {
+			atomic.AddInt32(&destroyed, 1)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// Like `infratest cleanup` after a run killed during apply: the checkpoint
+	// has no result for apply, and smoke never ran
+	f := &Flow{
+		Name:       "crashed",
+		WorkingDir: t.TempDir(),
+		Steps: []Step{
+			{Name: "apply", Type: "http", URL: server.URL + "/apply", ExpectedStatus: 200},
+			{Name: "smoke", Type: "http", URL: server.URL + "/smoke", ExpectedStatus: 200, After: []string{"apply"}},
+			{Name: "destroy", Type: "http", URL: server.URL + "/destroy", ExpectedStatus: 200, Retries: 1, Delay: "10ms", When: "always", After: []string{"smoke"}},
+		},
+	}
+	if err := validateFlow(f); err != nil {
+		t.Fatalf("validateFlow() error: %v", err)
+	}
+	e := &Executor{flow: f, outputs: map[string]interface{}{}}
+	e.Restore(&Checkpoint{})
+
+	cm := NewCleanupManager(e, time.Minute, false)
+	cm.Start()
+	defer cm.Stop()
+	if err := cm.RunCleanup(); err != nil {
+		t.Fatalf("RunCleanup() error: %v", err)
+	}
+	if atomic.LoadInt32(&destroyed) != 1 || !e.CleanedUp() {
+		t.Errorf("Expected destroy to run once and pass, got %d request(s), results %+v", destroyed, e.GetResults())
+	}
+}
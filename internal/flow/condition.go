@@ -0,0 +1,175 @@
+package flow
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/infratest/infratest/internal/flow/expression"
+)
+
+// conditionKeywords are the `when` shorthands that predate expressions
+var conditionKeywords = map[string]bool{
+	"always":     true,
+	"on-success": true,
+	"on-failure": true,
+}
+
+// conditionRoots are the namespaces a `when` expression may reference
+var conditionRoots = map[string]bool{
+	"output": true, // terraform outputs
+	"steps":  true, // results of other steps
+	"env":    true, // process environment variables
+	"matrix": true, // values of the current matrix run
+	"var":    true, // flow variables and --var overrides
+}
+
+// stepFields are the attributes exposed for each step under steps.<name>
+var stepFields = map[string]bool{
+	"success":  true,
+	"failed":   true,
+	"skipped":  true,
+	"status":   true,
+	"duration": true,
+}
+
+// parseCondition parses a step's `when` expression. It returns nil for an
+// empty condition or one of the keyword shorthands.
+func parseCondition(step Step) (*expression.Expression, error) {
+	when := strings.TrimSpace(step.When)
+	if when == "" || conditionKeywords[when] {
+		return nil, nil
+	}
+	return expression.Parse(when)
+}
+
+// validateCondition checks that a step's `when` expression parses and only
+// references known namespaces, steps and fields
+func validateCondition(step Step, byName map[string]Step) error {
+	cond, err := parseCondition(step)
+	if err != nil {
+		return fmt.Errorf("step %s: invalid when expression %q: %w", step.Name, step.When, err)
+	}
+	if cond == nil {
+		return nil
+	}
+
+	for _, ref := range cond.References() {
+		root, rest, _ := strings.Cut(ref, ".")
+		if !conditionRoots[root] {
+			return fmt.Errorf("step %s: when expression references unknown namespace %q (expected output, steps, env, matrix or var)", step.Name, root)
+		}
+		if root == "var" {
+			// --var may set variables the flow doesn't declare, so only the name is checked
+			name := rest
+			if i := strings.IndexAny(rest, ".["); i >= 0 {
+				name = rest[:i]
+			}
+			if !varNameRegex.MatchString(name) {
+				return fmt.Errorf("step %s: when expression references invalid variable var.%s", step.Name, rest)
+			}
+			continue
+		}
+		if root != "steps" {
+			continue
+		}
+
+		name, field, _ := strings.Cut(rest, ".")
+		ref, ok := byName[name]
+		if !ok {
+			return fmt.Errorf("step %s: when expression references unknown step %s", step.Name, name)
+		}
+		if name == step.Name {
+			return fmt.Errorf("step %s: when expression references the step itself", step.Name)
+		}
+		field, _, _ = strings.Cut(field, ".")
+		if _, captured := ref.Capture[field]; !stepFields[field] && !captured {
+			return fmt.Errorf("step %s: when expression references unknown field steps.%s.%s (expected success, failed, skipped, status, duration or a captured value)", step.Name, name, field)
+		}
+	}
+	return nil
+}
+
+// conditionSteps returns the steps referenced by a `when` expression. Those
+// steps must finish before the condition can be evaluated.
+func conditionSteps(step Step) []string {
+	cond, err := parseCondition(step)
+	if err != nil || cond == nil {
+		return nil
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, ref := range cond.References() {
+		root, rest, _ := strings.Cut(ref, ".")
+		if root != "steps" {
+			continue
+		}
+		name, _, _ := strings.Cut(rest, ".")
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// conditionEnv builds the namespaces a `when` expression is evaluated against
+func (e *Executor) conditionEnv(status map[string]stepStatus) expression.MapEnv {
+	results := e.GetResults()
+	steps := make(map[string]interface{}, len(e.flow.Steps))
+	for _, s := range e.flow.Steps {
+		fields := map[string]interface{}{
+			"success":  false,
+			"failed":   false,
+			"skipped":  false,
+			"status":   statusName(status[s.Name]),
+			"duration": time.Duration(0),
+		}
+		for _, r := range results {
+			if r.StepName != s.Name {
+				continue
+			}
+			fields["success"] = r.Success
+			fields["failed"] = !r.Success && !r.Skipped
+			fields["skipped"] = r.Skipped
+			fields["duration"] = r.Duration
+			for k, v := range r.Captured {
+				fields[k] = v
+			}
+		}
+		steps[s.Name] = fields
+	}
+
+	env := make(map[string]interface{})
+	for _, kv := range os.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			env[k] = v
+		}
+	}
+
+	return expression.MapEnv{
+		"output": e.currentOutputs(),
+		"steps":  steps,
+		"env":    env,
+		"matrix": e.flow.MatrixValues,
+		"var":    e.flowVariables(),
+	}
+}
+
+// statusName returns the name of a step status as seen by `when` expressions
+func statusName(s stepStatus) string {
+	switch s {
+	case statusRunning:
+		return "running"
+	case statusPassed:
+		return "passed"
+	case statusFailed:
+		return "failed"
+	case statusSkipped, statusBlocked:
+		return "skipped"
+	default:
+		return "pending"
+	}
+}
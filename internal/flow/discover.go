@@ -0,0 +1,107 @@
+package flow
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Discover expands flow files, directories and glob patterns into a sorted
+// list of flow files. Directories are searched recursively for YAML files
+// that look like flows; include fragments and other YAML files are ignored.
+// Files named explicitly are always returned.
+func Discover(args []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var files []string
+	add := func(path string) {
+		path = filepath.Clean(path)
+		if !seen[path] {
+			seen[path] = true
+			files = append(files, path)
+		}
+	}
+
+	for _, arg := range args {
+		matches := []string{arg}
+		if strings.ContainsAny(arg, "*?[") {
+			var err error
+			matches, err = filepath.Glob(arg)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %s: %w", arg, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no files match %s", arg)
+			}
+		}
+
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				return nil, err
+			}
+			if !info.IsDir() {
+				// Explicit files are run as given; globbed files must look like flows
+				if match == arg || IsFlowFile(match) {
+					add(match)
+				}
+				continue
+			}
+
+			var found []string
+			err = filepath.WalkDir(match, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if d.IsDir() {
+					// Skip hidden directories and terraform's own data
+					if path != match && strings.HasPrefix(d.Name(), ".") {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if IsFlowFile(path) {
+					found = append(found, path)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			sort.Strings(found)
+			for _, path := range found {
+				add(path)
+			}
+		}
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no flow files found in %s", strings.Join(args, ", "))
+	}
+	return files, nil
+}
+
+// IsFlowFile reports whether a file is a YAML document with the top-level
+// keys of a flow (working_dir and steps)
+func IsFlowFile(path string) bool {
+	ext := filepath.Ext(path)
+	if ext != ".yaml" && ext != ".yml" {
+		return false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+
+	var doc map[string]yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return false
+	}
+	_, hasDir := doc["working_dir"]
+	_, hasSteps := doc["steps"]
+	return hasDir && hasSteps
+}
@@ -0,0 +1,72 @@
+package flow
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDiscover(t *testing.T) {
+	dir := t.TempDir()
+	flowYAML := "name: x\nworking_dir: ./terraform\nsteps:\n  - name: apply\n    type: terraform\n    command: apply\n"
+	writeFiles(t, dir, map[string]string{
+		"vpc/flow.yaml":           flowYAML,
+		"alb/flow.yml":            flowYAML,
+		"common/terraform.yaml":   "params:\n  plan_file: plan.tfplan\nsteps:\n  - name: plan\n    type: terraform\n    command: plan\n",
+		"notes.txt":               "working_dir: .\nsteps: []\n",
+		".hidden/flow.yaml":       flowYAML,
+		"vpc/terraform/vars.yaml": "region: us-east-1\n",
+	})
+	path := func(rel string) string { return filepath.Join(dir, rel) }
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "directory",
+			args: []string{dir},
+			want: []string{path("alb/flow.yml"), path("vpc/flow.yaml")},
+		},
+		{
+			name: "glob",
+			args: []string{filepath.Join(dir, "vpc", "*.yaml")},
+			want: []string{path("vpc/flow.yaml")},
+		},
+		{
+			name: "explicit files are kept as given",
+			args: []string{path("common/terraform.yaml"), path("vpc/flow.yaml"), path("vpc/flow.yaml")},
+			want: []string{path("common/terraform.yaml"), path("vpc/flow.yaml")},
+		},
+		{
+			name:    "glob without matches",
+			args:    []string{filepath.Join(dir, "*.json")},
+			wantErr: true,
+		},
+		{
+			name:    "directory without flows",
+			args:    []string{path("common")},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Discover(tt.args)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Discover() expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Discover() error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Discover() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package flow
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/infratest/infratest/internal/dns"
+	"github.com/infratest/infratest/internal/terraform"
+	"github.com/infratest/infratest/internal/ui"
+)
+
+// validateDNS checks the fields of a dns step, and that other steps don't use them
+func validateDNS(step Step) error {
+	if step.Type != "dns" {
+		if step.Hostname != "" || step.RecordType != "" || step.Resolver != "" || len(step.Records) > 0 || step.RecordsInOutput != "" {
+			return fmt.Errorf("hostname, record_type, resolver, records and records_in_output are only supported on dns steps")
+		}
+		return nil
+	}
+
+	if step.RecordType != "" {
+		known := false
+		for _, t := range dns.RecordTypes {
+			known = known || strings.EqualFold(step.RecordType, t)
+		}
+		if !known {
+			return fmt.Errorf("unsupported record_type %q (expected %s)", step.RecordType, strings.Join(dns.RecordTypes, ", "))
+		}
+	}
+	if step.Resolver != "" && !strings.Contains(step.Resolver, "${") {
+		if _, _, err := net.SplitHostPort(step.Resolver); err != nil {
+			return fmt.Errorf("invalid resolver: %w", err)
+		}
+	}
+	return nil
+}
+
+// dnsQuery builds the lookup of a dns step, with its fields interpolated and
+// the allowed values read from records_in_output
+func (e *Executor) dnsQuery(step Step) (dns.Query, error) {
+	q := dns.Query{
+		Name:   e.interpolate(step.Hostname),
+		Type:   step.RecordType,
+		Server: e.interpolate(step.Resolver),
+	}
+	for _, record := range step.Records {
+		q.Records = append(q.Records, e.interpolate(record))
+	}
+
+	if step.RecordsInOutput == "" {
+		return q, nil
+	}
+	val, err := terraform.GetOutputValue(e.currentOutputs(), e.interpolate(step.RecordsInOutput))
+	if err != nil {
+		return q, fmt.Errorf("records_in_output: %w", err)
+	}
+	values, ok := val.([]interface{})
+	if !ok {
+		values = []interface{}{val}
+	}
+	for _, v := range values {
+		q.Allowed = append(q.Allowed, fmt.Sprintf("%v", v))
+	}
+	return q, nil
+}
+
+// executeDNSStep resolves the name of a dns step until the expected records
+// show up, and returns the resolved records
+func (e *Executor) executeDNSStep(ctx context.Context, step Step) ([]string, error) {
+	// Refresh outputs so the query sees the latest values
+	if err := e.refreshOutputs(ctx); err != nil {
+		ui.PrintDebug(e.debug, "Warning: failed to refresh outputs: %v", err)
+	}
+
+	q, err := e.dnsQuery(step)
+	if err != nil {
+		return nil, err
+	}
+	ui.PrintDebug(e.debug, "Resolving %s %s (resolver: %s)", step.RecordType, e.interpolateMasked(step.Hostname), e.interpolateMasked(step.Resolver))
+
+	retries, delay := checkRetries(step)
+	return dns.LookupWithRetryContext(ctx, q, retries, delay, e.debug)
+}
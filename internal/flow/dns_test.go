@@ -0,0 +1,58 @@
+package flow
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateDNS(t *testing.T) {
+	tests := []struct {
+		name    string
+		step    Step
+		wantErr string
+	}{
+		{name: "defaults", step: Step{Type: "dns", Hostname: "api.example.com"}},
+		{name: "resolver", step: Step{Type: "dns", Hostname: "api.example.com", RecordType: "cname", Resolver: "127.0.0.1:53"}},
+		{name: "resolver placeholder", step: Step{Type: "dns", Hostname: "api.example.com", Resolver: "${output.dns_ip}"}},
+		{name: "unknown record type", step: Step{Type: "dns", Hostname: "example.com", RecordType: "MX"}, wantErr: `unsupported record_type "MX"`},
+		{name: "resolver without port", step: Step{Type: "dns", Hostname: "example.com", Resolver: "127.0.0.1"}, wantErr: "invalid resolver"},
+		{name: "hostname on other step", step: Step{Type: "http", URL: "http://x", Hostname: "x"}, wantErr: "only supported on dns steps"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateDNS(tt.step)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateDNS() error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateDNS() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDNSQuery(t *testing.T) {
+	e := &Executor{
+		flow: &Flow{},
+		outputs: map[string]interface{}{
+			"zone":         "example.com",
+			"instance_ips": []interface{}{"10.0.0.1", "10.0.0.2"},
+		},
+	}
+
+	q, err := e.dnsQuery(Step{Type: "dns", Hostname: "api.${output.zone}", Records: StringList{"${output.instance_ips[0]}"}, RecordsInOutput: "instance_ips"})
+	if err != nil {
+		t.Fatalf("dnsQuery() error: %v", err)
+	}
+	if q.Name != "api.example.com" || strings.Join(q.Records, ",") != "10.0.0.1" || strings.Join(q.Allowed, ",") != "10.0.0.1,10.0.0.2" {
+		t.Errorf("Unexpected query: %+v", q)
+	}
+
+	if _, err := e.dnsQuery(Step{Type: "dns", Hostname: "api", RecordsInOutput: "missing"}); err == nil {
+		t.Error("Expected an error for a missing records_in_output")
+	}
+}
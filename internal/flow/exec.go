@@ -0,0 +1,203 @@
+package flow
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/infratest/infratest/internal/command"
+	"github.com/infratest/infratest/internal/jsonpath"
+	"github.com/infratest/infratest/internal/ui"
+)
+
+// validateExec checks the fields of an exec step, and that other steps don't use them
+func validateExec(step Step) error {
+	if step.Type != "exec" {
+		if len(step.Args) > 0 || len(step.Env) > 0 || step.Workdir != "" || step.Expect != nil {
+			return fmt.Errorf("args, env, workdir and expect are only supported on exec steps")
+		}
+		return nil
+	}
+
+	if _, err := command.Split(step.Command); err != nil {
+		return fmt.Errorf("invalid command: %w", err)
+	}
+	for name := range step.Env {
+		if name == "" || strings.ContainsAny(name, "= ") {
+			return fmt.Errorf("invalid env variable name %q", name)
+		}
+	}
+	if step.Expect == nil {
+		return nil
+	}
+	for _, pattern := range append(append([]string(nil), step.Expect.StdoutMatches...), step.Expect.StderrMatches...) {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("expect: invalid regex %q: %w", pattern, err)
+		}
+	}
+	for path := range step.Expect.JSON {
+		if _, err := jsonpath.Parse(path); err != nil {
+			return fmt.Errorf("expect: json: %w", err)
+		}
+	}
+	return nil
+}
+
+// execCommand builds the command of an exec step, with arguments, environment
+// and working directory resolved by interpolate. The command is split before
+// it's interpolated, so values with spaces or quotes stay single arguments.
+func (e *Executor) execCommand(step Step, interpolate func(string) string) (command.Command, error) {
+	parts, err := command.Split(step.Command)
+	if err != nil {
+		return command.Command{}, err
+	}
+	if len(parts) == 0 {
+		return command.Command{}, fmt.Errorf("empty command")
+	}
+	for i, part := range parts {
+		parts[i] = interpolate(part)
+	}
+	for _, arg := range step.Args {
+		parts = append(parts, interpolate(arg))
+	}
+
+	cmd := command.Command{Name: parts[0], Args: parts[1:], Dir: e.flow.WorkingDir}
+	if step.Workdir != "" {
+		cmd.Dir = interpolate(step.Workdir)
+	}
+	for _, name := range sortedNames(step.Env) {
+		cmd.Env = append(cmd.Env, name+"="+interpolate(step.Env[name]))
+	}
+	return cmd, nil
+}
+
+// executeExecStep runs the command of an exec step and checks its expectations
+func (e *Executor) executeExecStep(ctx context.Context, step Step) (command.Result, error) {
+	// Refresh outputs so the command sees the latest values
+	if err := e.refreshOutputs(ctx); err != nil {
+		ui.PrintDebug(e.debug, "Warning: failed to refresh outputs: %v", err)
+	}
+
+	cmd, err := e.execCommand(step, e.interpolate)
+	if err != nil {
+		return command.Result{}, err
+	}
+	if e.debug {
+		logged, _ := e.execCommand(step, e.interpolateMasked)
+		ui.PrintDebug(e.debug, "Running: %s", logged)
+		ui.PrintDebug(e.debug, "Working directory: %s", logged.Dir)
+	}
+
+	res, err := cmd.RunContext(ctx)
+	if err != nil {
+		return res, err
+	}
+	return res, checkExecResult(step.Expect, res)
+}
+
+// checkExecResult checks a command's result against the step's expectations.
+// Without expectations, the command must exit with code 0.
+func checkExecResult(expect *ExecExpect, res command.Result) error {
+	if expect == nil {
+		expect = &ExecExpect{}
+	}
+
+	wantCode := 0
+	if expect.ExitCode != nil {
+		wantCode = *expect.ExitCode
+	}
+	if res.ExitCode != wantCode {
+		return fmt.Errorf("exit code %d, expected %d%s", res.ExitCode, wantCode, lastLine(res.Stderr))
+	}
+
+	for _, stream := range []struct {
+		name     string
+		data     []byte
+		contains []string
+		matches  []string
+	}{
+		{"stdout", res.Stdout, expect.StdoutContains, expect.StdoutMatches},
+		{"stderr", res.Stderr, expect.StderrContains, expect.StderrMatches},
+	} {
+		for _, s := range stream.contains {
+			if !bytes.Contains(stream.data, []byte(s)) {
+				return fmt.Errorf("%s does not contain %q", stream.name, s)
+			}
+		}
+		for _, pattern := range stream.matches {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return err
+			}
+			if !re.Match(stream.data) {
+				return fmt.Errorf("%s does not match %q", stream.name, pattern)
+			}
+		}
+	}
+
+	if len(expect.JSON) == 0 {
+		return nil
+	}
+	var doc interface{}
+	if err := json.Unmarshal(res.Stdout, &doc); err != nil {
+		return fmt.Errorf("stdout is not valid JSON: %w", err)
+	}
+	paths := make([]string, 0, len(expect.JSON))
+	for path := range expect.JSON {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		actual, err := jsonpath.Get(doc, path)
+		if err != nil {
+			return fmt.Errorf("json %s: %w", path, err)
+		}
+		if !jsonValuesEqual(expect.JSON[path], actual) {
+			return fmt.Errorf("json %s: expected %v, got %v", path, expect.JSON[path], actual)
+		}
+	}
+	return nil
+}
+
+// jsonValuesEqual compares an expected YAML value with a decoded JSON value.
+// Expected strings match the string form of any value, like inventory
+// attributes; other values are compared after a JSON round trip, so 1 and 1.0
+// are equal.
+func jsonValuesEqual(expected, actual interface{}) bool {
+	if s, ok := expected.(string); ok {
+		return s == fmt.Sprintf("%v", actual)
+	}
+	data, err := json.Marshal(expected)
+	if err != nil {
+		return false
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(normalized, actual)
+}
+
+// sortedNames returns the keys of an env map in sorted order
+func sortedNames(env map[string]string) []string {
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lastLine returns the last non-empty line of a command's stderr as an error suffix
+func lastLine(stderr []byte) string {
+	lines := strings.Split(strings.TrimSpace(string(stderr)), "\n")
+	if last := strings.TrimSpace(lines[len(lines)-1]); last != "" {
+		return ": " + last
+	}
+	return ""
+}
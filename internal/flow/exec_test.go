@@ -0,0 +1,104 @@
+package flow
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/infratest/infratest/internal/command"
+)
+
+func TestCheckExecResult(t *testing.T) {
+	exitCode := func(code int) *int { return &code }
+	res := command.Result{
+		Stdout: []byte(`{"Buckets": [{"Name": "logs", "Versioning": true}], "Count": 1}`),
+		Stderr: []byte("warning: using default region\n"),
+	}
+
+	tests := []struct {
+		name    string
+		expect  *ExecExpect
+		res     command.Result
+		wantErr string
+	}{
+		{name: "no expectations", res: res},
+		{name: "non-zero exit", res: command.Result{ExitCode: 2, Stderr: []byte("usage: aws\nAccess denied\n")}, wantErr: "exit code 2, expected 0: Access denied"},
+		{name: "expected exit code", expect: &ExecExpect{ExitCode: exitCode(2)}, res: command.Result{ExitCode: 2}},
+		{name: "stdout contains", expect: &ExecExpect{StdoutContains: StringList{`"logs"`}}, res: res},
+		{name: "stdout missing substring", expect: &ExecExpect{StdoutContains: StringList{"metrics"}}, res: res, wantErr: `stdout does not contain "metrics"`},
+		{name: "stderr regex", expect: &ExecExpect{StderrMatches: StringList{`default region$`}}, res: res, wantErr: `stderr does not match "default region$"`},
+		{name: "stderr multiline regex", expect: &ExecExpect{StderrMatches: StringList{`(?m)default region$`}}, res: res},
+		{name: "json values", expect: &ExecExpect{JSON: map[string]interface{}{"$.Buckets[0].Name": "logs", "$.Count": 1, "$.Buckets[0].Versioning": true}}, res: res},
+		{name: "json mismatch", expect: &ExecExpect{JSON: map[string]interface{}{"$.Count": 2}}, res: res, wantErr: "json $.Count: expected 2, got 1"},
+		{name: "json not valid", expect: &ExecExpect{JSON: map[string]interface{}{"$.Count": 1}}, res: command.Result{Stdout: []byte("not json")}, wantErr: "stdout is not valid JSON"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkExecResult(tt.expect, tt.res)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("checkExecResult() error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("checkExecResult() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExecuteWithContextExec(t *testing.T) {
+	f := &Flow{
+		Name:       "exec",
+		WorkingDir: t.TempDir(),
+		Matrix:     &Matrix{Variables: map[string][]interface{}{"env": {"dev"}}, Order: []string{"env"}},
+		Steps: []Step{
+			{Name: "describe", Type: "exec", Command: `sh -c 'echo "{\"id\": \"$BUCKET\"}"'`, Env: map[string]string{"BUCKET": "bucket-${matrix.env}"},
+				Capture: map[string]CaptureSpec{"id": {JSONPath: "$.id"}}},
+			{Name: "check", Type: "exec", Command: "echo", Args: []string{"got ${steps.describe.id}", "in"}, Workdir: "/",
+				Expect: &ExecExpect{StdoutContains: StringList{"got bucket-dev in"}}},
+		},
+	}
+	if err := validateFlow(f); err != nil {
+		t.Fatalf("validateFlow() error: %v", err)
+	}
+
+	e := &Executor{flow: f.ExpandMatrix()[0], outputs: map[string]interface{}{}}
+	if err := e.ExecuteWithContext(context.Background()); err != nil {
+		t.Fatalf("ExecuteWithContext() error: %v", err)
+	}
+	results := e.GetResults()
+	if len(results) != 2 || results[0].Captured["id"] != "bucket-dev" {
+		t.Fatalf("Unexpected results: %+v", results)
+	}
+	if results[1].Output != "got bucket-dev in\n" {
+		t.Errorf("Output = %q", results[1].Output)
+	}
+}
+
+func TestExecCommandInterpolatesArguments(t *testing.T) {
+	f := &Flow{Name: "exec", WorkingDir: t.TempDir()}
+	e := &Executor{flow: f, outputs: map[string]interface{}{"msg": `it's a "test"`}}
+
+	cmd, err := e.execCommand(Step{Name: "say", Type: "exec", Command: `echo ${output.msg} "and ${output.msg}"`}, e.interpolate)
+	if err != nil {
+		t.Fatalf("execCommand() error: %v", err)
+	}
+	want := []string{`it's a "test"`, `and it's a "test"`}
+	if !reflect.DeepEqual(cmd.Args, want) {
+		t.Errorf("Args = %q, want %q", cmd.Args, want)
+	}
+
+	// terraform command lines are quoted again, so the value stays one argument
+	line, err := e.interpolateCommand(`apply -var "name=${output.msg}"`)
+	if err != nil {
+		t.Fatalf("interpolateCommand() error: %v", err)
+	}
+	args, err := command.Split(line)
+	if want := []string{"apply", "-var", `name=it's a "test"`}; err != nil || !reflect.DeepEqual(args, want) {
+		t.Errorf("interpolateCommand() = %s, split into %q, %v; want %q", line, args, err, want)
+	}
+}
@@ -2,13 +2,17 @@ package flow
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/infratest/infratest/internal/flow/interpolator"
+	"github.com/infratest/infratest/internal/command"
+	"github.com/infratest/infratest/internal/flow/expression"
 	"github.com/infratest/infratest/internal/http"
 	"github.com/infratest/infratest/internal/inventory"
+	"github.com/infratest/infratest/internal/network"
 	"github.com/infratest/infratest/internal/terraform"
 	"github.com/infratest/infratest/internal/ui"
 )
@@ -19,7 +23,12 @@ type Executor struct {
 	executor   *terraform.Executor
 	results    []StepResult
 	outputs    map[string]interface{}
+	sensitiveOutputs map[string]bool // outputs marked sensitive, left out of checkpoints
 	debug      bool
+	keepWorkspace bool    // keep the isolated workspace after the run
+	checkpointPath string // where progress is saved after every step, if set
+	flowHash      string  // fingerprint of the flow, recorded in checkpoints
+	mu         sync.Mutex // guards results, outputs and console output of concurrent steps
 }
 
 // NewExecutor creates a new flow executor
@@ -28,6 +37,9 @@ func NewExecutor(flow *Flow, debug bool) (*Executor, error) {
 	if err != nil {
 		return nil, err
 	}
+	if flow.Isolation == IsolationWorkspace {
+		executor.UseWorkspace(workspaceName(flow.Name))
+	}
 
 	return &Executor{
 		flow:     flow,
@@ -43,52 +55,227 @@ func (e *Executor) Execute() error {
 	return e.ExecuteWithContext(context.Background())
 }
 
-// ExecuteWithContext runs all steps in the flow with context support
+// ExecuteWithContext runs all steps in the flow with context support.
+// A step starts once all of its dependencies have finished, with at most
+// max_parallel steps in flight at any time.
 func (e *Executor) ExecuteWithContext(ctx context.Context) error {
+	// Apply the flow-level timeout, if any
+	if timeout, err := parseTimeout(e.flow.Timeout); err != nil {
+		return fmt.Errorf("invalid flow timeout: %w", err)
+	} else if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	steps := e.flow.Steps
 	stepMap := make(map[string]*Step)
-	for i := range e.flow.Steps {
-		stepMap[e.flow.Steps[i].Name] = &e.flow.Steps[i]
+	for i := range steps {
+		stepMap[steps[i].Name] = &steps[i]
 	}
 
+	deps := dependencies(steps)
+	status := make(map[string]stepStatus, len(steps))
+	// executed marks steps that reached a terminal status, whether they ran or
+	// were skipped; dependency ordering itself is enforced by the scheduler
 	executed := make(map[string]bool)
+	remaining := len(steps)
+
+	// Steps that passed before a resume don't run again
+	for _, name := range e.restoredSteps() {
+		if _, ok := stepMap[name]; ok && status[name] == statusPending {
+			status[name] = statusPassed
+			executed[name] = true
+			remaining--
+			ui.PrintProgress(e.stepNumber(name), len(steps), name, "OK", "(from checkpoint)")
+		}
+	}
+
+	type completion struct {
+		name string
+		err  error
+	}
+	done := make(chan completion)
+	running := 0
 	hasFailure := false
-	stepNum := 0
-	
-	for _, step := range e.flow.Steps {
-		// Check context cancellation
-		select {
-		case <-ctx.Done():
-			return fmt.Errorf("execution cancelled: %w", ctx.Err())
-		default:
+	var firstErr error
+
+	// evaluate resolves a `when` expression against the current outputs and
+	// the results of the steps that have finished so far
+	evaluate := func(cond *expression.Expression) (bool, error) {
+		for _, ref := range cond.References() {
+			if strings.HasPrefix(ref, "output.") {
+				e.refreshOutputs(ctx)
+				break
+			}
 		}
-		
-		// Check if step should run based on 'when' condition
-		if step.When == "on-success" && hasFailure {
-			ui.PrintDebug(e.debug, "Skipping step %s (when: on-success, but previous step failed)", step.Name)
-			continue
+		return cond.Evaluate(e.conditionEnv(status))
+	}
+
+	// launchReady starts or skips every step whose dependencies have finished.
+	// Skipping a step can unblock others, so it rescans until nothing changes.
+	launchReady := func() {
+		for progressed := true; progressed; {
+			progressed = false
+			for i := range steps {
+				step := steps[i]
+				if status[step.Name] != statusPending || !dependenciesFinished(deps[step.Name], status) {
+					continue
+				}
+
+				run, next, reason := readiness(step, deps[step.Name], status, hasFailure, evaluate)
+				if !run {
+					status[step.Name] = next
+					executed[step.Name] = true
+					remaining--
+					progressed = true
+					if next == statusFailed {
+						// The condition itself couldn't be evaluated
+						hasFailure = true
+						err := e.failStep(step, reason)
+						if firstErr == nil {
+							firstErr = err
+						}
+						continue
+					}
+					e.skipStep(step, reason)
+					continue
+				}
+
+				if running >= e.maxParallel() {
+					continue
+				}
+
+				status[step.Name] = statusRunning
+				running++
+				snapshot := make(map[string]bool, len(executed))
+				for k, v := range executed {
+					snapshot[k] = v
+				}
+				go func(step Step) {
+					err := e.executeStepWithContext(ctx, step, stepMap, snapshot)
+					done <- completion{name: step.Name, err: err}
+				}(step)
+			}
 		}
-		if step.When == "on-failure" && !hasFailure {
-			ui.PrintDebug(e.debug, "Skipping step %s (when: on-failure, but no previous failure)", step.Name)
-			continue
+	}
+
+	for remaining > 0 {
+		if ctx.Err() == nil {
+			launchReady()
+		}
+
+		if running == 0 {
+			if ctx.Err() != nil {
+				return e.interruptedError(ctx)
+			}
+			if remaining > 0 {
+				// Unreachable for a validated flow, but never block forever
+				return fmt.Errorf("no runnable steps left; %d step(s) have unresolved dependencies", remaining)
+			}
+			break
 		}
 
-		stepNum++
-		err := e.executeStepWithContext(ctx, step, stepMap, executed)
-		executed[step.Name] = true
+		c := <-done
+		running--
+		remaining--
+		executed[c.name] = true
 
-		if err != nil {
+		if c.err != nil {
+			status[c.name] = statusFailed
 			hasFailure = true
-			// Check if we should continue based on 'when' condition
-			if step.When == "always" {
-				// Continue even on error
-				ui.PrintDebug(e.debug, "Step %s failed but continuing (when: always)", step.Name)
-				continue
+			if firstErr == nil {
+				firstErr = c.err
 			}
-			return err
+			if stepMap[c.name].When == "always" {
+				ui.PrintDebug(e.debug, "Step %s failed but continuing (when: always)", c.name)
+			}
+			continue
 		}
+		status[c.name] = statusPassed
 	}
 
-	return nil
+	if ctx.Err() != nil && firstErr == nil {
+		return e.interruptedError(ctx)
+	}
+
+	return firstErr
+}
+
+// interruptedError describes why the flow stopped early: either its own
+// timeout expired or the caller cancelled it
+func (e *Executor) interruptedError(ctx context.Context) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) && e.flow.Timeout != "" {
+		return fmt.Errorf("flow timed out after %s: %w", e.flow.Timeout, ctx.Err())
+	}
+	return fmt.Errorf("execution cancelled: %w", ctx.Err())
+}
+
+// parseTimeout parses an optional timeout; an empty string means no timeout
+func parseTimeout(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("timeout must be positive, got %s", value)
+	}
+	return d, nil
+}
+
+// maxParallel returns the configured step concurrency limit
+func (e *Executor) maxParallel() int {
+	if e.flow.MaxParallel > 0 {
+		return e.flow.MaxParallel
+	}
+	return 1
+}
+
+// skipStep records a step that was not run and prints why
+func (e *Executor) skipStep(step Step, reason string) {
+	ui.PrintDebug(e.debug, "Skipping step %s (%s)", step.Name, reason)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.results = append(e.results, StepResult{
+		StepName:   step.Name,
+		StepType:   step.Type,
+		Skipped:    true,
+		SkipReason: reason,
+	})
+	e.saveCheckpoint()
+	ui.PrintProgress(e.stepNumber(step.Name), len(e.flow.Steps), step.Name, "SKIP", reason)
+}
+
+// failStep records a step that failed before it could run
+func (e *Executor) failStep(step Step, reason string) error {
+	err := fmt.Errorf("step %s failed: %s", step.Name, reason)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.results = append(e.results, StepResult{
+		StepName: step.Name,
+		StepType: step.Type,
+		Error:    fmt.Errorf("%s", reason),
+	})
+	e.saveCheckpoint()
+	ui.PrintProgress(e.stepNumber(step.Name), len(e.flow.Steps), step.Name, "FAIL", reason)
+	return err
+}
+
+// stepNumber returns the 1-based position of a step in the flow file
+func (e *Executor) stepNumber(name string) int {
+	for i, s := range e.flow.Steps {
+		if s.Name == name {
+			return i + 1
+		}
+	}
+	return 1
 }
 
 // executeStep is a wrapper for backward compatibility
@@ -103,25 +290,26 @@ func (e *Executor) ExecuteStepWithContext(ctx context.Context, step Step, stepMa
 
 func (e *Executor) executeStepWithContext(ctx context.Context, step Step, stepMap map[string]*Step, executed map[string]bool) error {
 	// Check dependencies
-	if step.After != "" {
-		if !executed[step.After] {
-			return fmt.Errorf("step %s depends on %s which hasn't been executed", step.Name, step.After)
+	for _, dep := range step.After {
+		if !executed[dep] {
+			return fmt.Errorf("step %s depends on %s which hasn't been executed", step.Name, dep)
 		}
 	}
 
 	// Find step number for progress display
-	stepNum := 1
+	stepNum := e.stepNumber(step.Name)
 	totalSteps := len(e.flow.Steps)
-	for i, s := range e.flow.Steps {
-		if s.Name == step.Name {
-			stepNum = i + 1
-			break
-		}
-	}
 
-	// Print step start
+	// Print step start. Concurrent steps get a line of their own so the
+	// progress output of steps running side by side doesn't interleave.
+	e.mu.Lock()
 	ui.PrintStep(stepNum, totalSteps, step.Name)
-	fmt.Print(" ... ")
+	if e.maxParallel() > 1 {
+		fmt.Println(" ... started")
+	} else {
+		fmt.Print(" ... ")
+	}
+	e.mu.Unlock()
 	
 	ui.PrintDebug(e.debug, "Executing step: %s (type: %s)", step.Name, step.Type)
 
@@ -132,7 +320,20 @@ func (e *Executor) executeStepWithContext(ctx context.Context, step Step, stepMa
 	}
 
 	var err error
-	var output string
+
+	// Derive the step's own deadline from its timeout
+	timeout, err := parseTimeout(step.Timeout)
+	if err != nil {
+		return fmt.Errorf("step %s has an invalid timeout: %w", step.Name, err)
+	}
+	if timeout == 0 && step.Type == "wait" {
+		timeout = defaultWaitTimeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
 
 	// Check context timeout before executing step
 	select {
@@ -140,65 +341,212 @@ func (e *Executor) executeStepWithContext(ctx context.Context, step Step, stepMa
 		result.Error = fmt.Errorf("step cancelled: %w", ctx.Err())
 		result.Success = false
 		result.Duration = time.Since(start)
-		e.results = append(e.results, result)
+		e.recordResult(result)
 		return fmt.Errorf("step %s cancelled: %w", step.Name, ctx.Err())
 	default:
 	}
 
+	// Run the step, retrying according to its retry policy
+	for attempt := 1; ; attempt++ {
+		attemptStart := time.Now()
+		err = e.runStep(ctx, step, &result)
+		result.Attempts = append(result.Attempts, AttemptResult{
+			Number:   attempt,
+			Success:  err == nil,
+			Error:    err,
+			Duration: time.Since(attemptStart),
+		})
+
+		if err == nil || !step.Retry.shouldRetry(attempt, err) || ctx.Err() != nil {
+			break
+		}
+
+		delay := step.Retry.backoff(attempt)
+		ui.PrintDebug(e.debug, "Step %s attempt %d/%d failed: %v (retrying in %s)", step.Name, attempt, step.Retry.Attempts, err, delay)
+		if sleepErr := sleepContext(ctx, delay); sleepErr != nil {
+			break
+		}
+	}
+
+	result.Duration = time.Since(start)
+	result.Error = err
+
+	// A step whose deadline expired is reported separately from an ordinary failure
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		result.TimedOut = true
+		if timeout > 0 && result.Duration >= timeout {
+			result.Error = fmt.Errorf("timed out after %s: %w", timeout, err)
+		} else {
+			result.Error = fmt.Errorf("timed out (flow deadline reached): %w", err)
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.results = append(e.results, result)
+	e.saveCheckpoint()
+
+	// Print step result with colored output
+	duration := result.Duration.Round(time.Second).String()
+	if len(result.Attempts) > 1 {
+		duration = fmt.Sprintf("%s (%d attempts)", duration, len(result.Attempts))
+	}
+	if result.TimedOut {
+		ui.PrintProgress(stepNum, totalSteps, step.Name, "TIMEOUT", duration)
+		return fmt.Errorf("step %s %w", step.Name, result.Error)
+	}
+	if err != nil {
+		ui.PrintProgress(stepNum, totalSteps, step.Name, "FAIL", duration)
+		return fmt.Errorf("step %s failed: %w", step.Name, err)
+	}
+	
+	ui.PrintProgress(stepNum, totalSteps, step.Name, "OK", duration)
+
+	return nil
+}
+
+// runStep performs a single attempt of a step, filling in the type-specific
+// fields of the result
+func (e *Executor) runStep(ctx context.Context, step Step, result *StepResult) error {
+	var err error
+
 	switch step.Type {
 	case "terraform":
+		var output string
 		output, err = e.executeTerraformStepWithContext(ctx, step)
 		result.Output = output
-		result.Success = err == nil
+
+	case "terraform-plan":
+		var plan *terraform.Plan
+		plan, result.Output, err = e.executePlanStep(ctx, step)
+		if plan != nil {
+			result.Body = plan.JSON
+		}
+
+	case "terraform-idempotency":
+		var plan *terraform.Plan
+		plan, result.Output, err = e.executeIdempotencyStep(ctx, step)
+		if plan != nil {
+			result.Body = plan.JSON
+		}
+
+	case "terraform-output":
+		result.Output, err = e.executeOutputStep(ctx, step)
 
 	case "terraform-inventory":
-		resources, err2 := e.executeInventoryStep(step)
+		var resources []Resource
+		resources, err = e.executeInventoryStep(ctx, step)
 		result.Resources = resources
-		result.Success = err2 == nil
-		err = err2
 
 	case "http":
-		status, err2 := e.executeHTTPStep(step)
-		result.HTTPStatus = status
-		result.Success = err2 == nil
-		err = err2
+		var resp http.Response
+		resp, err = e.executeHTTPStep(ctx, step)
+		result.HTTPStatus = resp.Status
+		result.Body = resp.Body
+
+	case "tcp", "udp":
+		var res network.Result
+		result.Output, res, err = e.executeNetworkStep(ctx, step)
+		result.Latency = res.Latency
+		result.Body = res.Banner
+
+	case "exec":
+		var res command.Result
+		res, err = e.executeExecStep(ctx, step)
+		result.Output = string(res.Output)
+		result.Body = append([]byte{}, res.Stdout...)
+
+	case "dns":
+		result.Records, err = e.executeDNSStep(ctx, step)
+		result.Output = strings.Join(result.Records, "\n")
+
+	case "wait":
+		var output string
+		output, err = e.executeWaitStep(ctx, step)
+		result.Output = output
 
 	default:
 		err = fmt.Errorf("unknown step type: %s", step.Type)
 	}
 
-	result.Duration = time.Since(start)
-	result.Error = err
+	// Capture values only from a successful attempt, so a failed capture can
+	// be retried like any other failure
+	if err == nil {
+		result.Captured, err = captureValues(step, result)
+		if err == nil && e.debug {
+			for name, val := range step.MaskCaptured(result.Captured) {
+				ui.PrintDebug(e.debug, "Captured steps.%s.%s = %v", step.Name, name, val)
+			}
+		}
+	}
+
+	result.Success = err == nil
+	return err
+}
+
+// recordResult appends a step result; safe for concurrent steps
+func (e *Executor) recordResult(result StepResult) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
 	e.results = append(e.results, result)
+	e.saveCheckpoint()
+}
 
-	// Print step result with colored output
-	duration := result.Duration.Round(time.Second).String()
+// refreshOutputs re-reads terraform outputs into the executor
+func (e *Executor) refreshOutputs(ctx context.Context) error {
+	details, err := e.readOutputDetails(ctx)
 	if err != nil {
-		ui.PrintProgress(stepNum, totalSteps, step.Name, "FAIL", duration)
-		return fmt.Errorf("step %s failed: %w", step.Name, err)
+		return err
 	}
-	
-	ui.PrintProgress(stepNum, totalSteps, step.Name, "OK", duration)
-
+	e.setOutputs(details)
 	return nil
 }
 
+// setOutputs replaces the known terraform outputs and returns their values
+func (e *Executor) setOutputs(details map[string]terraform.Output) map[string]interface{} {
+	values := make(map[string]interface{}, len(details))
+	sensitive := make(map[string]bool)
+	for name, o := range details {
+		values[name] = o.Value
+		if o.Sensitive {
+			sensitive[name] = true
+		}
+	}
+	e.mu.Lock()
+	e.outputs = values
+	e.sensitiveOutputs = sensitive
+	e.mu.Unlock()
+	return values
+}
+
+// currentOutputs returns the latest known terraform outputs
+func (e *Executor) currentOutputs() map[string]interface{} {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.outputs
+}
+
 func (e *Executor) executeTerraformStepWithContext(ctx context.Context, step Step) (string, error) {
 	// Refresh outputs before each terraform step
-	outputs, err := terraform.GetOutputs(e.flow.WorkingDir)
-	if err == nil {
-		e.outputs = outputs
+	e.refreshOutputs(ctx)
+
+	// Variables reach terraform through TF_VAR_* and -var-file
+	executor, err := e.stepExecutor(step)
+	if err != nil {
+		return "", err
 	}
 
 	if step.Command != "" {
-		// Interpolate terraform outputs in command
-		cmd := interpolator.Interpolate(step.Command, e.outputs)
-		output, err := e.executor.ExecuteWithContext(ctx, cmd)
+		// Interpolate terraform outputs and captured values in command
+		cmd, err := e.interpolateCommand(step.Command)
+		if err != nil {
+			return "", err
+		}
+		output, err := executor.ExecuteWithContext(ctx, cmd)
 		
 		// Auto-refresh outputs after successful apply
 		if err == nil && strings.Contains(step.Command, "apply") {
-			if newOutputs, err2 := terraform.GetOutputs(e.flow.WorkingDir); err2 == nil {
-				e.outputs = newOutputs
+			if err2 := e.refreshOutputs(ctx); err2 == nil {
 				ui.PrintDebug(e.debug, "Refreshed outputs after apply")
 			}
 		}
@@ -210,16 +558,17 @@ func (e *Executor) executeTerraformStepWithContext(ctx context.Context, step Ste
 		// Interpolate commands
 		interpolated := make([]string, len(step.Commands))
 		for i, cmd := range step.Commands {
-			interpolated[i] = interpolator.Interpolate(cmd, e.outputs)
+			if interpolated[i], err = e.interpolateCommand(cmd); err != nil {
+				return "", err
+			}
 		}
-		output, err := e.executor.ExecuteMultipleWithContext(ctx, interpolated)
+		output, err := executor.ExecuteMultipleWithContext(ctx, interpolated)
 		
 		// Auto-refresh outputs after successful apply
 		if err == nil {
 			for _, cmd := range step.Commands {
 				if strings.Contains(cmd, "apply") {
-					if newOutputs, err2 := terraform.GetOutputs(e.flow.WorkingDir); err2 == nil {
-						e.outputs = newOutputs
+					if err2 := e.refreshOutputs(ctx); err2 == nil {
 						ui.PrintDebug(e.debug, "Refreshed outputs after apply")
 					}
 					break
@@ -233,18 +582,18 @@ func (e *Executor) executeTerraformStepWithContext(ctx context.Context, step Ste
 	return "", fmt.Errorf("no command or commands specified for terraform step")
 }
 
-func (e *Executor) executeInventoryStep(step Step) ([]Resource, error) {
+func (e *Executor) executeInventoryStep(ctx context.Context, step Step) ([]Resource, error) {
 	// Get current state
-	state, err := terraform.GetState(e.flow.WorkingDir)
+	state, err := e.readState(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get terraform state: %w", err)
 	}
 
 	allResources := state.GetResources()
-	ui.PrintDebug(e.debug, "Found %d managed resources in state", len(allResources))
+	ui.PrintDebug(e.debug, "Found %d resources in state", len(allResources))
 	if e.debug {
 		for _, r := range allResources {
-			ui.PrintDebug(e.debug, "  - %s.%s (id: %s)", r.Type, r.Name, r.ID)
+			ui.PrintDebug(e.debug, "  - %s (id: %s)", r.Address, r.ID)
 		}
 	}
 
@@ -286,8 +635,13 @@ func (e *Executor) executeInventoryStep(step Step) ([]Resource, error) {
 
 		for _, r := range resources {
 			foundResources = append(foundResources, Resource{
-				Type: r.Type,
-				ID:   r.ID,
+				Type:       r.Type,
+				ID:         r.ID,
+				Name:       r.Name,
+				Address:    r.Address,
+				Module:     r.Module,
+				Mode:       r.Mode,
+				Attributes: r.Attributes,
 			})
 		}
 	}
@@ -300,7 +654,7 @@ func (e *Executor) executeInventoryStep(step Step) ([]Resource, error) {
 		}
 
 		for _, r := range allResources {
-			if !expectedTypes[r.Type] {
+			if r.Mode == "managed" && !expectedTypes[r.Type] {
 				return nil, fmt.Errorf("unexpected resource found: %s (id: %s)", r.Type, r.ID)
 			}
 		}
@@ -317,6 +671,8 @@ func (e *Executor) executeAdvancedInventory(step Step, allResources []terraform.
 			Type:       r.Type,
 			Name:       r.Name,
 			Address:    r.Address,
+			Module:     r.Module,
+			Mode:       r.Mode,
 			ID:         r.ID,
 			Attributes: r.Attributes,
 		}
@@ -325,22 +681,30 @@ func (e *Executor) executeAdvancedInventory(step Step, allResources []terraform.
 	// Parse expected resources
 	expected := make(map[string]inventory.ResourceMatch)
 	for pattern, match := range step.ExpectedResources {
-		// Parse pattern like "aws_vpc.main" or "aws_subnet.*"
-		parts := strings.SplitN(pattern, ".", 2)
-		if len(parts) != 2 {
-			return nil, fmt.Errorf("invalid resource pattern: %s (expected format: type.name)", pattern)
+		// Parse pattern like "aws_vpc.main", "data.aws_ami.ubuntu" or "module.network.aws_subnet.*"
+		module, resourceType, resourceName, err := inventory.ParsePattern(pattern)
+		if err != nil {
+			return nil, err
 		}
 
-		resourceType := parts[0]
-		resourceName := parts[1]
+		references := make(map[string]inventory.Reference, len(match.References))
+		for attrPath, value := range match.References {
+			ref, err := inventory.ParseReference(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: references %s: %w", pattern, attrPath, err)
+			}
+			references[attrPath] = ref
+		}
 
 		expected[pattern] = inventory.ResourceMatch{
+			Module:     module,
 			Type:       resourceType,
 			Name:       resourceName,
 			Count:      match.Count,
 			MinCount:   match.MinCount,
 			MaxCount:   match.MaxCount,
 			Attributes: match.Attributes,
+			References: references,
 		}
 	}
 
@@ -366,8 +730,13 @@ func (e *Executor) executeAdvancedInventory(step Step, allResources []terraform.
 	for _, result := range results {
 		for _, res := range result.Resources {
 			foundResources = append(foundResources, Resource{
-				Type: res.Type,
-				ID:   res.ID,
+				Type:       res.Type,
+				ID:         res.ID,
+				Name:       res.Name,
+				Address:    res.Address,
+				Module:     res.Module,
+				Mode:       res.Mode,
+				Attributes: res.Attributes,
 			})
 		}
 	}
@@ -375,14 +744,12 @@ func (e *Executor) executeAdvancedInventory(step Step, allResources []terraform.
 	return foundResources, nil
 }
 
-func (e *Executor) executeHTTPStep(step Step) (int, error) {
+func (e *Executor) executeHTTPStep(ctx context.Context, step Step) (http.Response, error) {
 	// Refresh outputs before HTTP step to ensure we have the latest values
-	outputs, err := terraform.GetOutputs(e.flow.WorkingDir)
-	if err == nil {
-		e.outputs = outputs
+	if err := e.refreshOutputs(ctx); err == nil {
 		ui.PrintDebug(e.debug, "Refreshed terraform outputs:")
 		if e.debug {
-			for k, v := range e.outputs {
+			for k, v := range e.currentOutputs() {
 				ui.PrintDebug(e.debug, "  %s = %v", k, v)
 			}
 		}
@@ -390,12 +757,18 @@ func (e *Executor) executeHTTPStep(step Step) (int, error) {
 		ui.PrintDebug(e.debug, "Warning: failed to refresh outputs: %v", err)
 	}
 
-	// Interpolate URL with terraform outputs
-	url := interpolator.Interpolate(step.URL, e.outputs)
+	// Interpolate URL with terraform outputs and captured values
+	url := e.interpolate(step.URL)
 	
 	ui.PrintDebug(e.debug, "Original URL template: %s", step.URL)
-	ui.PrintDebug(e.debug, "Interpolated URL: %s", url)
+	ui.PrintDebug(e.debug, "Interpolated URL: %s", e.interpolateMasked(step.URL))
 
+	retries, delay := checkRetries(step)
+	return http.FetchWithRetryContext(ctx, url, step.ExpectedStatus, retries, delay, e.debug)
+}
+
+// checkRetries returns the built-in retries and delay of http, tcp and udp steps
+func checkRetries(step Step) (int, time.Duration) {
 	// Parse delay
 	delay, err := time.ParseDuration(step.Delay)
 	if err != nil {
@@ -403,12 +776,10 @@ func (e *Executor) executeHTTPStep(step Step) (int, error) {
 	}
 
 	retries := step.Retries
-	if retries == 0 {
-		retries = 3 // default
+	if retries == 0 && step.Retry == nil {
+		retries = 3 // default; a retry block replaces the built-in retries
 	}
-
-	status, err := http.CheckWithRetry(url, step.ExpectedStatus, retries, delay, e.debug)
-	return status, err
+	return retries, delay
 }
 
 // GetFlow returns the flow configuration
@@ -418,11 +789,13 @@ func (e *Executor) GetFlow() *Flow {
 
 // GetOutputs returns the terraform outputs
 func (e *Executor) GetOutputs() map[string]interface{} {
-	return e.outputs
+	return e.currentOutputs()
 }
 
 // GetResults returns all step results
 func (e *Executor) GetResults() []StepResult {
-	return e.results
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]StepResult(nil), e.results...)
 }
 
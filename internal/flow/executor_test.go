@@ -0,0 +1,148 @@
+package flow
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestExecuteWithContextStepTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	f := &Flow{
+		Name:       "timeouts",
+		WorkingDir: t.TempDir(),
+		Steps: []Step{
+			{Name: "hung", Type: "http", URL: server.URL, ExpectedStatus: 200, Retries: 5, Delay: "10ms", Timeout: "200ms"},
+		},
+	}
+	if err := validateFlow(f); err != nil {
+		t.Fatalf("validateFlow() error: %v", err)
+	}
+
+	e := &Executor{flow: f, outputs: map[string]interface{}{}}
+	start := time.Now()
+	err := e.ExecuteWithContext(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("Expected timeout error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected step to stop at its timeout, took %s", elapsed)
+	}
+
+	results := e.GetResults()
+	if len(results) != 1 || !results[0].TimedOut || results[0].Success {
+		t.Errorf("Expected a single timed-out result, got %+v", results)
+	}
+}
+
+func TestExecuteWithContextFlowTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	f := &Flow{
+		Name:       "flow-timeout",
+		WorkingDir: t.TempDir(),
+		Timeout:    "200ms",
+		Steps: []Step{
+			{Name: "hung", Type: "http", URL: server.URL, ExpectedStatus: 200, Retries: 1, Delay: "10ms"},
+			{Name: "next", Type: "http", URL: server.URL, ExpectedStatus: 200, Retries: 1, Delay: "10ms"},
+		},
+	}
+
+	e := &Executor{flow: f, outputs: map[string]interface{}{}}
+	err := e.ExecuteWithContext(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("Expected flow timeout error, got %v", err)
+	}
+
+	results := e.GetResults()
+	if len(results) != 1 || !results[0].TimedOut {
+		t.Errorf("Expected only the hung step to run and time out, got %+v", results)
+	}
+}
+
+func TestValidateFlowTimeouts(t *testing.T) {
+	base := func() *Flow {
+		return &Flow{Name: "test", WorkingDir: "./terraform", Steps: []Step{{Name: "test", Type: "terraform"}}}
+	}
+
+	f := base()
+	f.Timeout = "forever"
+	if err := validateFlow(f); err == nil {
+		t.Error("Expected invalid flow timeout to be rejected")
+	}
+
+	f = base()
+	f.Steps[0].Timeout = "-5s"
+	if err := validateFlow(f); err == nil {
+		t.Error("Expected negative step timeout to be rejected")
+	}
+
+	f = base()
+	f.Timeout = "1h"
+	f.Steps[0].Timeout = "10m"
+	if err := validateFlow(f); err != nil {
+		t.Errorf("Expected valid timeouts, got %v", err)
+	}
+}
+
+func TestExecuteWithContextRetryPolicy(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	f := &Flow{
+		Name:       "retries",
+		WorkingDir: t.TempDir(),
+		Steps: []Step{
+			{
+				Name: "flaky", Type: "http", URL: server.URL, ExpectedStatus: 200, Retries: 0, Delay: "1ms",
+				Retry: &RetryPolicy{Attempts: 5, Backoff: "exponential", Delay: "10ms", RetryOn: []string{"expected status"}},
+			},
+		},
+	}
+	if err := validateFlow(f); err != nil {
+		t.Fatalf("validateFlow() error: %v", err)
+	}
+
+	e := &Executor{flow: f, outputs: map[string]interface{}{}}
+	if err := e.ExecuteWithContext(context.Background()); err != nil {
+		t.Fatalf("Expected flaky step to succeed after retries, got %v", err)
+	}
+
+	results := e.GetResults()
+	if len(results) != 1 || !results[0].Success {
+		t.Fatalf("Expected one successful result, got %+v", results)
+	}
+	attempts := results[0].Attempts
+	if len(attempts) != 3 {
+		t.Fatalf("Expected 3 attempts, got %d", len(attempts))
+	}
+	if attempts[0].Success || attempts[1].Success || !attempts[2].Success {
+		t.Errorf("Expected two failed attempts followed by a success, got %+v", attempts)
+	}
+}
@@ -0,0 +1,127 @@
+package expression
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// compare applies a comparison operator to two values. Numbers compare
+// numerically, durations compare as durations (a string operand such as a
+// terraform output of "90s" is parsed), and strings compare lexically.
+// Equality between other mixed types falls back to comparing their string forms.
+func compare(op string, left, right interface{}) (bool, error) {
+	if ld, rd, ok := asDurations(left, right); ok {
+		return compareOrdered(op, float64(ld), float64(rd))
+	}
+
+	if isNumeric(left) || isNumeric(right) {
+		ln, lok := asNumber(left)
+		rn, rok := asNumber(right)
+		if lok && rok {
+			return compareOrdered(op, ln, rn)
+		}
+	}
+
+	if ls, ok := left.(string); ok {
+		if rs, ok := right.(string); ok {
+			return compareOrdered(op, float64(strings.Compare(ls, rs)), 0)
+		}
+	}
+
+	switch op {
+	case "==":
+		return looselyEqual(left, right), nil
+	case "!=":
+		return !looselyEqual(left, right), nil
+	default:
+		return false, fmt.Errorf("cannot compare %v %s %v", left, op, right)
+	}
+}
+
+func compareOrdered(op string, l, r float64) (bool, error) {
+	switch op {
+	case "==":
+		return l == r, nil
+	case "!=":
+		return l != r, nil
+	case "<":
+		return l < r, nil
+	case "<=":
+		return l <= r, nil
+	case ">":
+		return l > r, nil
+	case ">=":
+		return l >= r, nil
+	}
+	return false, fmt.Errorf("unknown operator %s", op)
+}
+
+// asDurations converts both operands to durations if at least one of them is
+// a time.Duration and the other is a duration or a duration string
+func asDurations(left, right interface{}) (time.Duration, time.Duration, bool) {
+	ld, lok := left.(time.Duration)
+	rd, rok := right.(time.Duration)
+	if !lok && !rok {
+		return 0, 0, false
+	}
+	if !lok {
+		d, ok := parseDuration(left)
+		if !ok {
+			return 0, 0, false
+		}
+		ld = d
+	}
+	if !rok {
+		d, ok := parseDuration(right)
+		if !ok {
+			return 0, 0, false
+		}
+		rd = d
+	}
+	return ld, rd, true
+}
+
+func parseDuration(v interface{}) (time.Duration, bool) {
+	s, ok := v.(string)
+	if !ok {
+		return 0, false
+	}
+	d, err := time.ParseDuration(s)
+	return d, err == nil
+}
+
+func isNumeric(v interface{}) bool {
+	switch v.(type) {
+	case float64, float32, int, int64:
+		return true
+	}
+	return false
+}
+
+// asNumber converts v to a float64. Strings are parsed so that a terraform
+// output declared as a string can still be compared with a number literal.
+func asNumber(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func looselyEqual(left, right interface{}) bool {
+	if left == nil || right == nil {
+		return left == nil && right == nil
+	}
+	return fmt.Sprintf("%v", left) == fmt.Sprintf("%v", right)
+}
@@ -0,0 +1,292 @@
+// Package expression implements the small boolean language used by step
+// `when` conditions, e.g.
+//
+//	${output.environment} == "prod"
+//	steps.apply.success && !steps.smoke.success
+//	steps.inventory.duration > 5m
+package expression
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/infratest/infratest/internal/terraform"
+)
+
+// Env resolves references such as output.environment or steps.apply.success
+type Env interface {
+	Lookup(ref string) (interface{}, bool)
+}
+
+// MapEnv is an Env backed by nested maps. The first path segment selects the
+// namespace (e.g. "output" or "steps"); the rest is resolved with the same
+// dot and index syntax as terraform output interpolation.
+type MapEnv map[string]interface{}
+
+// Lookup implements Env
+func (m MapEnv) Lookup(ref string) (interface{}, bool) {
+	val, err := terraform.GetOutputValue(m, ref)
+	if err != nil {
+		return nil, false
+	}
+	return val, true
+}
+
+// Expression is a parsed condition
+type Expression struct {
+	source string
+	root   node
+	refs   []string
+}
+
+// Parse parses a condition expression
+func Parse(source string) (*Expression, error) {
+	tokens, err := lex(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+	}
+
+	return &Expression{source: source, root: root, refs: p.refs}, nil
+}
+
+// String returns the expression source
+func (x *Expression) String() string {
+	return x.source
+}
+
+// References returns every reference path used by the expression
+func (x *Expression) References() []string {
+	return x.refs
+}
+
+// Evaluate evaluates the expression and requires a boolean result
+func (x *Expression) Evaluate(env Env) (bool, error) {
+	val, err := x.root.eval(env)
+	if err != nil {
+		return false, err
+	}
+	b, ok := val.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression %q evaluated to %v, not a boolean", x.source, val)
+	}
+	return b, nil
+}
+
+// node is an element of the expression tree
+type node interface {
+	eval(env Env) (interface{}, error)
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n literalNode) eval(Env) (interface{}, error) {
+	return n.value, nil
+}
+
+type refNode struct {
+	path string
+}
+
+func (n refNode) eval(env Env) (interface{}, error) {
+	val, ok := env.Lookup(n.path)
+	if !ok {
+		return nil, fmt.Errorf("unknown reference: %s", n.path)
+	}
+	return val, nil
+}
+
+type notNode struct {
+	operand node
+}
+
+func (n notNode) eval(env Env) (interface{}, error) {
+	val, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := val.(bool)
+	if !ok {
+		return nil, fmt.Errorf("operator ! requires a boolean, got %v", val)
+	}
+	return !b, nil
+}
+
+type logicalNode struct {
+	op          string
+	left, right node
+}
+
+func (n logicalNode) eval(env Env) (interface{}, error) {
+	left, err := evalBool(n.left, env, n.op)
+	if err != nil {
+		return nil, err
+	}
+	// Short-circuit so `steps.a.success && ${output.only_set_on_success}` works
+	if n.op == "&&" && !left {
+		return false, nil
+	}
+	if n.op == "||" && left {
+		return true, nil
+	}
+	return evalBool(n.right, env, n.op)
+}
+
+func evalBool(n node, env Env, op string) (bool, error) {
+	val, err := n.eval(env)
+	if err != nil {
+		return false, err
+	}
+	b, ok := val.(bool)
+	if !ok {
+		return false, fmt.Errorf("operator %s requires booleans, got %v", op, val)
+	}
+	return b, nil
+}
+
+type compareNode struct {
+	op          string
+	left, right node
+}
+
+func (n compareNode) eval(env Env) (interface{}, error) {
+	left, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	return compare(n.op, left, right)
+}
+
+// parser is a recursive-descent parser over the token stream.
+// Precedence from lowest to highest: ||, &&, comparisons, !
+type parser struct {
+	tokens []token
+	pos    int
+	refs   []string
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOp && p.peek().text == "||" {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = logicalNode{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOp && p.peek().text == "&&" {
+		p.next()
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		left = logicalNode{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind == tokOp && isComparison(tok.text) {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if next := p.peek(); next.kind == tokOp && isComparison(next.text) {
+			return nil, fmt.Errorf("comparisons cannot be chained (position %d); use && instead", next.pos)
+		}
+		return compareNode{op: tok.text, left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if tok := p.peek(); tok.kind == tokOp && tok.text == "!" {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokRef:
+		if strings.HasSuffix(tok.text, ".") || strings.HasPrefix(tok.text, ".") || strings.Contains(tok.text, "..") {
+			return nil, fmt.Errorf("invalid reference %q at position %d", tok.text, tok.pos)
+		}
+		p.refs = append(p.refs, tok.text)
+		return refNode{path: tok.text}, nil
+	case tokString, tokNumber, tokDuration, tokBool:
+		return literalNode{value: tok.value}, nil
+	case tokNull:
+		return literalNode{value: nil}, nil
+	case tokLParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, fmt.Errorf("expected ')' at position %d", closing.pos)
+		}
+		return inner, nil
+	case tokEOF:
+		return nil, fmt.Errorf("unexpected end of expression")
+	default:
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+	}
+}
+
+func isComparison(op string) bool {
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=":
+		return true
+	}
+	return false
+}
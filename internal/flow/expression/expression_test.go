@@ -0,0 +1,112 @@
+package expression
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEvaluate(t *testing.T) {
+	env := MapEnv{
+		"output": map[string]interface{}{
+			"environment": "prod",
+			"azs":         []interface{}{"us-east-1a", "us-east-1b"},
+			"count":       float64(3),
+			"timeout":     "90s",
+		},
+		"steps": map[string]interface{}{
+			"apply":         map[string]interface{}{"success": true, "duration": 2 * time.Minute},
+			"smoke":         map[string]interface{}{"success": false},
+			"inventory":     map[string]interface{}{"success": true, "duration": 6 * time.Minute},
+			"init-and-plan": map[string]interface{}{"success": true},
+		},
+	}
+
+	tests := []struct {
+		name string
+		expr string
+		want bool
+	}{
+		{"string equality", `${output.environment} == "prod"`, true},
+		{"string inequality", `${output.environment} != 'prod'`, false},
+		{"bare step reference", `steps.apply.success`, true},
+		{"and with not", `steps.apply.success && !steps.smoke.success`, true},
+		{"or", `steps.smoke.success || steps.apply.success`, true},
+		{"duration comparison", `steps.inventory.duration > 5m`, true},
+		{"duration comparison false", `steps.apply.duration >= 1h`, false},
+		{"duration against string output", `${output.timeout} < 2m`, true},
+		{"number comparison", `${output.count} >= 3`, true},
+		{"list index", `${output.azs[1]} == "us-east-1b"`, true},
+		{"hyphenated step name", `steps.init-and-plan.success`, true},
+		{"parentheses", `!(steps.smoke.success || false) && true`, true},
+		{"short-circuit skips unknown reference", `steps.smoke.success && ${output.missing} == 1`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse(%q) error: %v", tt.expr, err)
+			}
+			got, err := x.Evaluate(env)
+			if err != nil {
+				t.Fatalf("Evaluate(%q) error: %v", tt.expr, err)
+			}
+			if got != tt.want {
+				t.Errorf("Evaluate(%q) = %v, want %v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		``,
+		`steps.apply.success &&`,
+		`(steps.apply.success`,
+		`${output.environment == "prod"`,
+		`"unterminated`,
+		`steps.a.duration > 5x`,
+		`1 < 2 < 3`,
+		`steps.apply.success steps.smoke.success`,
+		`$output`,
+		`steps.apply.success & true`,
+	}
+
+	for _, src := range tests {
+		if _, err := Parse(src); err == nil {
+			t.Errorf("Parse(%q) expected error", src)
+		}
+	}
+}
+
+func TestEvaluateErrors(t *testing.T) {
+	env := MapEnv{"output": map[string]interface{}{"name": "web"}}
+
+	tests := []string{
+		`${output.missing} == "x"`,
+		`${output.name}`,
+		`!${output.name}`,
+		`${output.name} > 5m`,
+	}
+
+	for _, src := range tests {
+		x, err := Parse(src)
+		if err != nil {
+			t.Fatalf("Parse(%q) error: %v", src, err)
+		}
+		if _, err := x.Evaluate(env); err == nil {
+			t.Errorf("Evaluate(%q) expected error", src)
+		}
+	}
+}
+
+func TestReferences(t *testing.T) {
+	x, err := Parse(`${output.env} == "prod" && steps.apply.success`)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	refs := x.References()
+	if len(refs) != 2 || refs[0] != "output.env" || refs[1] != "steps.apply.success" {
+		t.Errorf("References() = %v", refs)
+	}
+}
@@ -0,0 +1,181 @@
+package expression
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// tokenKind identifies the type of a lexical token
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokRef           // ${output.key} or a bare path like steps.apply.success
+	tokString
+	tokNumber
+	tokDuration
+	tokBool
+	tokNull
+	tokOp // ==, !=, <, <=, >, >=, &&, ||, !
+	tokLParen
+	tokRParen
+)
+
+// token is a single lexical element of an expression
+type token struct {
+	kind  tokenKind
+	text  string
+	value interface{}
+	pos   int
+}
+
+// lex splits an expression into tokens
+func lex(src string) ([]token, error) {
+	var tokens []token
+	i := 0
+
+	for i < len(src) {
+		c := src[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case c == '(':
+			tokens = append(tokens, token{kind: tokLParen, text: "(", pos: i})
+			i++
+
+		case c == ')':
+			tokens = append(tokens, token{kind: tokRParen, text: ")", pos: i})
+			i++
+
+		case c == '$':
+			if i+1 >= len(src) || src[i+1] != '{' {
+				return nil, fmt.Errorf("unexpected '$' at position %d", i)
+			}
+			end := strings.IndexByte(src[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated ${ at position %d", i)
+			}
+			ref := strings.TrimSpace(src[i+2 : i+end])
+			if ref == "" {
+				return nil, fmt.Errorf("empty reference at position %d", i)
+			}
+			tokens = append(tokens, token{kind: tokRef, text: ref, pos: i})
+			i += end + 1
+
+		case c == '"' || c == '\'':
+			str, n, err := lexString(src[i:])
+			if err != nil {
+				return nil, fmt.Errorf("%v at position %d", err, i)
+			}
+			tokens = append(tokens, token{kind: tokString, text: src[i : i+n], value: str, pos: i})
+			i += n
+
+		case c >= '0' && c <= '9':
+			start := i
+			for i < len(src) && (isDigit(src[i]) || src[i] == '.' || isLetter(src[i])) {
+				i++
+			}
+			tok, err := lexNumber(src[start:i])
+			if err != nil {
+				return nil, fmt.Errorf("%v at position %d", err, start)
+			}
+			tok.pos = start
+			tokens = append(tokens, tok)
+
+		case isLetter(c) || c == '_':
+			start := i
+			for i < len(src) && isIdentChar(src[i]) {
+				i++
+			}
+			word := src[start:i]
+			switch word {
+			case "true", "false":
+				tokens = append(tokens, token{kind: tokBool, text: word, value: word == "true", pos: start})
+			case "null":
+				tokens = append(tokens, token{kind: tokNull, text: word, pos: start})
+			default:
+				tokens = append(tokens, token{kind: tokRef, text: word, pos: start})
+			}
+
+		default:
+			op := lexOperator(src[i:])
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+			}
+			tokens = append(tokens, token{kind: tokOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+
+	tokens = append(tokens, token{kind: tokEOF, pos: len(src)})
+	return tokens, nil
+}
+
+// lexString reads a quoted string. Double-quoted strings support Go escapes,
+// single-quoted strings are taken literally.
+func lexString(src string) (string, int, error) {
+	quote := src[0]
+	for i := 1; i < len(src); i++ {
+		if quote == '"' && src[i] == '\\' {
+			i++
+			continue
+		}
+		if src[i] == quote {
+			if quote == '\'' {
+				return src[1:i], i + 1, nil
+			}
+			str, err := strconv.Unquote(src[:i+1])
+			if err != nil {
+				return "", 0, fmt.Errorf("invalid string literal %s", src[:i+1])
+			}
+			return str, i + 1, nil
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+// lexNumber parses a number literal, or a duration literal such as 5m or 1h30m
+func lexNumber(text string) (token, error) {
+	if strings.IndexFunc(text, unicode.IsLetter) >= 0 {
+		d, err := time.ParseDuration(text)
+		if err != nil {
+			return token{}, fmt.Errorf("invalid duration %q", text)
+		}
+		return token{kind: tokDuration, text: text, value: d}, nil
+	}
+
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return token{}, fmt.Errorf("invalid number %q", text)
+	}
+	return token{kind: tokNumber, text: text, value: f}, nil
+}
+
+// lexOperator returns the operator at the start of src, or "" if there is none
+func lexOperator(src string) string {
+	for _, op := range []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!"} {
+		if strings.HasPrefix(src, op) {
+			return op
+		}
+	}
+	return ""
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// isIdentChar reports whether c can appear in a bare reference path.
+// Hyphens are allowed because step names commonly contain them.
+func isIdentChar(c byte) bool {
+	return isLetter(c) || isDigit(c) || c == '_' || c == '-' || c == '.' || c == '[' || c == ']'
+}
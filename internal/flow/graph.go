@@ -0,0 +1,216 @@
+package flow
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/infratest/infratest/internal/flow/expression"
+)
+
+// stepStatus tracks where a step is in the execution graph
+type stepStatus int
+
+const (
+	statusPending stepStatus = iota
+	statusRunning
+	statusPassed
+	statusFailed
+	statusSkipped // skipped by its own condition or because an explicit prerequisite didn't run
+	statusBlocked // skipped because a prerequisite failed
+)
+
+// finished reports whether the step has reached a terminal status
+func (s stepStatus) finished() bool {
+	return s != statusPending && s != statusRunning
+}
+
+// dependencies returns the prerequisites of every step, keyed by step name.
+// Steps with an explicit `after` depend only on the steps listed there.
+// Steps without one wait for every step declared before them, so flows that
+// don't use `after` keep running in file order. Steps referenced by a `when`
+// expression or a ${steps.<name>.<var>} template are added too, since the
+// step needs their results.
+func dependencies(steps []Step) map[string][]string {
+	deps := make(map[string][]string, len(steps))
+	for i, step := range steps {
+		var list []string
+		if len(step.After) > 0 {
+			list = append(list, step.After...)
+		} else {
+			for _, s := range steps[:i] {
+				list = append(list, s.Name)
+			}
+		}
+
+		for _, ref := range append(conditionSteps(step), templateSteps(step)...) {
+			if !containsString(list, ref) {
+				list = append(list, ref)
+			}
+		}
+		deps[step.Name] = list
+	}
+	return deps
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// validateDependencies checks that step names are unique, that every `after`
+// reference names an existing step and that the dependency graph is acyclic
+func validateDependencies(steps []Step) error {
+	byName := make(map[string]Step, len(steps))
+	for _, step := range steps {
+		if step.Name == "" {
+			return fmt.Errorf("every step requires a name")
+		}
+		if _, exists := byName[step.Name]; exists {
+			return fmt.Errorf("duplicate step name: %s", step.Name)
+		}
+		byName[step.Name] = step
+	}
+
+	for _, step := range steps {
+		for _, dep := range step.After {
+			if _, exists := byName[dep]; !exists {
+				return fmt.Errorf("step %s depends on unknown step %s", step.Name, dep)
+			}
+			if dep == step.Name {
+				return fmt.Errorf("step %s depends on itself", step.Name)
+			}
+		}
+		if err := validateCondition(step, byName); err != nil {
+			return err
+		}
+		if err := validateCaptures(step); err != nil {
+			return err
+		}
+		if err := validateTemplateRefs(step, byName); err != nil {
+			return err
+		}
+	}
+
+	if cycle := findCycle(steps, dependencies(steps)); len(cycle) > 0 {
+		return fmt.Errorf("dependency cycle detected: %s", strings.Join(cycle, " -> "))
+	}
+
+	return nil
+}
+
+// findCycle returns the steps forming a dependency cycle, or nil if there is none
+func findCycle(steps []Step, deps map[string][]string) []string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	state := make(map[string]int, len(steps))
+	var path []string
+	var cycle []string
+
+	var visit func(name string) bool
+	visit = func(name string) bool {
+		state[name] = visiting
+		path = append(path, name)
+
+		for _, dep := range deps[name] {
+			switch state[dep] {
+			case visiting:
+				// Slice the path from the first occurrence of dep to close the loop
+				for i, n := range path {
+					if n == dep {
+						cycle = append(append([]string(nil), path[i:]...), dep)
+						break
+					}
+				}
+				return true
+			case unvisited:
+				if visit(dep) {
+					return true
+				}
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[name] = visited
+		return false
+	}
+
+	for _, step := range steps {
+		if state[step.Name] == unvisited && visit(step.Name) {
+			return cycle
+		}
+	}
+	return nil
+}
+
+// dependenciesFinished reports whether all of a step's prerequisites are done
+func dependenciesFinished(deps []string, status map[string]stepStatus) bool {
+	for _, dep := range deps {
+		if !status[dep].finished() {
+			return false
+		}
+	}
+	return true
+}
+
+// readiness decides whether a step whose prerequisites have finished should
+// run. If not, it returns the status to record and a human-readable reason.
+// A `when` expression takes over the decision entirely: the step runs if it
+// evaluates to true, whatever the outcome of its prerequisites.
+func readiness(step Step, deps []string, status map[string]stepStatus, hasFailure bool, evaluate func(*expression.Expression) (bool, error)) (bool, stepStatus, string) {
+	switch step.When {
+	case "always":
+		return true, statusRunning, ""
+	case "on-failure":
+		if !hasFailure {
+			return false, statusSkipped, "when: on-failure, but no previous step failed"
+		}
+		return true, statusRunning, ""
+	}
+
+	cond, err := parseCondition(step)
+	if err != nil {
+		return false, statusFailed, fmt.Sprintf("invalid when expression %q: %v", step.When, err)
+	}
+	if cond != nil {
+		ok, err := evaluate(cond)
+		if err != nil {
+			return false, statusFailed, fmt.Sprintf("failed to evaluate when expression %q: %v", step.When, err)
+		}
+		if !ok {
+			return false, statusSkipped, fmt.Sprintf("when: %s evaluated to false", step.When)
+		}
+		return true, statusRunning, ""
+	}
+
+	explicit := make(map[string]bool, len(step.After))
+	for _, dep := range step.After {
+		explicit[dep] = true
+	}
+
+	for _, dep := range deps {
+		switch status[dep] {
+		case statusFailed:
+			return false, statusBlocked, fmt.Sprintf("prerequisite step %s failed", dep)
+		case statusBlocked:
+			return false, statusBlocked, fmt.Sprintf("prerequisite step %s was skipped after an earlier failure", dep)
+		case statusSkipped:
+			if explicit[dep] {
+				return false, statusSkipped, fmt.Sprintf("prerequisite step %s was skipped", dep)
+			}
+		}
+	}
+
+	if step.When == "on-success" && hasFailure {
+		return false, statusSkipped, "when: on-success, but a previous step failed"
+	}
+
+	return true, statusRunning, ""
+}
@@ -0,0 +1,255 @@
+package flow
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDependencies(t *testing.T) {
+	steps := []Step{
+		{Name: "apply"},
+		{Name: "probe-a", After: StringList{"apply"}},
+		{Name: "probe-b", After: StringList{"apply"}},
+		{Name: "call", After: StringList{"apply"}, URL: "http://api/${steps.probe-a.token}"},
+		{Name: "destroy"},
+	}
+
+	deps := dependencies(steps)
+
+	if len(deps["apply"]) != 0 {
+		t.Errorf("Expected apply to have no dependencies, got %v", deps["apply"])
+	}
+	if len(deps["probe-b"]) != 1 || deps["probe-b"][0] != "apply" {
+		t.Errorf("Expected probe-b to depend only on apply, got %v", deps["probe-b"])
+	}
+	// Captured values referenced in templates add a dependency
+	if len(deps["call"]) != 2 || deps["call"][1] != "probe-a" {
+		t.Errorf("Expected call to depend on apply and probe-a, got %v", deps["call"])
+	}
+	// Steps without `after` wait for everything declared before them
+	if len(deps["destroy"]) != 4 {
+		t.Errorf("Expected destroy to depend on 4 steps, got %v", deps["destroy"])
+	}
+}
+
+func TestValidateDependencies(t *testing.T) {
+	tests := []struct {
+		name    string
+		steps   []Step
+		wantErr string
+	}{
+		{
+			name: "valid graph",
+			steps: []Step{
+				{Name: "apply"},
+				{Name: "check", After: StringList{"apply"}},
+			},
+		},
+		{
+			name: "unknown dependency",
+			steps: []Step{
+				{Name: "check", After: StringList{"apply"}},
+			},
+			wantErr: "unknown step apply",
+		},
+		{
+			name: "duplicate name",
+			steps: []Step{
+				{Name: "apply"},
+				{Name: "apply"},
+			},
+			wantErr: "duplicate step name",
+		},
+		{
+			name: "cycle",
+			steps: []Step{
+				{Name: "a", After: StringList{"c"}},
+				{Name: "b", After: StringList{"a"}},
+				{Name: "c", After: StringList{"b"}},
+			},
+			wantErr: "dependency cycle detected",
+		},
+		{
+			name: "cycle through implicit ordering",
+			steps: []Step{
+				{Name: "a", After: StringList{"b"}},
+				{Name: "b"},
+			},
+			wantErr: "a -> b -> a",
+		},
+		{
+			name: "malformed when expression",
+			steps: []Step{
+				{Name: "apply"},
+				{Name: "check", When: "steps.apply.success &&"},
+			},
+			wantErr: "invalid when expression",
+		},
+		{
+			name: "when expression references unknown step",
+			steps: []Step{
+				{Name: "check", When: "steps.apply.success"},
+			},
+			wantErr: "unknown step apply",
+		},
+		{
+			name: "when expression references unknown namespace",
+			steps: []Step{
+				{Name: "check", When: `${outputs.env} == "prod"`},
+			},
+			wantErr: "unknown namespace",
+		},
+		{
+			name: "when expression references invalid variable",
+			steps: []Step{
+				{Name: "check", When: `var == "prod"`},
+			},
+			wantErr: "invalid variable var.",
+		},
+		{
+			name: "when expression references a later step",
+			steps: []Step{
+				{Name: "check", When: "steps.apply.success"},
+				{Name: "apply"},
+			},
+			wantErr: "dependency cycle detected",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateDependencies(tt.steps)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateDependencies() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateDependencies() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExecuteWithContextParallel(t *testing.T) {
+	var inFlight, maxInFlight int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			m := atomic.LoadInt32(&maxInFlight)
+			if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+				break
+			}
+		}
+		time.Sleep(100 * time.Millisecond)
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	f := &Flow{
+		Name:        "parallel",
+		WorkingDir:  t.TempDir(),
+		MaxParallel: 3,
+		Steps: []Step{
+			{Name: "root", Type: "http", URL: server.URL, ExpectedStatus: 200, Retries: 1, Delay: "10ms"},
+			{Name: "probe-a", Type: "http", After: StringList{"root"}, URL: server.URL, ExpectedStatus: 200, Retries: 1, Delay: "10ms"},
+			{Name: "probe-b", Type: "http", After: StringList{"root"}, URL: server.URL + "/fail", ExpectedStatus: 200, Retries: 1, Delay: "10ms"},
+			{Name: "probe-c", Type: "http", After: StringList{"root"}, URL: server.URL, ExpectedStatus: 200, Retries: 1, Delay: "10ms"},
+			{Name: "after-b", Type: "http", After: StringList{"probe-b"}, URL: server.URL, ExpectedStatus: 200, Retries: 1, Delay: "10ms"},
+			{Name: "cleanup", Type: "http", When: "always", URL: server.URL, ExpectedStatus: 200, Retries: 1, Delay: "10ms"},
+		},
+	}
+	if err := validateFlow(f); err != nil {
+		t.Fatalf("validateFlow() error: %v", err)
+	}
+
+	e := &Executor{flow: f, outputs: map[string]interface{}{}}
+	err := e.ExecuteWithContext(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "probe-b") {
+		t.Fatalf("Expected probe-b failure, got %v", err)
+	}
+
+	if maxInFlight < 2 {
+		t.Errorf("Expected independent probes to run concurrently, max in flight = %d", maxInFlight)
+	}
+
+	byName := make(map[string]StepResult)
+	for _, r := range e.GetResults() {
+		byName[r.StepName] = r
+	}
+
+	if !byName["probe-a"].Success || !byName["probe-c"].Success {
+		t.Errorf("Expected probe-a and probe-c to succeed")
+	}
+	if r := byName["after-b"]; !r.Skipped || !strings.Contains(r.SkipReason, "probe-b failed") {
+		t.Errorf("Expected after-b to be skipped because probe-b failed, got %+v", r)
+	}
+	if r := byName["cleanup"]; r.Skipped || !r.Success {
+		t.Errorf("Expected cleanup to run despite the failure, got %+v", r)
+	}
+}
+
+func TestExecuteWithContextWhenExpression(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	f := &Flow{
+		Name:         "conditions",
+		WorkingDir:   t.TempDir(),
+		Variables:    map[string]interface{}{"environment": "dev", "tags": map[string]interface{}{"team": "platform"}},
+		VarOverrides: map[string]interface{}{"environment": "prod"},
+		Steps: []Step{
+			{Name: "apply", Type: "http", URL: server.URL, ExpectedStatus: 200, Retries: 1, Delay: "10ms"},
+			{Name: "smoke", Type: "http", After: StringList{"apply"}, URL: server.URL + "/fail", ExpectedStatus: 200, Retries: 1, Delay: "10ms"},
+			{Name: "diagnose", Type: "http", When: "steps.apply.success && !steps.smoke.success", URL: server.URL, ExpectedStatus: 200, Retries: 1, Delay: "10ms"},
+			{Name: "slow-only", Type: "http", When: "steps.apply.duration > 1h", URL: server.URL, ExpectedStatus: 200, Retries: 1, Delay: "10ms"},
+			{Name: "prod-only", Type: "http", When: `var.environment == "prod" && var.tags.team == "platform"`, URL: server.URL, ExpectedStatus: 200, Retries: 1, Delay: "10ms"},
+			{Name: "dev-only", Type: "http", When: `${var.environment} == "dev"`, URL: server.URL, ExpectedStatus: 200, Retries: 1, Delay: "10ms"},
+		},
+	}
+	if err := validateFlow(f); err != nil {
+		t.Fatalf("validateFlow() error: %v", err)
+	}
+
+	e := &Executor{flow: f, outputs: map[string]interface{}{}}
+	if err := e.ExecuteWithContext(context.Background()); err == nil {
+		t.Fatal("Expected smoke failure to fail the flow")
+	}
+
+	byName := make(map[string]StepResult)
+	for _, r := range e.GetResults() {
+		byName[r.StepName] = r
+	}
+
+	if r := byName["diagnose"]; r.Skipped || !r.Success {
+		t.Errorf("Expected diagnose to run after smoke failed, got %+v", r)
+	}
+	if r := byName["slow-only"]; !r.Skipped || !strings.Contains(r.SkipReason, "evaluated to false") {
+		t.Errorf("Expected slow-only to be skipped by its condition, got %+v", r)
+	}
+
+	// --var overrides the flow's variables
+	if r := byName["prod-only"]; r.Skipped || !r.Success {
+		t.Errorf("Expected prod-only to run with --var environment=prod, got %+v", r)
+	}
+	if r := byName["dev-only"]; !r.Skipped {
+		t.Errorf("Expected dev-only to be skipped, got %+v", r)
+	}
+}
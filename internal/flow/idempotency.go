@@ -0,0 +1,236 @@
+package flow
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/infratest/infratest/internal/terraform"
+	"github.com/infratest/infratest/internal/ui"
+)
+
+// validateIdempotency checks the command and allowlist of a
+// terraform-idempotency step, and that other steps don't use the allowlist
+func validateIdempotency(step Step) error {
+	if step.Type != "terraform-idempotency" {
+		if len(step.IgnoreAttributes) > 0 {
+			return fmt.Errorf("ignore_attributes are only supported on terraform-idempotency steps")
+		}
+		return nil
+	}
+	if err := validatePlanCommand(step, "-out", "-detailed-exitcode"); err != nil {
+		return err
+	}
+	for pattern, attributes := range step.IgnoreAttributes {
+		if pattern == "" {
+			return fmt.Errorf("ignore_attributes: empty resource address")
+		}
+		if len(attributes) == 0 {
+			return fmt.Errorf("ignore_attributes: no attributes for %s (use \"*\" to ignore the whole resource)", pattern)
+		}
+	}
+	return nil
+}
+
+// drift is a pending change of a resource that should already be up to date
+type drift struct {
+	Address    string
+	Action     string
+	Attributes []string // changed attribute paths, not for creates and deletes
+}
+
+// String renders the drift like "aws_instance.web: update (tags.Name)"
+func (d drift) String() string {
+	if len(d.Attributes) == 0 {
+		return fmt.Sprintf("%s: %s", d.Address, d.Action)
+	}
+	return fmt.Sprintf("%s: %s (%s)", d.Address, d.Action, strings.Join(d.Attributes, ", "))
+}
+
+// executeIdempotencyStep plans again against the applied state and fails if
+// terraform would change anything outside the step's allowlist
+func (e *Executor) executeIdempotencyStep(ctx context.Context, step Step) (*terraform.Plan, string, error) {
+	// Refresh outputs so the command sees the latest values
+	e.refreshOutputs(ctx)
+
+	// Variables reach terraform through TF_VAR_* and -var-file
+	executor, err := e.stepExecutor(step)
+	if err != nil {
+		return nil, "", err
+	}
+
+	cmd, err := e.interpolateCommand(planCommand(step))
+	if err != nil {
+		return nil, "", err
+	}
+	plan, output, err := executor.DriftWithContext(ctx, cmd)
+	if err != nil || plan == nil {
+		return nil, output, err
+	}
+
+	drifts, ignored := planDrift(plan, step.IgnoreAttributes)
+	for _, d := range ignored {
+		ui.PrintDebug(e.debug, "Ignoring drift of %s", d)
+	}
+	if len(drifts) > 0 {
+		lines := make([]string, len(drifts))
+		for i, d := range drifts {
+			lines[i] = d.String()
+		}
+		return plan, output, fmt.Errorf("plan is not idempotent, %d resource(s) would change:\n%s", len(drifts), strings.Join(lines, "\n"))
+	}
+	if len(ignored) == 0 {
+		// Terraform reported changes, but none to resources
+		return plan, output, fmt.Errorf("plan is not idempotent: terraform reports pending changes outside resources (e.g. outputs)")
+	}
+	return plan, output, nil
+}
+
+// planDrift returns the pending changes of managed resources, split into
+// drift and changes covered by the allowlist
+func planDrift(plan *terraform.Plan, ignore map[string]StringList) (drifts, ignored []drift) {
+	for _, rc := range plan.ResourceChanges {
+		action := rc.Action()
+		if rc.Mode != "managed" || action == "no-op" || action == "read" {
+			continue
+		}
+
+		var allowed []string
+		for pattern, attributes := range ignore {
+			if matchAddress(pattern, rc.Address) {
+				allowed = append(allowed, attributes...)
+			}
+		}
+
+		d := drift{Address: rc.Address, Action: action}
+		if action == "update" || action == "replace" {
+			d.Attributes = changedAttributes("", rc.Change.Before, rc.Change.After, rc.Change.AfterUnknown)
+		}
+
+		var kept []string
+		for _, attr := range d.Attributes {
+			if !ignoredAttribute(allowed, attr) {
+				kept = append(kept, attr)
+			}
+		}
+		// Creates and deletes are only ignored with "*"
+		if containsString(allowed, "*") || (action == "update" && len(kept) == 0) {
+			ignored = append(ignored, d)
+			continue
+		}
+		if action == "update" {
+			d.Attributes = kept
+		}
+		drifts = append(drifts, d)
+	}
+	return drifts, ignored
+}
+
+// changedAttributes returns the paths of the values that differ between
+// before and after, like tags.Name or ingress[0].cidr_blocks. Values that
+// are only known after apply count as changed.
+func changedAttributes(path string, before, after, unknown interface{}) []string {
+	if unknown == true {
+		return []string{path}
+	}
+
+	switch b := before.(type) {
+	case map[string]interface{}:
+		a, ok := after.(map[string]interface{})
+		if !ok {
+			break
+		}
+		u, _ := unknown.(map[string]interface{})
+		keys := make(map[string]bool, len(b)+len(a)+len(u))
+		for _, m := range []map[string]interface{}{b, a, u} {
+			for k := range m {
+				keys[k] = true
+			}
+		}
+		names := make([]string, 0, len(keys))
+		for k := range keys {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+
+		var changed []string
+		for _, k := range names {
+			sub := k
+			if path != "" {
+				sub = path + "." + k
+			}
+			changed = append(changed, changedAttributes(sub, b[k], a[k], u[k])...)
+		}
+		return changed
+
+	case []interface{}:
+		a, ok := after.([]interface{})
+		if !ok || len(a) != len(b) {
+			break
+		}
+		u, _ := unknown.([]interface{})
+		var changed []string
+		for i := range b {
+			var elem interface{}
+			if i < len(u) {
+				elem = u[i]
+			}
+			changed = append(changed, changedAttributes(fmt.Sprintf("%s[%d]", path, i), b[i], a[i], elem)...)
+		}
+		return changed
+	}
+
+	if reflect.DeepEqual(before, after) && !hasUnknown(unknown) {
+		return nil
+	}
+	return []string{path}
+}
+
+// hasUnknown reports whether an after_unknown value marks anything unknown
+func hasUnknown(unknown interface{}) bool {
+	switch u := unknown.(type) {
+	case bool:
+		return u
+	case map[string]interface{}:
+		for _, v := range u {
+			if hasUnknown(v) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, v := range u {
+			if hasUnknown(v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ignoredAttribute reports whether an attribute path is in the allowlist;
+// an allowed attribute also covers the values nested in it
+func ignoredAttribute(allowed []string, path string) bool {
+	for _, a := range allowed {
+		if a == "*" || a == path || strings.HasPrefix(path, a+".") || strings.HasPrefix(path, a+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+// matchAddress matches a resource address against a pattern where * stands
+// for any characters, e.g. aws_instance.* or module.*.aws_s3_bucket.logs
+func matchAddress(pattern, address string) bool {
+	if !strings.Contains(pattern, "*") {
+		return pattern == address
+	}
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	re := regexp.MustCompile("^" + strings.Join(parts, ".*") + "$")
+	return re.MatchString(address)
+}
@@ -0,0 +1,133 @@
+package flow
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/infratest/infratest/internal/terraform"
+)
+
+func TestValidateIdempotency(t *testing.T) {
+	tests := []struct {
+		name    string
+		step    Step
+		wantErr string
+	}{
+		{name: "default command", step: Step{Type: "terraform-idempotency", IgnoreAttributes: map[string]StringList{"aws_instance.*": {"tags"}}}},
+		{name: "plan command", step: Step{Type: "terraform-idempotency", Command: "terraform plan -refresh=true"}},
+		{name: "detailed exit code", step: Step{Type: "terraform-idempotency", Command: "plan -detailed-exitcode"}, wantErr: "-detailed-exitcode is set by the step"},
+		{name: "not a plan", step: Step{Type: "terraform-idempotency", Command: "terraform apply"}, wantErr: "terraform-idempotency steps run terraform plan"},
+		{name: "no attributes", step: Step{Type: "terraform-idempotency", IgnoreAttributes: map[string]StringList{"aws_instance.web": nil}}, wantErr: "no attributes for aws_instance.web"},
+		{name: "allowlist on other step", step: Step{Type: "terraform-plan", IgnoreAttributes: map[string]StringList{"aws_instance.web": {"*"}}}, wantErr: "only supported on terraform-idempotency steps"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateIdempotency(tt.step)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateIdempotency() error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateIdempotency() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPlanDrift(t *testing.T) {
+	var plan terraform.Plan
+	err := json.Unmarshal([]byte(`{"resource_changes": [
+		{"address": "aws_vpc.main", "mode": "managed", "change": {"actions": ["no-op"], "before": {"id": "vpc-1"}, "after": {"id": "vpc-1"}}},
+		{"address": "data.aws_ami.ubuntu", "mode": "data", "change": {"actions": ["read"]}},
+		{"address": "aws_instance.web", "mode": "managed", "change": {"actions": ["update"],
+			"before": {"ami": "ami-1", "tags": {"Name": "web", "LastModified": "monday"}},
+			"after": {"ami": "ami-1", "tags": {"Name": "web", "LastModified": "tuesday"}},
+			"after_unknown": {}}},
+		{"address": "aws_security_group.web", "mode": "managed", "change": {"actions": ["update"],
+			"before": {"ingress": [{"cidr_blocks": ["10.0.0.0/8"], "port": 443}], "description": "web"},
+			"after": {"ingress": [{"cidr_blocks": ["0.0.0.0/0"], "port": 443}], "description": "web"},
+			"after_unknown": {"ingress": [{"cidr_blocks": [false]}], "arn": true}}},
+		{"address": "null_resource.always", "mode": "managed", "change": {"actions": ["delete", "create"], "before": {"id": "1"}, "after": {}, "after_unknown": {"id": true}}},
+		{"address": "aws_s3_bucket.logs", "mode": "managed", "change": {"actions": ["create"], "after": {"bucket": "logs"}}}
+	]}`), &plan)
+	if err != nil {
+		t.Fatalf("json.Unmarshal() error: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		ignore      map[string]StringList
+		wantDrift   []string
+		wantIgnored int
+	}{
+		{
+			name: "no allowlist",
+			wantDrift: []string{
+				"aws_instance.web: update (tags.LastModified)",
+				"aws_security_group.web: update (arn, ingress[0].cidr_blocks[0])",
+				"null_resource.always: replace (id)",
+				"aws_s3_bucket.logs: create",
+			},
+		},
+		{
+			name: "allowlist",
+			ignore: map[string]StringList{
+				"aws_instance.*":         {"tags.LastModified"},
+				"aws_security_group.web": {"arn"},
+				"null_resource.always":   {"*"},
+				"aws_s3_bucket.logs":     {"bucket"},
+			},
+			wantDrift: []string{
+				"aws_security_group.web: update (ingress[0].cidr_blocks[0])",
+				"aws_s3_bucket.logs: create",
+			},
+			wantIgnored: 2,
+		},
+		{
+			name:        "nested attributes",
+			ignore:      map[string]StringList{"aws_*": {"tags", "ingress", "arn"}, "aws_s3_bucket.logs": {"*"}, "null_resource.*": {"*"}},
+			wantIgnored: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			drifts, ignored := planDrift(&plan, tt.ignore)
+			var got []string
+			for _, d := range drifts {
+				got = append(got, d.String())
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.wantDrift, "\n") {
+				t.Errorf("planDrift() = %q, want %q", got, tt.wantDrift)
+			}
+			if len(ignored) != tt.wantIgnored {
+				t.Errorf("planDrift() ignored %d change(s), want %d", len(ignored), tt.wantIgnored)
+			}
+		})
+	}
+}
+
+func TestMatchAddress(t *testing.T) {
+	tests := []struct {
+		pattern string
+		address string
+		want    bool
+	}{
+		{"aws_instance.web", "aws_instance.web", true},
+		{"aws_instance.web", "aws_instance.web2", false},
+		{"aws_instance.*", "aws_instance.web[0]", true},
+		{"aws_instance.web[0]", "aws_instance.web[0]", true},
+		{"module.*.aws_s3_bucket.logs", "module.storage.aws_s3_bucket.logs", true},
+		{"module.*.aws_s3_bucket.logs", "aws_s3_bucket.logs", false},
+	}
+
+	for _, tt := range tests {
+		if got := matchAddress(tt.pattern, tt.address); got != tt.want {
+			t.Errorf("matchAddress(%q, %q) = %v, want %v", tt.pattern, tt.address, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,480 @@
+package flow
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// paramRegex matches ${params.NAME} placeholders in templates and included files
+var paramRegex = regexp.MustCompile(`\$\{params\.([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// includeLoader expands `include`, `templates` and `uses` in flow files
+// before they are decoded. It works on YAML nodes so that values keep their
+// original types and line numbers.
+type includeLoader struct {
+	stack   []string              // absolute paths of the files being loaded, outermost first
+	sources map[*yaml.Node]string // file each included step node was read from
+}
+
+// expandIncludes resolves includes and templates in a parsed flow document.
+// Paths are resolved relative to the directory of the file that references them.
+// It returns the file each included step came from, keyed by step node.
+func expandIncludes(root *yaml.Node, path string) (map[*yaml.Node]string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &includeLoader{stack: []string{abs}, sources: make(map[*yaml.Node]string)}
+	templates := make(map[string]*yaml.Node)
+	if err := l.loadTemplates(root, filepath.Dir(abs), templates); err != nil {
+		return nil, err
+	}
+	if err := l.expandSteps(root, filepath.Dir(abs), templates); err != nil {
+		return nil, err
+	}
+	return l.sources, nil
+}
+
+// open reads an included file, guarding against include cycles
+func (l *includeLoader) open(dir string, name string) (*yaml.Node, string, error) {
+	path := name
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	path = filepath.Clean(path)
+
+	for i, p := range l.stack {
+		if p == path {
+			chain := append(append([]string(nil), l.stack[i:]...), path)
+			return nil, "", fmt.Errorf("include cycle detected: %s", l.describeChain(chain))
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: failed to read include: %w", l.current(), err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, "", fmt.Errorf("%s: failed to parse YAML: %w", path, err)
+	}
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	if len(doc.Content) > 0 {
+		root = doc.Content[0]
+	}
+	if root.Kind != yaml.MappingNode {
+		return nil, "", fmt.Errorf("%s: included file must be a mapping with steps or templates", path)
+	}
+
+	l.stack = append(l.stack, path)
+	return root, filepath.Dir(path), nil
+}
+
+// close finishes loading the innermost included file
+func (l *includeLoader) close() {
+	l.stack = l.stack[:len(l.stack)-1]
+}
+
+// current returns the file being loaded, for error messages
+func (l *includeLoader) current() string {
+	return l.stack[len(l.stack)-1]
+}
+
+// describeChain renders an include chain relative to the top-level flow file
+func (l *includeLoader) describeChain(chain []string) string {
+	base := filepath.Dir(l.stack[0])
+	names := make([]string, len(chain))
+	for i, p := range chain {
+		names[i] = p
+		if rel, err := filepath.Rel(base, p); err == nil {
+			names[i] = rel
+		}
+	}
+	return strings.Join(names, " -> ")
+}
+
+// loadTemplates collects the templates a file defines or pulls in through
+// its top-level `include` list, then removes both keys from the file. Files
+// with steps must be included from a step list instead.
+func (l *includeLoader) loadTemplates(root *yaml.Node, dir string, templates map[string]*yaml.Node) error {
+	if inc := mappingValue(root, "include"); inc != nil {
+		paths, err := scalarList(inc)
+		if err != nil {
+			return fmt.Errorf("%s:%d: include: %w", l.current(), inc.Line, err)
+		}
+		for _, p := range paths {
+			frag, fragDir, err := l.open(dir, p)
+			if err != nil {
+				return err
+			}
+			if steps := mappingValue(frag, "steps"); steps != nil {
+				err = fmt.Errorf("%s:%d: a top-level include only loads templates; include files with steps from the step list (- include: %s)", l.current(), steps.Line, p)
+			} else {
+				err = l.loadTemplates(frag, fragDir, templates)
+			}
+			l.close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if tpl := mappingValue(root, "templates"); tpl != nil {
+		if tpl.Kind != yaml.MappingNode {
+			return fmt.Errorf("%s:%d: templates must be a mapping of template names to step definitions", l.current(), tpl.Line)
+		}
+		for i := 0; i+1 < len(tpl.Content); i += 2 {
+			name, def := tpl.Content[i].Value, tpl.Content[i+1]
+			if def.Kind != yaml.MappingNode {
+				return fmt.Errorf("%s:%d: template %s must be a mapping", l.current(), def.Line, name)
+			}
+			if len(l.stack) > 1 {
+				resolveStepPaths(def, dir)
+			}
+			templates[name] = def
+		}
+	}
+
+	removeKeys(root, "include", "templates")
+	return nil
+}
+
+// expandSteps replaces `include` entries in a file's step list with the steps
+// of the included file, and steps that `use` a template with the merged result
+func (l *includeLoader) expandSteps(root *yaml.Node, dir string, templates map[string]*yaml.Node) error {
+	steps := mappingValue(root, "steps")
+	if steps == nil {
+		return nil
+	}
+	if steps.Kind != yaml.SequenceNode {
+		return fmt.Errorf("%s:%d: steps must be a list", l.current(), steps.Line)
+	}
+
+	var expanded []*yaml.Node
+	for _, item := range steps.Content {
+		if item.Kind != yaml.MappingNode {
+			expanded = append(expanded, item)
+			continue
+		}
+
+		if inc := mappingValue(item, "include"); inc != nil {
+			included, err := l.includeSteps(item, inc, dir, templates)
+			if err != nil {
+				return err
+			}
+			expanded = append(expanded, included...)
+			continue
+		}
+
+		if mappingValue(item, "uses") != nil {
+			step, err := l.applyTemplate(item, templates)
+			if err != nil {
+				return err
+			}
+			item = step
+		}
+		expanded = append(expanded, item)
+	}
+
+	steps.Content = expanded
+	return nil
+}
+
+// includeSteps loads the steps of an included file, substituting the
+// parameters given in the entry's `with` block
+func (l *includeLoader) includeSteps(item, inc *yaml.Node, dir string, templates map[string]*yaml.Node) ([]*yaml.Node, error) {
+	if inc.Kind != yaml.ScalarNode {
+		return nil, fmt.Errorf("%s:%d: a step include takes a single file path", l.current(), inc.Line)
+	}
+	for i := 0; i+1 < len(item.Content); i += 2 {
+		if key := item.Content[i].Value; key != "include" && key != "with" {
+			return nil, fmt.Errorf("%s:%d: unexpected field %q next to include (only with is allowed)", l.current(), item.Content[i].Line, key)
+		}
+	}
+
+	frag, fragDir, err := l.open(dir, inc.Value)
+	if err != nil {
+		return nil, err
+	}
+	defer l.close()
+
+	// Included files see the including file's templates as well as their own
+	scoped := make(map[string]*yaml.Node, len(templates))
+	for k, v := range templates {
+		scoped[k] = v
+	}
+	if err := l.loadTemplates(frag, fragDir, scoped); err != nil {
+		return nil, err
+	}
+	if err := l.expandSteps(frag, fragDir, scoped); err != nil {
+		return nil, err
+	}
+
+	params, err := mergeParams(mappingValue(frag, "params"), mappingValue(item, "with"))
+	if err != nil {
+		return nil, fmt.Errorf("%s:%d: %w", l.current(), item.Line, err)
+	}
+
+	steps := mappingValue(frag, "steps")
+	if steps == nil {
+		return nil, nil
+	}
+
+	var out []*yaml.Node
+	for _, s := range steps.Content {
+		step := copyNode(s)
+		if err := substituteParams(step, params); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", l.current(), s.Line, err)
+		}
+		if src, ok := l.sources[s]; ok {
+			l.sources[step] = src
+		} else {
+			l.sources[step] = l.current()
+			resolveStepPaths(step, fragDir)
+		}
+		out = append(out, step)
+	}
+	return out, nil
+}
+
+// resolveStepPaths resolves the relative var_files and workdir of an included
+// step or template against the directory of the file it was read from. Steps
+// of the top-level flow are resolved by the parser. Paths that are template
+// parameters are left to the file that passes them.
+func resolveStepPaths(step *yaml.Node, dir string) {
+	paths := []*yaml.Node{mappingValue(step, "workdir")}
+	if files := mappingValue(step, "var_files"); files != nil {
+		paths = append(paths, files.Content...)
+	}
+	for _, p := range paths {
+		if p != nil && p.Kind == yaml.ScalarNode && p.Value != "" && !filepath.IsAbs(p.Value) && !strings.HasPrefix(p.Value, "${params.") {
+			p.Value = filepath.Join(dir, p.Value)
+		}
+	}
+}
+
+// applyTemplate builds a step from the template it `uses`. Parameters in the
+// template are replaced with the step's `with` values (or the template's
+// `params` defaults); any other field on the step overrides the template's.
+func (l *includeLoader) applyTemplate(item *yaml.Node, templates map[string]*yaml.Node) (*yaml.Node, error) {
+	uses := mappingValue(item, "uses")
+	step, err := l.resolveTemplate(uses, templates, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	params, err := mergeParams(mappingValue(step, "params"), mappingValue(item, "with"))
+	if err != nil {
+		return nil, fmt.Errorf("%s:%d: template %s: %w", l.current(), item.Line, uses.Value, err)
+	}
+	removeKeys(step, "params")
+	if err := substituteParams(step, params); err != nil {
+		return nil, fmt.Errorf("%s:%d: template %s: %w", l.current(), item.Line, uses.Value, err)
+	}
+
+	for i := 0; i+1 < len(item.Content); i += 2 {
+		key := item.Content[i].Value
+		if key == "uses" || key == "with" {
+			continue
+		}
+		setMappingValue(step, item.Content[i], item.Content[i+1])
+	}
+	step.Line, step.Column = item.Line, item.Column
+
+	return step, nil
+}
+
+// resolveTemplate returns a copy of a template merged with the templates it
+// builds on. Parameters are left unsubstituted: a template's `with` values and
+// `params` become defaults layered over those of its base.
+func (l *includeLoader) resolveTemplate(uses *yaml.Node, templates map[string]*yaml.Node, seen []string) (*yaml.Node, error) {
+	name := uses.Value
+	tpl, ok := templates[name]
+	if !ok {
+		return nil, fmt.Errorf("%s:%d: unknown template %q", l.current(), uses.Line, name)
+	}
+	if containsString(seen, name) {
+		return nil, fmt.Errorf("%s:%d: template cycle detected: %s -> %s", l.current(), uses.Line, strings.Join(seen, " -> "), name)
+	}
+
+	step := copyNode(tpl)
+	baseUses := mappingValue(step, "uses")
+	if baseUses == nil {
+		return step, nil
+	}
+
+	base, err := l.resolveTemplate(baseUses, templates, append(seen, name))
+	if err != nil {
+		return nil, err
+	}
+
+	// Layer parameters: the base's defaults, then this template's own
+	// defaults, then the values it passes to the base
+	params := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, layer := range []*yaml.Node{mappingValue(base, "params"), mappingValue(step, "params"), mappingValue(step, "with")} {
+		if layer == nil {
+			continue
+		}
+		if layer.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("%s:%d: template %s: params and with must be mappings", l.current(), layer.Line, name)
+		}
+		for i := 0; i+1 < len(layer.Content); i += 2 {
+			setMappingValue(params, layer.Content[i], layer.Content[i+1])
+		}
+	}
+
+	for i := 0; i+1 < len(step.Content); i += 2 {
+		switch step.Content[i].Value {
+		case "uses", "with", "params":
+			continue
+		}
+		setMappingValue(base, step.Content[i], step.Content[i+1])
+	}
+	removeKeys(base, "params")
+	base.Content = append(base.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "params"}, params)
+
+	return base, nil
+}
+
+// mergeParams combines parameter defaults with the values a caller passes
+func mergeParams(defaults, with *yaml.Node) (map[string]*yaml.Node, error) {
+	params := make(map[string]*yaml.Node)
+	for _, n := range []*yaml.Node{defaults, with} {
+		if n == nil {
+			continue
+		}
+		if n.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("params and with must be mappings")
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			params[n.Content[i].Value] = n.Content[i+1]
+		}
+	}
+	return params, nil
+}
+
+// substituteParams replaces ${params.NAME} placeholders in a node tree. A
+// scalar that consists of a single placeholder takes the parameter's value
+// as-is, so lists, maps, numbers and booleans can be passed through.
+func substituteParams(node *yaml.Node, params map[string]*yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if !strings.Contains(node.Value, "${params.") {
+			return nil
+		}
+
+		if m := paramRegex.FindStringSubmatch(node.Value); m != nil && m[0] == node.Value {
+			val, ok := params[m[1]]
+			if !ok {
+				return fmt.Errorf("missing value for parameter %s", m[1])
+			}
+			line, column := node.Line, node.Column
+			*node = *copyNode(val)
+			node.Line, node.Column = line, column
+			return nil
+		}
+
+		var missing error
+		node.Value = paramRegex.ReplaceAllStringFunc(node.Value, func(match string) string {
+			name := paramRegex.FindStringSubmatch(match)[1]
+			val, ok := params[name]
+			if !ok {
+				missing = fmt.Errorf("missing value for parameter %s", name)
+				return match
+			}
+			if val.Kind != yaml.ScalarNode {
+				missing = fmt.Errorf("parameter %s is not a scalar and can't be embedded in %q", name, node.Value)
+				return match
+			}
+			return val.Value
+		})
+		// Substituted text is always a string, whatever the parameter's type
+		node.Tag = "!!str"
+		return missing
+
+	case yaml.MappingNode:
+		// Only values are substituted; keys stay as written
+		for i := 1; i < len(node.Content); i += 2 {
+			if err := substituteParams(node.Content[i], params); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	default:
+		for _, child := range node.Content {
+			if err := substituteParams(child, params); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// mappingValue returns the value node for a key in a mapping node, or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setMappingValue replaces the value for a key in a mapping node, appending
+// the pair if the key isn't present
+func setMappingValue(node *yaml.Node, key, value *yaml.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key.Value {
+			node.Content[i+1] = value
+			return
+		}
+	}
+	node.Content = append(node.Content, key, value)
+}
+
+// removeKeys deletes keys from a mapping node
+func removeKeys(node *yaml.Node, keys ...string) {
+	var content []*yaml.Node
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if containsString(keys, node.Content[i].Value) {
+			continue
+		}
+		content = append(content, node.Content[i], node.Content[i+1])
+	}
+	node.Content = content
+}
+
+// scalarList reads a node holding a single string or a list of strings
+func scalarList(node *yaml.Node) ([]string, error) {
+	var list StringList
+	if err := node.Decode(&list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// copyNode returns a deep copy of a node tree
+func copyNode(node *yaml.Node) *yaml.Node {
+	if node == nil {
+		return nil
+	}
+	c := *node
+	if len(node.Content) > 0 {
+		c.Content = make([]*yaml.Node, len(node.Content))
+		for i, child := range node.Content {
+			c.Content[i] = copyNode(child)
+		}
+	}
+	return &c
+}
@@ -38,6 +38,49 @@ func Interpolate(template string, outputs map[string]interface{}) string {
 	})
 }
 
+// scopeRegex matches ${namespace.path} references, e.g. ${steps.login.token}
+var scopeRegex = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\.([^}]+)\}`)
+
+// Scope maps interpolation namespaces (output, steps, ...) to their values
+type Scope map[string]map[string]interface{}
+
+// InterpolateScope replaces ${namespace.path} references with values from the scope.
+// References to namespaces that aren't in the scope, or paths that don't resolve,
+// are left unchanged, as with Interpolate.
+func InterpolateScope(template string, scope Scope) string {
+	return scopeRegex.ReplaceAllStringFunc(template, func(match string) string {
+		submatches := scopeRegex.FindStringSubmatch(match)
+		values, ok := scope[submatches[1]]
+		if !ok {
+			return match
+		}
+
+		val, err := terraform.GetOutputValue(values, submatches[2])
+		if err != nil {
+			return match
+		}
+
+		return formatValue(val)
+	})
+}
+
+// placeholderRegex matches any ${...} placeholder
+var placeholderRegex = regexp.MustCompile(`\$\{[^}]*\}`)
+
+// Unresolved returns the ${...} placeholders left in an interpolated string,
+// in order of appearance and without duplicates
+func Unresolved(s string) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, match := range placeholderRegex.FindAllString(s, -1) {
+		if !seen[match] {
+			seen[match] = true
+			out = append(out, match)
+		}
+	}
+	return out
+}
+
 // formatValue formats a value for interpolation
 func formatValue(val interface{}) string {
 	switch v := val.(type) {
@@ -1,6 +1,7 @@
 package interpolator
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -100,3 +101,59 @@ func TestFormatValue(t *testing.T) {
 	}
 }
 
+
+func TestInterpolateScope(t *testing.T) {
+	scope := Scope{
+		"output": {"alb_dns": "alb.example.com"},
+		"steps": {
+			"create-token": map[string]interface{}{
+				"token":   "abc123",
+				"subnets": []interface{}{"subnet-1", "subnet-2"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{"output", "http://${output.alb_dns}/", "http://alb.example.com/"},
+		{"captured value", "Bearer ${steps.create-token.token}", "Bearer abc123"},
+		{"captured list index", "${steps.create-token.subnets[1]}", "subnet-2"},
+		{"mixed", "${output.alb_dns}?t=${steps.create-token.token}", "alb.example.com?t=abc123"},
+		{"unknown variable", "${steps.create-token.missing}", "${steps.create-token.missing}"},
+		{"unknown namespace", "${env.HOME}", "${env.HOME}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := InterpolateScope(tt.template, scope)
+			if got != tt.want {
+				t.Errorf("InterpolateScope() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnresolved(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{"resolved", "http://alb.example.com/", nil},
+		{"single", "http://${output.alb_dns}/", []string{"${output.alb_dns}"}},
+		{"duplicates", "${steps.login.token}:${env.HOME}:${steps.login.token}", []string{"${steps.login.token}", "${env.HOME}"}},
+		{"empty placeholder", "${}", []string{"${}"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Unresolved(tt.in)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Unresolved() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package flow
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/infratest/infratest/internal/inventory"
+	"github.com/infratest/infratest/internal/jsonpath"
+)
+
+// validateExpectedResources checks the patterns and attribute matchers of
+// expected_resources
+func validateExpectedResources(step Step) error {
+	patterns := make([]string, 0, len(step.ExpectedResources))
+	for pattern := range step.ExpectedResources {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+
+	for _, pattern := range patterns {
+		if _, _, _, err := inventory.ParsePattern(pattern); err != nil {
+			return err
+		}
+		attributes := step.ExpectedResources[pattern].Attributes
+		paths := make([]string, 0, len(attributes))
+		for path := range attributes {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			if _, err := jsonpath.Parse(path); err != nil {
+				return fmt.Errorf("%s: attribute %s: %w", pattern, path, err)
+			}
+			if err := inventory.ValidateMatcher(attributes[path]); err != nil {
+				return fmt.Errorf("%s: attribute %s: %w", pattern, path, err)
+			}
+		}
+
+		references := step.ExpectedResources[pattern].References
+		paths = paths[:0]
+		for path := range references {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			if _, err := jsonpath.Parse(path); err != nil {
+				return fmt.Errorf("%s: references %s: %w", pattern, path, err)
+			}
+			if _, err := inventory.ParseReference(references[path]); err != nil {
+				return fmt.Errorf("%s: references %s: %w", pattern, path, err)
+			}
+		}
+	}
+	return nil
+}
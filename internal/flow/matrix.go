@@ -0,0 +1,188 @@
+package flow
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// matrixVarRegex restricts matrix variable names to identifiers usable in ${matrix.<name>}
+var matrixVarRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// runNameRegex matches characters that are replaced in matrix run names
+var runNameRegex = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// matrixRefRegex finds ${matrix.<name>} references in step templates
+var matrixRefRegex = regexp.MustCompile(`\$\{matrix\.([^}.\[]+)`)
+
+// Matrix expands one flow into a run per combination of variable values
+type Matrix struct {
+	Variables map[string][]interface{} // values for each variable
+	Order     []string                 // variable names in declaration order
+	Exclude   []map[string]interface{} // combinations to leave out
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler. Every key is a variable with a
+// list of values, except `exclude`, which lists combinations to skip.
+func (m *Matrix) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: matrix must be a mapping of variable names to lists of values", value.Line)
+	}
+
+	m.Variables = make(map[string][]interface{})
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		key, val := value.Content[i], value.Content[i+1]
+
+		if key.Value == "exclude" {
+			if err := val.Decode(&m.Exclude); err != nil {
+				return fmt.Errorf("line %d: matrix exclude must be a list of variable combinations: %w", val.Line, err)
+			}
+			continue
+		}
+
+		if val.Kind != yaml.SequenceNode {
+			return fmt.Errorf("line %d: matrix variable %s must be a list of values", val.Line, key.Value)
+		}
+		var values []interface{}
+		if err := val.Decode(&values); err != nil {
+			return fmt.Errorf("line %d: matrix variable %s: %w", val.Line, key.Value, err)
+		}
+		m.Variables[key.Value] = values
+		m.Order = append(m.Order, key.Value)
+	}
+	return nil
+}
+
+// validate checks variable names, values and exclusions
+func (m *Matrix) validate() error {
+	if m == nil {
+		return nil
+	}
+	if len(m.Order) == 0 {
+		return fmt.Errorf("matrix must define at least one variable")
+	}
+	for _, name := range m.Order {
+		if !matrixVarRegex.MatchString(name) {
+			return fmt.Errorf("invalid matrix variable name %q (use letters, digits and underscores)", name)
+		}
+		if len(m.Variables[name]) == 0 {
+			return fmt.Errorf("matrix variable %s has no values", name)
+		}
+	}
+	for _, ex := range m.Exclude {
+		for name := range ex {
+			if _, ok := m.Variables[name]; !ok {
+				return fmt.Errorf("matrix exclude references unknown variable %s", name)
+			}
+		}
+	}
+	if len(m.Cells()) == 0 {
+		return fmt.Errorf("matrix excludes every combination")
+	}
+	return nil
+}
+
+// Cells returns every combination of variable values, minus exclusions.
+// The first variable changes slowest.
+func (m *Matrix) Cells() []map[string]interface{} {
+	cells := []map[string]interface{}{{}}
+	for _, name := range m.Order {
+		var next []map[string]interface{}
+		for _, cell := range cells {
+			for _, val := range m.Variables[name] {
+				c := make(map[string]interface{}, len(cell)+1)
+				for k, v := range cell {
+					c[k] = v
+				}
+				c[name] = val
+				next = append(next, c)
+			}
+		}
+		cells = next
+	}
+
+	var out []map[string]interface{}
+	for _, cell := range cells {
+		if !m.excluded(cell) {
+			out = append(out, cell)
+		}
+	}
+	return out
+}
+
+// excluded reports whether a cell matches one of the exclude entries
+func (m *Matrix) excluded(cell map[string]interface{}) bool {
+	for _, ex := range m.Exclude {
+		match := true
+		for k, v := range ex {
+			if fmt.Sprint(cell[k]) != fmt.Sprint(v) {
+				match = false
+				break
+			}
+		}
+		if match && len(ex) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpandMatrix returns one flow per matrix cell, each with its own name and
+// matrix values. A flow without a matrix is returned as-is.
+func (f *Flow) ExpandMatrix() []*Flow {
+	if f.Matrix == nil {
+		return []*Flow{f}
+	}
+
+	var runs []*Flow
+	for _, cell := range f.Matrix.Cells() {
+		run := *f
+		run.Matrix = nil
+		run.MatrixValues = cell
+		run.matrixOrder = f.Matrix.Order
+		run.Steps = append([]Step(nil), f.Steps...)
+
+		suffix := make([]string, len(f.Matrix.Order))
+		for i, name := range f.Matrix.Order {
+			suffix[i] = sanitizeRunName(fmt.Sprint(cell[name]))
+		}
+		run.Name = f.Name + "-" + strings.Join(suffix, "-")
+
+		runs = append(runs, &run)
+	}
+	return runs
+}
+
+// MatrixLabel describes a matrix run, e.g. "instance_type=t3.micro, az_count=2"
+func (f *Flow) MatrixLabel() string {
+	parts := make([]string, 0, len(f.matrixOrder))
+	for _, name := range f.matrixOrder {
+		parts = append(parts, fmt.Sprintf("%s=%v", name, f.MatrixValues[name]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// sanitizeRunName makes a matrix value safe to use in run names and report paths
+func sanitizeRunName(s string) string {
+	return strings.Trim(runNameRegex.ReplaceAllString(s, "_"), "_")
+}
+
+// validateMatrixRefs checks that ${matrix.<name>} references in steps name
+// variables declared in the flow's matrix
+func validateMatrixRefs(f *Flow) error {
+	for _, step := range f.Steps {
+		for _, tmpl := range step.templates() {
+			for _, m := range matrixRefRegex.FindAllStringSubmatch(tmpl, -1) {
+				if f.Matrix == nil {
+					return fmt.Errorf("step %s references ${matrix.%s}, but the flow has no matrix", step.Name, m[1])
+				}
+				if _, ok := f.Matrix.Variables[m[1]]; !ok {
+					return fmt.Errorf("step %s references unknown matrix variable %s", step.Name, m[1])
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package flow
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseFlowWithMatrix(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"flow.yaml": `name: vpc
+working_dir: ./terraform
+matrix:
+  region: [us-east-1, eu-west-1]
+  az_count: [1, 2]
+  exclude:
+    - region: eu-west-1
+      az_count: 2
+steps:
+  - name: apply
+    type: terraform
+    command: apply -auto-approve
+`,
+	})
+
+	f, err := ParseFlow(filepath.Join(dir, "flow.yaml"))
+	if err != nil {
+		t.Fatalf("ParseFlow() error: %v", err)
+	}
+
+	runs := f.ExpandMatrix()
+	want := []struct {
+		name  string
+		label string
+	}{
+		{"vpc-us-east-1-1", "region=us-east-1, az_count=1"},
+		{"vpc-us-east-1-2", "region=us-east-1, az_count=2"},
+		{"vpc-eu-west-1-1", "region=eu-west-1, az_count=1"},
+	}
+	if len(runs) != len(want) {
+		t.Fatalf("Expected %d runs, got %d", len(want), len(runs))
+	}
+	for i, w := range want {
+		if runs[i].Name != w.name {
+			t.Errorf("run %d: expected name %s, got %s", i, w.name, runs[i].Name)
+		}
+		if got := runs[i].MatrixLabel(); got != w.label {
+			t.Errorf("run %d: expected label %q, got %q", i, w.label, got)
+		}
+		if runs[i].Matrix != nil {
+			t.Errorf("run %d: expected matrix to be cleared", i)
+		}
+	}
+	if runs[0].MatrixValues["az_count"] != 1 {
+		t.Errorf("Expected az_count to keep its type, got %#v", runs[0].MatrixValues["az_count"])
+	}
+
+	if got := f.ExpandMatrix(); len(got) != 3 {
+		t.Errorf("ExpandMatrix() should not modify the flow, got %d runs on second call", len(got))
+	}
+	if got := (&Flow{Name: "plain"}).ExpandMatrix(); len(got) != 1 || got[0].Name != "plain" {
+		t.Errorf("Expected a flow without matrix to run once, got %v", got)
+	}
+}
+
+func TestValidateMatrix(t *testing.T) {
+	tests := []struct {
+		name    string
+		flow    string
+		wantErr string
+	}{
+		{
+			name:    "variable is not a list",
+			flow:    "matrix:\n  region: us-east-1\n",
+			wantErr: "must be a list of values",
+		},
+		{
+			name:    "variable without values",
+			flow:    "matrix:\n  region: []\n",
+			wantErr: "has no values",
+		},
+		{
+			name:    "invalid variable name",
+			flow:    "matrix:\n  aws-region: [us-east-1]\n",
+			wantErr: "invalid matrix variable name",
+		},
+		{
+			name:    "exclude references unknown variable",
+			flow:    "matrix:\n  region: [us-east-1]\n  exclude:\n    - zone: a\n",
+			wantErr: "unknown variable zone",
+		},
+		{
+			name:    "everything excluded",
+			flow:    "matrix:\n  region: [us-east-1]\n  exclude:\n    - region: us-east-1\n",
+			wantErr: "excludes every combination",
+		},
+		{
+			name:    "unknown matrix reference",
+			flow:    "matrix:\n  region: [us-east-1]\nsteps:\n  - name: check\n    type: http\n    url: http://${matrix.zone}/\n    expected_status: 200\n",
+			wantErr: "unknown matrix variable zone",
+		},
+		{
+			name:    "matrix reference without matrix",
+			flow:    "steps:\n  - name: check\n    type: http\n    url: http://${matrix.zone}/\n    expected_status: 200\n",
+			wantErr: "the flow has no matrix",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			content := "name: vpc\nworking_dir: ./terraform\n" + tt.flow
+			if !strings.Contains(tt.flow, "steps:") {
+				content += "steps:\n  - name: apply\n    type: terraform\n    command: apply\n"
+			}
+			writeFiles(t, dir, map[string]string{"flow.yaml": content})
+
+			_, err := ParseFlow(filepath.Join(dir, "flow.yaml"))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseFlow() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExecuteWithContextMatrix(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/eu-west-1/2" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	f := &Flow{
+		Name:       "matrix",
+		WorkingDir: t.TempDir(),
+		Matrix: &Matrix{
+			Variables: map[string][]interface{}{"region": {"eu-west-1"}, "az_count": {2}},
+			Order:     []string{"region", "az_count"},
+		},
+		Steps: []Step{
+			{Name: "check", Type: "http", URL: server.URL + "/${matrix.region}/${matrix.az_count}", ExpectedStatus: 200, Retries: 1, Delay: "10ms"},
+			{Name: "multi-az", Type: "http", When: "matrix.az_count > 1", URL: server.URL + "/eu-west-1/2", ExpectedStatus: 200, Retries: 1, Delay: "10ms"},
+		},
+	}
+	if err := validateFlow(f); err != nil {
+		t.Fatalf("validateFlow() error: %v", err)
+	}
+
+	run := f.ExpandMatrix()[0]
+	e := &Executor{flow: run, outputs: map[string]interface{}{}}
+	if err := e.ExecuteWithContext(context.Background()); err != nil {
+		t.Fatalf("ExecuteWithContext() error: %v", err)
+	}
+
+	for _, r := range e.GetResults() {
+		if !r.Success || r.Skipped {
+			t.Errorf("Expected step %s to run and succeed with matrix values, got %+v", r.StepName, r)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package flow
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/infratest/infratest/internal/network"
+	"github.com/infratest/infratest/internal/ui"
+)
+
+// validateNetwork checks the fields of a tcp or udp step, and that other steps don't use them
+func validateNetwork(step Step) error {
+	if step.Type != "tcp" && step.Type != "udp" {
+		if step.Address != "" || step.Reachable != nil || step.Send != "" || step.Banner != "" {
+			return fmt.Errorf("address, reachable, send and banner are only supported on tcp and udp steps")
+		}
+		return nil
+	}
+
+	if step.Address != "" && !strings.Contains(step.Address, "${") {
+		if _, _, err := net.SplitHostPort(step.Address); err != nil {
+			return fmt.Errorf("invalid address: %w", err)
+		}
+	}
+	// A udp probe may send a payload the service would answer
+	if step.Reachable != nil && !*step.Reachable && (step.Banner != "" || step.Send != "" && step.Type == "tcp") {
+		return fmt.Errorf("send and banner require a reachable port")
+	}
+	return nil
+}
+
+// networkCheck builds the connectivity check of a tcp or udp step, with its address interpolated
+func (e *Executor) networkCheck(step Step) network.Check {
+	return network.Check{
+		Network:   step.Type,
+		Address:   e.interpolate(step.Address),
+		Reachable: step.Reachable == nil || *step.Reachable,
+		Send:      []byte(e.interpolate(step.Send)),
+		Banner:    e.interpolate(step.Banner),
+	}
+}
+
+// executeNetworkStep dials the address of a tcp or udp step and checks that
+// it is reachable (or not), returning a summary for the step output
+func (e *Executor) executeNetworkStep(ctx context.Context, step Step) (string, network.Result, error) {
+	// Refresh outputs so the address sees the latest values
+	if err := e.refreshOutputs(ctx); err != nil {
+		ui.PrintDebug(e.debug, "Warning: failed to refresh outputs: %v", err)
+	}
+
+	check := e.networkCheck(step)
+	ui.PrintDebug(e.debug, "Original address template: %s", step.Address)
+	ui.PrintDebug(e.debug, "Interpolated address: %s", e.interpolateMasked(step.Address))
+
+	retries, delay := checkRetries(step)
+	res, err := network.CheckWithRetryContext(ctx, check, retries, delay, e.debug)
+
+	var output string
+	switch {
+	case res.Reachable && res.Latency == 0:
+		output = fmt.Sprintf("%s %s reachable (no reply)", check.Network, check.Address)
+	case res.Reachable:
+		output = fmt.Sprintf("%s %s reachable (latency %s)", check.Network, check.Address, res.Latency.Round(time.Microsecond))
+	case res.Reason != "":
+		output = fmt.Sprintf("%s %s unreachable: %s", check.Network, check.Address, res.Reason)
+	}
+	return output, res, err
+}
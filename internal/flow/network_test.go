@@ -0,0 +1,93 @@
+package flow
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestValidateNetwork(t *testing.T) {
+	closed := false
+	tests := []struct {
+		name    string
+		step    Step
+		wantErr string
+	}{
+		{name: "tcp", step: Step{Type: "tcp", Address: "db.internal:5432", Banner: "ok"}},
+		{name: "udp placeholder", step: Step{Type: "udp", Address: "${output.dns_ip}:53", Send: "ping"}},
+		{name: "missing port", step: Step{Type: "tcp", Address: "db.internal"}, wantErr: "invalid address"},
+		{name: "banner on closed port", step: Step{Type: "tcp", Address: "db:22", Reachable: &closed, Banner: "SSH"}, wantErr: "require a reachable port"},
+		{name: "send on closed tcp port", step: Step{Type: "tcp", Address: "db:22", Reachable: &closed, Send: "x"}, wantErr: "require a reachable port"},
+		{name: "probe on closed udp port", step: Step{Type: "udp", Address: "db:161", Reachable: &closed, Send: "x"}},
+		{name: "banner on closed udp port", step: Step{Type: "udp", Address: "db:161", Reachable: &closed, Banner: "x"}, wantErr: "require a reachable port"},
+		{name: "address on other step", step: Step{Type: "http", URL: "http://x", Address: "x:80"}, wantErr: "only supported on tcp and udp steps"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateNetwork(tt.step)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateNetwork() error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateNetwork() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExecuteWithContextTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write([]byte("+OK redis 7.2\r\n"))
+			conn.Close()
+		}
+	}()
+	closedListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error: %v", err)
+	}
+	closedListener.Close()
+
+	closed := false
+	f := &Flow{
+		Name:       "tcp",
+		WorkingDir: t.TempDir(),
+		Steps: []Step{
+			{Name: "redis", Type: "tcp", Address: listener.Addr().String(), Banner: "+OK", Retries: 1, Delay: "10ms",
+				Capture: map[string]CaptureSpec{"version": {Regex: `redis ([0-9.]+)`}}},
+			{Name: "admin-closed", Type: "tcp", Address: closedListener.Addr().String(), Reachable: &closed, Retries: 1, Delay: "10ms"},
+		},
+	}
+	if err := validateFlow(f); err != nil {
+		t.Fatalf("validateFlow() error: %v", err)
+	}
+
+	e := &Executor{flow: f, outputs: map[string]interface{}{}}
+	if err := e.ExecuteWithContext(context.Background()); err != nil {
+		t.Fatalf("ExecuteWithContext() error: %v", err)
+	}
+	results := e.GetResults()
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %+v", results)
+	}
+	if results[0].Latency <= 0 || results[0].Captured["version"] != "7.2" {
+		t.Errorf("Unexpected result: %+v", results[0])
+	}
+	if !strings.Contains(results[1].Output, "unreachable") {
+		t.Errorf("Output = %q, want unreachable", results[1].Output)
+	}
+}
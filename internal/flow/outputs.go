@@ -0,0 +1,218 @@
+package flow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/infratest/infratest/internal/terraform"
+)
+
+// outputTypes are the value types a terraform-output step can assert
+var outputTypes = []string{"string", "number", "bool", "list", "map"}
+
+// validateOutputs checks the assertions of a terraform-output step, and that
+// other steps don't use outputs
+func validateOutputs(step Step) error {
+	if step.Type != "terraform-output" {
+		if len(step.Outputs) > 0 {
+			return fmt.Errorf("outputs are only supported on terraform-output steps")
+		}
+		return nil
+	}
+
+	for _, path := range sortedOutputPaths(step.Outputs) {
+		a := step.Outputs[path]
+		if outputName(path) == "" {
+			return fmt.Errorf("outputs: invalid output path %q", path)
+		}
+		if a.Exists != nil && !*a.Exists {
+			if a.Type != "" || a.Equals != nil || a.Matches != "" || a.Length != nil || a.Sensitive != nil {
+				return fmt.Errorf("outputs: %s: exists: false can't be combined with other assertions", path)
+			}
+			continue
+		}
+		if a.Type != "" && !containsString(outputTypes, a.Type) {
+			return fmt.Errorf("outputs: %s: unknown type %q (expected %s)", path, a.Type, strings.Join(outputTypes, ", "))
+		}
+		if a.Matches != "" && !strings.Contains(a.Matches, "${") {
+			if _, err := regexp.Compile(a.Matches); err != nil {
+				return fmt.Errorf("outputs: %s: invalid matches regex: %w", path, err)
+			}
+		}
+		if a.Length != nil && *a.Length < 0 {
+			return fmt.Errorf("outputs: %s: length must not be negative", path)
+		}
+		if a.Sensitive != nil && path != outputName(path) {
+			return fmt.Errorf("outputs: %s: sensitive applies to whole outputs, use %s", path, outputName(path))
+		}
+	}
+	return nil
+}
+
+// executeOutputStep reads the terraform outputs and checks them against the
+// step's assertions, reporting every failed assertion at once. The output
+// lists the checked values, with sensitive ones masked.
+func (e *Executor) executeOutputStep(ctx context.Context, step Step) (string, error) {
+	details, err := e.readOutputDetails(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to read outputs: %w", err)
+	}
+
+	// Keep interpolation in sync with the outputs just read
+	values := e.setOutputs(details)
+
+	var lines, issues []string
+	for _, path := range sortedOutputPaths(step.Outputs) {
+		a := e.outputAssertion(step.Outputs[path])
+		o, declared := details[outputName(path)]
+		value, err := terraform.GetOutputValue(values, path)
+		exists := declared && err == nil
+
+		if a.Exists != nil && !*a.Exists {
+			if exists {
+				issues = append(issues, fmt.Sprintf("%s: expected no output, got %s", path, formatOutputValue(value, o.Sensitive)))
+			}
+			continue
+		}
+		if !exists {
+			if !declared {
+				err = fmt.Errorf("output %s not found", outputName(path))
+			}
+			issues = append(issues, fmt.Sprintf("%s: %v", path, err))
+			continue
+		}
+
+		lines = append(lines, fmt.Sprintf("%s = %s", path, formatOutputValue(value, o.Sensitive)))
+		for _, issue := range a.check(value, o.Sensitive) {
+			issues = append(issues, fmt.Sprintf("%s: %s", path, issue))
+		}
+	}
+
+	output := strings.Join(lines, "\n")
+	if len(issues) > 0 {
+		return output, fmt.Errorf("output check failed:\n%s", strings.Join(issues, "\n"))
+	}
+	return output, nil
+}
+
+// templates returns the assertion fields that are interpolated
+func (a OutputAssertion) templates() []string {
+	templates := []string{a.Matches}
+	if s, ok := a.Equals.(string); ok {
+		templates = append(templates, s)
+	}
+	return templates
+}
+
+// outputAssertion returns a copy of an assertion with its fields interpolated
+func (e *Executor) outputAssertion(a OutputAssertion) OutputAssertion {
+	a.Matches = e.interpolate(a.Matches)
+	if s, ok := a.Equals.(string); ok {
+		a.Equals = e.interpolate(s)
+	}
+	return a
+}
+
+// check returns the assertions a value fails; sensitive values are not
+// printed
+func (a OutputAssertion) check(value interface{}, sensitive bool) []string {
+	var issues []string
+	typ := outputType(value)
+	if a.Type != "" && a.Type != typ {
+		issues = append(issues, fmt.Sprintf("expected type %s, got %s", a.Type, typ))
+	}
+	if a.Sensitive != nil && *a.Sensitive != sensitive {
+		issues = append(issues, fmt.Sprintf("expected sensitive: %t, got %t", *a.Sensitive, sensitive))
+	}
+	if a.Equals != nil && !jsonValuesEqual(a.Equals, value) {
+		if sensitive {
+			issues = append(issues, "sensitive value doesn't match equals")
+		} else {
+			issues = append(issues, fmt.Sprintf("expected %v, got %s", a.Equals, formatOutputValue(value, false)))
+		}
+	}
+	if a.Matches != "" {
+		re, err := regexp.Compile(a.Matches)
+		switch {
+		case err != nil:
+			issues = append(issues, fmt.Sprintf("invalid matches regex: %v", err))
+		case typ == "list" || typ == "map" || typ == "null":
+			issues = append(issues, fmt.Sprintf("matches requires a string, number or bool, got %s", typ))
+		case !re.MatchString(fmt.Sprintf("%v", value)):
+			if sensitive {
+				issues = append(issues, fmt.Sprintf("sensitive value doesn't match %q", a.Matches))
+			} else {
+				issues = append(issues, fmt.Sprintf("%v doesn't match %q", value, a.Matches))
+			}
+		}
+	}
+	if a.Length != nil {
+		switch v := value.(type) {
+		case []interface{}:
+			if len(v) != *a.Length {
+				issues = append(issues, fmt.Sprintf("expected length %d, got %d", *a.Length, len(v)))
+			}
+		case map[string]interface{}:
+			if len(v) != *a.Length {
+				issues = append(issues, fmt.Sprintf("expected length %d, got %d", *a.Length, len(v)))
+			}
+		default:
+			issues = append(issues, fmt.Sprintf("length requires a list or map, got %s", typ))
+		}
+	}
+	return issues
+}
+
+// outputType returns the type of a decoded output value: string, number,
+// bool, list (also tuples and sets), map (also objects) or null
+func outputType(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "bool"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "map"
+	default:
+		return "null"
+	}
+}
+
+// formatOutputValue renders an output value as JSON, masking sensitive values
+func formatOutputValue(value interface{}, sensitive bool) string {
+	if sensitive {
+		return "(sensitive)"
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
+
+// outputName returns the name of the output a path starts with, e.g.
+// subnets for subnets[0].cidr
+func outputName(path string) string {
+	if i := strings.IndexAny(path, ".["); i >= 0 {
+		return path[:i]
+	}
+	return path
+}
+
+// sortedOutputPaths returns the output paths of a terraform-output step in order
+func sortedOutputPaths(outputs map[string]OutputAssertion) []string {
+	paths := make([]string, 0, len(outputs))
+	for path := range outputs {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -0,0 +1,87 @@
+package flow
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestValidateOutputs(t *testing.T) {
+	no := false
+	yes := true
+	length := -1
+	tests := []struct {
+		name    string
+		step    Step
+		wantErr string
+	}{
+		{name: "assertions", step: Step{Type: "terraform-output", Outputs: map[string]OutputAssertion{"subnets[0].cidr": {Type: "string", Matches: `^10\.`}, "db_password": {Sensitive: &yes}}}},
+		{name: "missing output", step: Step{Type: "terraform-output", Outputs: map[string]OutputAssertion{"legacy_dns": {Exists: &no}}}},
+		{name: "missing with type", step: Step{Type: "terraform-output", Outputs: map[string]OutputAssertion{"legacy_dns": {Exists: &no, Type: "string"}}}, wantErr: "can't be combined"},
+		{name: "unknown type", step: Step{Type: "terraform-output", Outputs: map[string]OutputAssertion{"ids": {Type: "array"}}}, wantErr: `unknown type "array"`},
+		{name: "invalid regex", step: Step{Type: "terraform-output", Outputs: map[string]OutputAssertion{"dns": {Matches: "("}}}, wantErr: "invalid matches regex"},
+		{name: "negative length", step: Step{Type: "terraform-output", Outputs: map[string]OutputAssertion{"ids": {Length: &length}}}, wantErr: "must not be negative"},
+		{name: "nested sensitive", step: Step{Type: "terraform-output", Outputs: map[string]OutputAssertion{"db.password": {Sensitive: &yes}}}, wantErr: "use db"},
+		{name: "invalid path", step: Step{Type: "terraform-output", Outputs: map[string]OutputAssertion{"[0]": {}}}, wantErr: "invalid output path"},
+		{name: "outputs on other step", step: Step{Type: "terraform", Command: "apply", Outputs: map[string]OutputAssertion{"ids": {}}}, wantErr: "only supported on terraform-output steps"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateOutputs(tt.step)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateOutputs() error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateOutputs() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOutputAssertionCheck(t *testing.T) {
+	tests := []struct {
+		name      string
+		assertion string
+		value     string
+		sensitive bool
+		want      []string
+	}{
+		{name: "string", assertion: `{type: string, equals: alb.example.com, matches: '\.com$'}`, value: `"alb.example.com"`},
+		{name: "number", assertion: `{type: number, equals: 5432}`, value: `5432`},
+		{name: "bool", assertion: `{type: bool, equals: true}`, value: `true`},
+		{name: "list", assertion: `{type: list, length: 2, equals: [a, b]}`, value: `["a", "b"]`},
+		{name: "map", assertion: `{type: map, length: 1, equals: {Name: web}}`, value: `{"Name": "web"}`},
+		{name: "wrong type", assertion: `{type: number}`, value: `"5432"`, want: []string{"expected type number, got string"}},
+		{name: "wrong length", assertion: `{length: 3}`, value: `["a"]`, want: []string{"expected length 3, got 1"}},
+		{name: "length of string", assertion: `{length: 3}`, value: `"abc"`, want: []string{"length requires a list or map, got string"}},
+		{name: "no match", assertion: `{matches: '^10\.'}`, value: `"192.168.0.1"`, want: []string{`192.168.0.1 doesn't match "^10\\."`}},
+		{name: "matches list", assertion: `{matches: a}`, value: `["a"]`, want: []string{"matches requires a string, number or bool, got list"}},
+		{name: "not equal", assertion: `{equals: [a, c]}`, value: `["a", "b"]`, want: []string{`expected [a c], got ["a","b"]`}},
+		{name: "sensitive flag", assertion: `{sensitive: true}`, value: `"x"`, want: []string{"expected sensitive: true, got false"}},
+		{name: "sensitive value masked", assertion: `{sensitive: true, equals: hunter2, matches: '^h'}`, value: `"s3cret"`, sensitive: true,
+			want: []string{"sensitive value doesn't match equals", `sensitive value doesn't match "^h"`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var a OutputAssertion
+			if err := yaml.Unmarshal([]byte(tt.assertion), &a); err != nil {
+				t.Fatalf("yaml.Unmarshal() error: %v", err)
+			}
+			var value interface{}
+			if err := json.Unmarshal([]byte(tt.value), &value); err != nil {
+				t.Fatalf("json.Unmarshal() error: %v", err)
+			}
+			got := a.check(value, tt.sensitive)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("check() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -10,16 +10,50 @@ import (
 
 // ParseFlow reads and parses a YAML flow file
 func ParseFlow(path string) (*Flow, error) {
+	return parseFlow(path, false)
+}
+
+// ParseFlowStrict parses a flow file like ParseFlow, but also rejects unknown
+// fields and checks every step against its type. Problems are returned as
+// ValidationErrors, each with the YAML line and column it was found at.
+func ParseFlowStrict(path string) (*Flow, error) {
+	return parseFlow(path, true)
+}
+
+func parseFlow(path string, strict bool) (*Flow, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read flow file: %w", err)
 	}
 
-	var flow Flow
-	if err := yaml.Unmarshal(data, &flow); err != nil {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
 		return nil, fmt.Errorf("failed to parse YAML: %w", err)
 	}
 
+	var flow Flow
+	var root *yaml.Node
+	var sources map[*yaml.Node]string
+	var fieldErrs ValidationErrors
+	if len(doc.Content) > 0 {
+		root = doc.Content[0]
+		if root.Kind == yaml.MappingNode {
+			// Splice in included steps and templates before decoding
+			if sources, err = expandIncludes(root, path); err != nil {
+				return nil, fmt.Errorf("failed to expand includes: %w", err)
+			}
+		}
+		if strict {
+			fieldErrs = checkFields(root, path, sources)
+		}
+		if err := root.Decode(&flow); err != nil {
+			if len(fieldErrs) > 0 {
+				return nil, fieldErrs
+			}
+			return nil, fmt.Errorf("failed to parse YAML: %w", err)
+		}
+	}
+
 	// Resolve working_dir relative to the flow file's directory
 	flowFileDir := filepath.Dir(path)
 	if !filepath.IsAbs(flow.WorkingDir) {
@@ -29,9 +63,33 @@ func ParseFlow(path string) (*Flow, error) {
 	// Clean the path to remove any ".." or "." components
 	flow.WorkingDir = filepath.Clean(flow.WorkingDir)
 
+	// Resolve var files the same way
+	resolveVarFiles(flow.VarFiles, flowFileDir)
+	for i, step := range flow.Steps {
+		resolveVarFiles(step.VarFiles, flowFileDir)
+		if step.Workdir != "" && !filepath.IsAbs(step.Workdir) {
+			flow.Steps[i].Workdir = filepath.Join(flowFileDir, step.Workdir)
+		}
+	}
+
+	if strict {
+		if errs := append(fieldErrs, validateStrict(&flow, root, path, sources)...); len(errs) > 0 {
+			errs.sort(path)
+			return nil, errs
+		}
+	}
 	if err := validateFlow(&flow); err != nil {
+		if strict {
+			// Anything the strict checks missed is reported at the document root
+			return nil, ValidationErrors{newValidationError(path, root, err)}
+		}
 		return nil, fmt.Errorf("invalid flow: %w", err)
 	}
+	if abs, err := filepath.Abs(path); err == nil {
+		flow.Path = abs
+	} else {
+		flow.Path = path
+	}
 
 	return &flow, nil
 }
@@ -46,6 +104,65 @@ func validateFlow(flow *Flow) error {
 	if len(flow.Steps) == 0 {
 		return fmt.Errorf("at least one step is required")
 	}
+	if flow.MaxParallel < 0 {
+		return fmt.Errorf("max_parallel must not be negative")
+	}
+	if _, err := parseTimeout(flow.Timeout); err != nil {
+		return fmt.Errorf("invalid flow timeout: %w", err)
+	}
+	if err := validateIsolation(flow); err != nil {
+		return err
+	}
+	if err := flow.Matrix.validate(); err != nil {
+		return fmt.Errorf("invalid matrix: %w", err)
+	}
+	if err := validateMatrixRefs(flow); err != nil {
+		return err
+	}
+	if err := validateVariables(flow.Variables); err != nil {
+		return err
+	}
+	for _, step := range flow.Steps {
+		if _, err := parseTimeout(step.Timeout); err != nil {
+			return fmt.Errorf("step %s: invalid timeout: %w", step.Name, err)
+		}
+		if err := validateVariables(step.Variables); err != nil {
+			return fmt.Errorf("step %s: %w", step.Name, err)
+		}
+		if (len(step.Variables) > 0 || len(step.VarFiles) > 0) && !runsTerraform(step) {
+			return fmt.Errorf("step %s: variables and var_files are only supported on terraform steps", step.Name)
+		}
+		if err := step.Retry.validate(); err != nil {
+			return fmt.Errorf("step %s: %w", step.Name, err)
+		}
+		if err := validateExec(step); err != nil {
+			return fmt.Errorf("step %s: %w", step.Name, err)
+		}
+		if err := validateWait(step); err != nil {
+			return fmt.Errorf("step %s: %w", step.Name, err)
+		}
+		if err := validateNetwork(step); err != nil {
+			return fmt.Errorf("step %s: %w", step.Name, err)
+		}
+		if err := validateDNS(step); err != nil {
+			return fmt.Errorf("step %s: %w", step.Name, err)
+		}
+		if err := validatePlanChanges(step); err != nil {
+			return fmt.Errorf("step %s: %w", step.Name, err)
+		}
+		if err := validateIdempotency(step); err != nil {
+			return fmt.Errorf("step %s: %w", step.Name, err)
+		}
+		if err := validateOutputs(step); err != nil {
+			return fmt.Errorf("step %s: %w", step.Name, err)
+		}
+		if err := validateExpectedResources(step); err != nil {
+			return fmt.Errorf("step %s: %w", step.Name, err)
+		}
+	}
+	if err := validateDependencies(flow.Steps); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -2,6 +2,8 @@ package flow
 
 import (
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -183,6 +185,26 @@ func TestValidateFlow(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "workspace isolation",
+			flow: &Flow{
+				Name:       "test",
+				WorkingDir: "./terraform",
+				Isolation:  "workspace",
+				Steps:      []Step{{Name: "test", Type: "terraform"}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "unknown isolation",
+			flow: &Flow{
+				Name:       "test",
+				WorkingDir: "./terraform",
+				Isolation:  "container",
+				Steps:      []Step{{Name: "test", Type: "terraform"}},
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -195,3 +217,264 @@ func TestValidateFlow(t *testing.T) {
 	}
 }
 
+
+func TestParseFlowWithDependencyList(t *testing.T) {
+	yamlContent := `name: dag-test
+working_dir: ./terraform
+max_parallel: 4
+steps:
+  - name: apply
+    type: terraform
+    command: terraform apply -auto-approve
+  - name: smoke
+    type: http
+    after: apply
+    url: http://example.com
+  - name: inventory
+    type: terraform-inventory
+    after: [apply, smoke]
+`
+
+	tmpFile, err := os.CreateTemp("", "test-dag-*.yaml")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.WriteString(yamlContent); err != nil {
+		t.Fatalf("Failed to write temp file: %v", err)
+	}
+	tmpFile.Close()
+
+	flow, err := ParseFlow(tmpFile.Name())
+	if err != nil {
+		t.Fatalf("Failed to parse flow: %v", err)
+	}
+
+	if flow.MaxParallel != 4 {
+		t.Errorf("Expected max_parallel 4, got %d", flow.MaxParallel)
+	}
+	if len(flow.Steps[1].After) != 1 || flow.Steps[1].After[0] != "apply" {
+		t.Errorf("Expected after [apply], got %v", flow.Steps[1].After)
+	}
+	if len(flow.Steps[2].After) != 2 || flow.Steps[2].After[1] != "smoke" {
+		t.Errorf("Expected after [apply smoke], got %v", flow.Steps[2].After)
+	}
+}
+
+func TestParseFlowRejectsCycle(t *testing.T) {
+	yamlContent := `name: cycle-test
+working_dir: ./terraform
+steps:
+  - name: a
+    type: terraform
+    command: terraform plan
+    after: b
+  - name: b
+    type: terraform
+    command: terraform plan
+    after: a
+`
+
+	tmpFile, err := os.CreateTemp("", "test-cycle-*.yaml")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.WriteString(yamlContent); err != nil {
+		t.Fatalf("Failed to write temp file: %v", err)
+	}
+	tmpFile.Close()
+
+	if _, err := ParseFlow(tmpFile.Name()); err == nil {
+		t.Fatal("Expected dependency cycle to be rejected")
+	}
+}
+
+// writeFiles creates files under dir, creating parent directories as needed
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+}
+
+func TestParseFlowWithIncludes(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"common/lifecycle.yaml": `params:
+  plan_file: plan.tfplan
+steps:
+  - name: ${params.prefix}init
+    type: terraform
+    commands:
+      - terraform init
+      - terraform plan -out=${params.plan_file}
+    var_files:
+      - ./shared.tfvars
+  - name: ${params.prefix}apply
+    type: terraform
+    command: terraform apply -auto-approve ${params.plan_file}
+  - name: ${params.prefix}smoke
+    type: exec
+    command: ./smoke.sh
+    workdir: scripts
+`,
+		"common/templates.yaml": `templates:
+  destroy:
+    type: terraform
+    command: terraform destroy -auto-approve
+    when: always
+    var_files:
+      - ./destroy.tfvars
+  health:
+    type: http
+    params:
+      path: /health
+      retries: 5
+    url: "http://${output.alb_dns}${params.path}"
+    expected_status: 200
+    retries: ${params.retries}
+`,
+		"flows/flow.yaml": `name: included
+working_dir: ./terraform
+include:
+  - ../common/templates.yaml
+templates:
+  slow-health:
+    uses: health
+    delay: 30s
+steps:
+  - include: ../common/lifecycle.yaml
+    with:
+      prefix: vpc-
+  - name: health
+    uses: health
+    with:
+      path: /status
+  - name: slow-health
+    uses: slow-health
+    with:
+      retries: 10
+    timeout: 10m
+  - name: destroy
+    uses: destroy
+`,
+	})
+
+	flow, err := ParseFlow(filepath.Join(dir, "flows", "flow.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to parse flow: %v", err)
+	}
+
+	var names []string
+	for _, s := range flow.Steps {
+		names = append(names, s.Name)
+	}
+	if got := strings.Join(names, ","); got != "vpc-init,vpc-apply,vpc-smoke,health,slow-health,destroy" {
+		t.Fatalf("Unexpected steps: %s", got)
+	}
+
+	if got := flow.Steps[0].Commands[1]; got != "terraform plan -out=plan.tfplan" {
+		t.Errorf("Expected include param default to be applied, got %q", got)
+	}
+
+	// Paths in included steps are relative to the included file
+	if want := filepath.Join(dir, "common", "shared.tfvars"); len(flow.Steps[0].VarFiles) != 1 || flow.Steps[0].VarFiles[0] != want {
+		t.Errorf("Expected var file %s, got %v", want, flow.Steps[0].VarFiles)
+	}
+	if want := filepath.Join(dir, "common", "scripts"); flow.Steps[2].Workdir != want {
+		t.Errorf("Expected workdir %s, got %s", want, flow.Steps[2].Workdir)
+	}
+
+	health := flow.Steps[3]
+	if health.URL != "http://${output.alb_dns}/status" || health.Retries != 5 || health.ExpectedStatus != 200 {
+		t.Errorf("Unexpected health step: %+v", health)
+	}
+
+	slow := flow.Steps[4]
+	if slow.Retries != 10 || slow.Delay != "30s" || slow.Timeout != "10m" || slow.URL != "http://${output.alb_dns}/health" {
+		t.Errorf("Unexpected slow-health step: %+v", slow)
+	}
+
+	if flow.Steps[5].When != "always" || flow.Steps[5].Command != "terraform destroy -auto-approve" {
+		t.Errorf("Unexpected destroy step: %+v", flow.Steps[5])
+	}
+	// ...and so are paths in templates, to the file defining them
+	if want := filepath.Join(dir, "common", "destroy.tfvars"); len(flow.Steps[5].VarFiles) != 1 || flow.Steps[5].VarFiles[0] != want {
+		t.Errorf("Expected template var file %s, got %v", want, flow.Steps[5].VarFiles)
+	}
+}
+
+func TestParseFlowIncludeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantErr string
+	}{
+		{
+			name: "include cycle",
+			files: map[string]string{
+				"flow.yaml": "name: cycle\nworking_dir: .\nsteps:\n  - include: a.yaml\n",
+				"a.yaml":    "steps:\n  - include: b.yaml\n",
+				"b.yaml":    "steps:\n  - include: a.yaml\n",
+			},
+			wantErr: "include cycle detected: a.yaml -> b.yaml -> a.yaml",
+		},
+		{
+			name: "missing include",
+			files: map[string]string{
+				"flow.yaml": "name: missing\nworking_dir: .\nsteps:\n  - include: nope.yaml\n",
+			},
+			wantErr: "failed to read include",
+		},
+		{
+			name: "unknown template",
+			files: map[string]string{
+				"flow.yaml": "name: unknown\nworking_dir: .\nsteps:\n  - name: a\n    uses: nope\n",
+			},
+			wantErr: `unknown template "nope"`,
+		},
+		{
+			name: "missing parameter",
+			files: map[string]string{
+				"flow.yaml": "name: params\nworking_dir: .\ntemplates:\n  t:\n    type: http\n    url: http://${params.host}/\nsteps:\n  - name: a\n    uses: t\n",
+			},
+			wantErr: "missing value for parameter host",
+		},
+		{
+			name: "template cycle",
+			files: map[string]string{
+				"flow.yaml": "name: params\nworking_dir: .\ntemplates:\n  a:\n    uses: b\n  b:\n    uses: a\nsteps:\n  - name: x\n    uses: a\n",
+			},
+			wantErr: "template cycle detected: a -> b -> a",
+		},
+		{
+			name: "steps in top-level include",
+			files: map[string]string{
+				"flow.yaml":   "name: steps\nworking_dir: .\ninclude: common.yaml\nsteps:\n  - name: a\n    type: wait\n    duration: 1s\n",
+				"common.yaml": "steps:\n  - name: b\n    type: wait\n    duration: 1s\n",
+			},
+			wantErr: "common.yaml:2: a top-level include only loads templates; include files with steps from the step list (- include: common.yaml)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files)
+
+			_, err := ParseFlow(filepath.Join(dir, "flow.yaml"))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseFlow() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package flow
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/infratest/infratest/internal/flow/interpolator"
+	"github.com/infratest/infratest/internal/terraform"
+)
+
+// StepPlan describes what a step would do, without running it
+type StepPlan struct {
+	Name       string
+	Type       string
+	Stage      int      // steps in the same stage may run in parallel, starting at 1
+	After      []string // steps that must finish first
+	When       string
+	Cleanup    bool              // when: always steps run even after a failure
+	Commands   []string          // terraform command lines, interpolated
+	Variables  map[string]string // terraform variables, as written to the generated var file
+	URL        string            // http URL, interpolated
+	Address    string            // tcp/udp address or dns hostname, interpolated
+	Condition  string            // what a wait step polls for, interpolated
+	Patterns   []string          // inventory resource patterns
+	Outputs    []string          // output paths a terraform-output step checks
+	Unresolved []string          // ${...} placeholders that couldn't be resolved
+	Runtime    []string          // ${steps.*} captured values, only known at run time
+}
+
+// Plan previews a flow run: every step in execution order, with includes,
+// variables and interpolations resolved as far as the given terraform
+// outputs allow. Captured ${steps.*} values are only known at run time.
+func Plan(f *Flow, outputs map[string]interface{}) ([]StepPlan, error) {
+	if outputs == nil {
+		outputs = map[string]interface{}{}
+	}
+	e := &Executor{flow: f, outputs: outputs}
+
+	stages := executionStages(f.Steps)
+	deps := dependencies(f.Steps)
+	plans := make([]StepPlan, 0, len(f.Steps))
+	for _, step := range f.Steps {
+		p := StepPlan{
+			Name:    step.Name,
+			Type:    step.Type,
+			Stage:   stages[step.Name],
+			After:   deps[step.Name],
+			When:    step.When,
+			Cleanup: step.When == "always",
+		}
+
+		var resolved []string
+		switch step.Type {
+		case "terraform", "terraform-plan", "terraform-idempotency":
+			commands := step.Commands
+			if step.Command != "" {
+				commands = []string{step.Command}
+			}
+			switch step.Type {
+			case "terraform-plan":
+				commands = []string{planCommand(step)}
+				p.Patterns = inventoryPatterns(step)
+			case "terraform-idempotency":
+				commands = []string{planCommand(step) + " -detailed-exitcode"}
+			}
+			varFiles := e.stepVarFiles(step)
+			vars := e.stepVariables(step)
+			for _, cmd := range commands {
+				interpolated, err := e.interpolateCommand(cmd)
+				if err != nil {
+					return nil, fmt.Errorf("step %s: %w", step.Name, err)
+				}
+				line, err := terraform.CommandLine(interpolated, varFiles, vars)
+				if err != nil {
+					return nil, fmt.Errorf("step %s: %w", step.Name, err)
+				}
+				p.Commands = append(p.Commands, line)
+			}
+			resolved = append(resolved, p.Commands...)
+
+			p.Variables = make(map[string]string, len(vars))
+			for name, value := range vars {
+				formatted, err := terraform.VarFileValue(value)
+				if err != nil {
+					return nil, fmt.Errorf("step %s: variable %s: %w", step.Name, name, err)
+				}
+				p.Variables[name] = formatted
+			}
+			resolved = append(resolved, stringValues(vars)...)
+		case "terraform-inventory":
+			p.Patterns = inventoryPatterns(step)
+		case "terraform-output":
+			p.Outputs = sortedOutputPaths(step.Outputs)
+			for _, path := range p.Outputs {
+				resolved = append(resolved, e.outputAssertion(step.Outputs[path]).templates()...)
+			}
+		case "http":
+			p.URL = e.interpolate(step.URL)
+			resolved = append(resolved, p.URL)
+		case "exec":
+			cmd, err := e.execCommand(step, e.interpolate)
+			if err != nil {
+				return nil, fmt.Errorf("step %s: %w", step.Name, err)
+			}
+			p.Commands = []string{cmd.String()}
+			resolved = append(resolved, p.Commands...)
+			resolved = append(resolved, cmd.Env...)
+		case "tcp", "udp":
+			p.Address = e.interpolate(step.Address)
+			resolved = append(resolved, p.Address)
+		case "dns":
+			p.Address = e.interpolate(step.Hostname)
+			resolved = append(resolved, p.Address)
+		case "wait":
+			if step.Until != nil {
+				cond := e.waitCondition(step)
+				p.Condition = cond.String()
+				resolved = append(resolved, cond.templates()...)
+			}
+		}
+
+		seen := make(map[string]bool)
+		for _, s := range resolved {
+			for _, placeholder := range interpolator.Unresolved(s) {
+				if seen[placeholder] {
+					continue
+				}
+				seen[placeholder] = true
+				if stepRefRegex.MatchString(placeholder) {
+					p.Runtime = append(p.Runtime, placeholder)
+				} else {
+					p.Unresolved = append(p.Unresolved, placeholder)
+				}
+			}
+		}
+		plans = append(plans, p)
+	}
+	return plans, nil
+}
+
+// executionStages assigns every step the earliest stage it can run in:
+// one after the latest stage of its prerequisites
+func executionStages(steps []Step) map[string]int {
+	deps := dependencies(steps)
+	stages := make(map[string]int, len(steps))
+	var stage func(name string) int
+	stage = func(name string) int {
+		if s, ok := stages[name]; ok {
+			return s
+		}
+		s := 1
+		for _, dep := range deps[name] {
+			if d := stage(dep) + 1; d > s {
+				s = d
+			}
+		}
+		stages[name] = s
+		return s
+	}
+	for _, step := range steps {
+		stage(step.Name)
+	}
+	return stages
+}
+
+// inventoryPatterns returns the resource patterns an inventory step checks
+func inventoryPatterns(step Step) []string {
+	var patterns []string
+	for pattern := range step.ExpectedResources {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	if step.Expected != nil {
+		for _, expected := range step.Expected.Resources {
+			patterns = append(patterns, expected.Type)
+		}
+	}
+	return patterns
+}
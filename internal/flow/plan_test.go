@@ -0,0 +1,69 @@
+package flow
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPlan(t *testing.T) {
+	f := &Flow{
+		Name:       "plan",
+		WorkingDir: t.TempDir(),
+		Variables:  map[string]interface{}{"region": "us-east-1", "azs": []interface{}{"a", "b"}},
+		VarFiles:   []string{"/vars/common.tfvars"},
+		Steps: []Step{
+			{Name: "apply", Type: "terraform", Command: "apply -auto-approve"},
+			{Name: "login", Type: "http", URL: "http://${output.alb_dns}/login", ExpectedStatus: 200,
+				Capture: map[string]CaptureSpec{"token": {JSONPath: "$.token"}}},
+			{Name: "inventory", Type: "terraform-inventory", After: StringList{"apply"},
+				ExpectedResources: map[string]ResourceMatchConfig{"aws_vpc.main": {}, "aws_subnet.*": {}}},
+			{Name: "profile", Type: "http", URL: "http://${output.alb_dns}/me?t=${steps.login.token}", ExpectedStatus: 200},
+			{Name: "destroy", Type: "terraform", When: "always", Command: "destroy -auto-approve -var bucket=${output.bucket}"},
+		},
+	}
+	if err := validateFlow(f); err != nil {
+		t.Fatalf("validateFlow() error: %v", err)
+	}
+
+	plans, err := Plan(f, map[string]interface{}{"alb_dns": "alb.example.com"})
+	if err != nil {
+		t.Fatalf("Plan() error: %v", err)
+	}
+	byName := make(map[string]StepPlan)
+	for _, p := range plans {
+		byName[p.Name] = p
+	}
+
+	apply := byName["apply"]
+	if want := []string{"terraform apply -var-file=/vars/common.tfvars '-var-file=<generated>.tfvars.json' -auto-approve"}; !reflect.DeepEqual(apply.Commands, want) {
+		t.Errorf("apply commands = %q, want %q", apply.Commands, want)
+	}
+	if want := map[string]string{"region": `"us-east-1"`, "azs": `["a","b"]`}; !reflect.DeepEqual(apply.Variables, want) {
+		t.Errorf("apply variables = %v, want %v", apply.Variables, want)
+	}
+
+	if got := byName["login"].URL; got != "http://alb.example.com/login" {
+		t.Errorf("login URL = %s", got)
+	}
+	if got := byName["inventory"].Patterns; !reflect.DeepEqual(got, []string{"aws_subnet.*", "aws_vpc.main"}) {
+		t.Errorf("inventory patterns = %v", got)
+	}
+	if profile := byName["profile"]; len(profile.Unresolved) != 0 || !reflect.DeepEqual(profile.Runtime, []string{"${steps.login.token}"}) {
+		t.Errorf("profile plan = %+v, want ${steps.login.token} known at run time only", profile)
+	}
+
+	destroy := byName["destroy"]
+	if !destroy.Cleanup || !reflect.DeepEqual(destroy.Unresolved, []string{"${output.bucket}"}) {
+		t.Errorf("destroy plan = %+v", destroy)
+	}
+
+	// inventory only waits for apply, so it shares a stage with login
+	stages := map[string]int{}
+	for _, p := range plans {
+		stages[p.Name] = p.Stage
+	}
+	want := map[string]int{"apply": 1, "login": 2, "inventory": 2, "profile": 3, "destroy": 4}
+	if !reflect.DeepEqual(stages, want) {
+		t.Errorf("stages = %v, want %v", stages, want)
+	}
+}
@@ -0,0 +1,120 @@
+package flow
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"time"
+)
+
+// defaultRetryDelay is used when a retry policy doesn't set a delay
+const defaultRetryDelay = 5 * time.Second
+
+// validate checks the retry policy fields and compiles its retry_on regexes
+func (p *RetryPolicy) validate() error {
+	if p == nil {
+		return nil
+	}
+	if p.Attempts < 1 {
+		return fmt.Errorf("retry.attempts must be at least 1")
+	}
+	switch p.Backoff {
+	case "", "constant", "exponential":
+	default:
+		return fmt.Errorf("retry.backoff must be constant or exponential, got %q", p.Backoff)
+	}
+	for field, value := range map[string]string{"delay": p.Delay, "max_delay": p.MaxDelay, "jitter": p.Jitter} {
+		if value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(value); err != nil || d < 0 {
+			return fmt.Errorf("retry.%s must be a non-negative duration, got %q", field, value)
+		}
+	}
+	retryOn, err := compileRetryOn(p.RetryOn)
+	if err != nil {
+		return err
+	}
+	p.retryOn = retryOn
+	return nil
+}
+
+// compileRetryOn compiles retry_on regexes
+func compileRetryOn(patterns []string) ([]*regexp.Regexp, error) {
+	retryOn := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("retry.retry_on: invalid regex %q: %w", pattern, err)
+		}
+		retryOn = append(retryOn, re)
+	}
+	return retryOn, nil
+}
+
+// shouldRetry reports whether another attempt should follow a failed one
+func (p *RetryPolicy) shouldRetry(attempt int, err error) bool {
+	if p == nil || attempt >= p.Attempts {
+		return false
+	}
+	if len(p.RetryOn) == 0 {
+		return true
+	}
+	retryOn := p.retryOn
+	if len(retryOn) != len(p.RetryOn) {
+		// The policy wasn't validated, e.g. one built in code: compile it here
+		compiled, compileErr := compileRetryOn(p.RetryOn)
+		if compileErr != nil {
+			return false
+		}
+		retryOn = compiled
+	}
+	for _, re := range retryOn {
+		if re.MatchString(err.Error()) {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the delay to wait after the given failed attempt
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	delay := defaultRetryDelay
+	if d, err := time.ParseDuration(p.Delay); err == nil {
+		delay = d
+	}
+
+	if p.Backoff == "exponential" {
+		for i := 1; i < attempt; i++ {
+			delay *= 2
+			// Stop doubling before overflowing; max_delay caps it below anyway
+			if delay > 24*time.Hour {
+				break
+			}
+		}
+	}
+
+	if maxDelay, err := time.ParseDuration(p.MaxDelay); err == nil && maxDelay > 0 && delay > maxDelay {
+		delay = maxDelay
+	}
+
+	if jitter, err := time.ParseDuration(p.Jitter); err == nil && jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(jitter)))
+	}
+
+	return delay
+}
+
+// sleepContext waits for the given duration unless the context is done first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
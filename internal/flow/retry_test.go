@@ -0,0 +1,87 @@
+package flow
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  RetryPolicy
+		attempt int
+		want    time.Duration
+	}{
+		{"constant", RetryPolicy{Attempts: 5, Delay: "2s"}, 3, 2 * time.Second},
+		{"default delay", RetryPolicy{Attempts: 5}, 1, defaultRetryDelay},
+		{"exponential first", RetryPolicy{Attempts: 5, Backoff: "exponential", Delay: "1s"}, 1, time.Second},
+		{"exponential third", RetryPolicy{Attempts: 5, Backoff: "exponential", Delay: "1s"}, 3, 4 * time.Second},
+		{"exponential capped", RetryPolicy{Attempts: 10, Backoff: "exponential", Delay: "1s", MaxDelay: "10s"}, 8, 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.backoff(tt.attempt); got != tt.want {
+				t.Errorf("backoff(%d) = %s, want %s", tt.attempt, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyJitter(t *testing.T) {
+	p := RetryPolicy{Attempts: 3, Delay: "1s", Jitter: "500ms"}
+	for i := 0; i < 20; i++ {
+		got := p.backoff(1)
+		if got < time.Second || got >= 1500*time.Millisecond {
+			t.Fatalf("backoff with jitter = %s, want within [1s, 1.5s)", got)
+		}
+	}
+}
+
+func TestRetryPolicyShouldRetry(t *testing.T) {
+	var nilPolicy *RetryPolicy
+	if nilPolicy.shouldRetry(1, errors.New("boom")) {
+		t.Error("Expected no retries without a policy")
+	}
+
+	p := &RetryPolicy{Attempts: 3, RetryOn: []string{"(?i)throttl", "eventual consistency"}}
+	if err := p.validate(); err != nil {
+		t.Fatalf("validate() error: %v", err)
+	}
+	if !p.shouldRetry(1, errors.New("Error: Throttling: Rate exceeded")) {
+		t.Error("Expected matching error to be retried")
+	}
+	if p.shouldRetry(1, errors.New("invalid CIDR block")) {
+		t.Error("Expected non-matching error not to be retried")
+	}
+	if p.shouldRetry(3, errors.New("throttled")) {
+		t.Error("Expected no retry after the last attempt")
+	}
+
+	// A policy that skipped validation still honors retry_on
+	unvalidated := &RetryPolicy{Attempts: 3, RetryOn: []string{"(?i)throttl"}}
+	if !unvalidated.shouldRetry(1, errors.New("Throttling")) || unvalidated.shouldRetry(1, errors.New("invalid CIDR block")) {
+		t.Error("Expected unvalidated policy to match retry_on")
+	}
+}
+
+func TestRetryPolicyValidate(t *testing.T) {
+	invalid := []RetryPolicy{
+		{Attempts: 0},
+		{Attempts: 3, Backoff: "linear"},
+		{Attempts: 3, MaxDelay: "soon"},
+		{Attempts: 3, RetryOn: []string{"("}},
+	}
+	for _, p := range invalid {
+		p := p
+		if err := p.validate(); err == nil {
+			t.Errorf("validate(%+v) expected error", p)
+		}
+	}
+
+	valid := RetryPolicy{Attempts: 4, Backoff: "exponential", Delay: "1s", MaxDelay: "30s", Jitter: "2s", RetryOn: []string{"timeout"}}
+	if err := valid.validate(); err != nil {
+		t.Errorf("validate() unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,418 @@
+package flow
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ValidationError is a problem found in a flow file, with its YAML position
+type ValidationError struct {
+	File   string
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %v", e.File, e.Line, e.Column, e.Err)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationErrors lists every problem strict parsing found
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	lines := make([]string, len(e))
+	for i, err := range e {
+		lines[i] = err.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// sort orders errors by position, those in the flow file itself first
+func (e ValidationErrors) sort(path string) {
+	sort.SliceStable(e, func(i, j int) bool {
+		a, b := e[i], e[j]
+		if a.File != b.File {
+			if a.File == path || b.File == path {
+				return a.File == path
+			}
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+}
+
+// newValidationError positions an error at a YAML node (or the start of the file)
+func newValidationError(file string, node *yaml.Node, err error) *ValidationError {
+	line, column := 1, 1
+	if node != nil && node.Line > 0 {
+		line, column = node.Line, node.Column
+	}
+	return &ValidationError{File: file, Line: line, Column: column, Err: err}
+}
+
+var (
+	unmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+
+	// decodeLineRegex matches the position prefix of yaml.v3 decode errors
+	decodeLineRegex = regexp.MustCompile(`^line \d+: `)
+)
+
+// checkFields reports mapping keys that don't match a field of the flow
+// schema and values that can't be decoded into their field's type
+func checkFields(root *yaml.Node, path string, sources map[*yaml.Node]string) ValidationErrors {
+	var errs ValidationErrors
+	var walk func(node *yaml.Node, t reflect.Type, file string)
+	walk = func(node *yaml.Node, t reflect.Type, file string) {
+		if src, ok := sources[node]; ok {
+			file = src
+		}
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+
+		// Leaf values, types that decode themselves and mismatched kinds are
+		// checked by decoding them
+		leaf := reflect.PtrTo(t).Implements(unmarshalerType) ||
+			(t.Kind() != reflect.Struct && t.Kind() != reflect.Slice && t.Kind() != reflect.Map) ||
+			(t.Kind() == reflect.Slice && node.Kind != yaml.SequenceNode) ||
+			(t.Kind() != reflect.Slice && node.Kind != yaml.MappingNode)
+		if leaf {
+			if t.Kind() == reflect.Interface || isNull(node) {
+				return
+			}
+			if err := node.Decode(reflect.New(t).Interface()); err != nil {
+				errs = append(errs, newValidationError(file, node, decodeError(err)))
+			}
+			return
+		}
+
+		switch t.Kind() {
+		case reflect.Slice:
+			for _, item := range node.Content {
+				walk(item, t.Elem(), file)
+			}
+		case reflect.Map:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				walk(node.Content[i+1], t.Elem(), file)
+			}
+		case reflect.Struct:
+			fields := yamlFields(t)
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				key, value := node.Content[i], node.Content[i+1]
+				field, ok := fields[key.Value]
+				if !ok {
+					errs = append(errs, newValidationError(file, key, fmt.Errorf("unknown field %q%s", key.Value, suggestField(key.Value, fields))))
+					continue
+				}
+				walk(value, field, file)
+			}
+		}
+	}
+	walk(root, reflect.TypeOf(Flow{}), path)
+	return errs
+}
+
+// isNull reports whether a node is an empty or explicit null value
+func isNull(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.Tag == "!!null"
+}
+
+// decodeError strips the line prefix from yaml.v3 decode errors, since
+// validation errors carry their own position
+func decodeError(err error) error {
+	if te, ok := err.(*yaml.TypeError); ok {
+		msgs := make([]string, len(te.Errors))
+		for i, msg := range te.Errors {
+			msgs[i] = decodeLineRegex.ReplaceAllString(msg, "")
+		}
+		return fmt.Errorf("%s", strings.Join(msgs, "; "))
+	}
+	return err
+}
+
+// yamlFields returns the YAML keys of a struct and their field types
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// suggestField returns a "did you mean" hint for a misspelled key
+func suggestField(key string, fields map[string]reflect.Type) string {
+	best, bestDist := "", 3
+	for name := range fields {
+		if d := editDistance(key, name); d < bestDist || (d == bestDist && best != "" && name < best) {
+			best, bestDist = name, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (did you mean %q?)", best)
+}
+
+// editDistance returns the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// validateStrict checks a decoded flow against the schema of each step type
+// and positions every problem at the YAML node it comes from
+func validateStrict(f *Flow, root *yaml.Node, path string, sources map[*yaml.Node]string) ValidationErrors {
+	var errs ValidationErrors
+	flowErr := func(key string, err error) {
+		node := mappingValue(root, key)
+		if node == nil {
+			node = root
+		}
+		errs = append(errs, newValidationError(path, node, err))
+	}
+
+	if f.Name == "" {
+		flowErr("name", fmt.Errorf("flow name is required"))
+	}
+	if mappingValue(root, "working_dir") == nil {
+		flowErr("working_dir", fmt.Errorf("working_dir is required"))
+	}
+	if len(f.Steps) == 0 {
+		flowErr("steps", fmt.Errorf("at least one step is required"))
+	}
+	if f.MaxParallel < 0 {
+		flowErr("max_parallel", fmt.Errorf("max_parallel must not be negative"))
+	}
+	if _, err := parseTimeout(f.Timeout); err != nil {
+		flowErr("timeout", fmt.Errorf("invalid flow timeout: %w", err))
+	}
+	if err := validateIsolation(f); err != nil {
+		flowErr("isolation", err)
+	}
+	if err := f.Matrix.validate(); err != nil {
+		flowErr("matrix", fmt.Errorf("invalid matrix: %w", err))
+	} else if err := validateMatrixRefs(f); err != nil {
+		flowErr("matrix", err)
+	}
+	if err := validateVariables(f.Variables); err != nil {
+		flowErr("variables", err)
+	}
+
+	var stepNodes []*yaml.Node
+	if steps := mappingValue(root, "steps"); steps != nil {
+		stepNodes = steps.Content
+	}
+	byName := make(map[string]Step, len(f.Steps))
+	for _, step := range f.Steps {
+		if _, exists := byName[step.Name]; !exists {
+			byName[step.Name] = step
+		}
+	}
+
+	defined := make(map[string]*yaml.Node)
+	dependencyErrors := false
+	for i, step := range f.Steps {
+		node := root
+		if i < len(stepNodes) {
+			node = stepNodes[i]
+		}
+		file := path
+		if src, ok := sources[node]; ok {
+			file = src
+		}
+		label := step.Name
+		if label == "" {
+			label = fmt.Sprintf("#%d", i+1)
+		}
+		// report positions an error at one of the step's keys (or the step itself)
+		report := func(key string, err error) {
+			at := mappingValue(node, key)
+			if at == nil {
+				at = node
+			}
+			errs = append(errs, newValidationError(file, at, err))
+		}
+		stepErr := func(key string, err error) {
+			report(key, fmt.Errorf("step %s: %w", label, err))
+		}
+
+		if step.Name == "" {
+			stepErr("name", fmt.Errorf("name is required"))
+		} else if first, exists := defined[step.Name]; exists {
+			stepErr("name", fmt.Errorf("duplicate step name (first defined at line %d)", first.Line))
+		}
+		if key, err := validateStepType(step); err != nil {
+			stepErr(key, err)
+		}
+
+		for _, dep := range step.After {
+			switch {
+			case dep == step.Name:
+				stepErr("after", fmt.Errorf("depends on itself"))
+			case defined[dep] != nil:
+			case byName[dep].Name != "":
+				stepErr("after", fmt.Errorf("after references step %s, which is defined later", dep))
+			default:
+				stepErr("after", fmt.Errorf("after references unknown step %s", dep))
+			}
+			dependencyErrors = dependencyErrors || defined[dep] == nil
+		}
+		if step.Name != "" && defined[step.Name] == nil {
+			defined[step.Name] = mappingValue(node, "name")
+		}
+
+		if _, err := parseTimeout(step.Timeout); err != nil {
+			stepErr("timeout", fmt.Errorf("invalid timeout: %w", err))
+		}
+		if step.Delay != "" {
+			if _, err := time.ParseDuration(step.Delay); err != nil {
+				stepErr("delay", fmt.Errorf("invalid delay: %w", err))
+			}
+		}
+		if err := step.Retry.validate(); err != nil {
+			stepErr("retry", err)
+		}
+		if err := validateExec(step); err != nil {
+			stepErr("", err)
+		}
+		if err := validateWait(step); err != nil {
+			stepErr("until", err)
+		}
+		if err := validateNetwork(step); err != nil {
+			stepErr("address", err)
+		}
+		if err := validateDNS(step); err != nil {
+			stepErr("record_type", err)
+		}
+		if err := validatePlanChanges(step); err != nil {
+			stepErr("changes", err)
+		}
+		if err := validateIdempotency(step); err != nil {
+			stepErr("ignore_attributes", err)
+		}
+		if err := validateOutputs(step); err != nil {
+			stepErr("outputs", err)
+		}
+		if err := validateExpectedResources(step); err != nil {
+			stepErr("expected_resources", err)
+		}
+		if err := validateVariables(step.Variables); err != nil {
+			stepErr("variables", err)
+		}
+		if len(step.Variables) > 0 && !runsTerraform(step) {
+			stepErr("variables", fmt.Errorf("variables are only supported on terraform steps"))
+		}
+		if len(step.VarFiles) > 0 && !runsTerraform(step) {
+			stepErr("var_files", fmt.Errorf("var_files are only supported on terraform steps"))
+		}
+		if err := validateCondition(step, byName); err != nil {
+			report("when", err)
+		}
+		if err := validateCaptures(step); err != nil {
+			report("capture", err)
+		}
+		if err := validateTemplateRefs(step, byName); err != nil {
+			report("", err)
+		}
+	}
+
+	// Cycles can only come from condition and template references once
+	// every `after` points backwards
+	if !dependencyErrors {
+		if cycle := findCycle(f.Steps, dependencies(f.Steps)); len(cycle) > 0 {
+			flowErr("steps", fmt.Errorf("dependency cycle detected: %s", strings.Join(cycle, " -> ")))
+		}
+	}
+	return errs
+}
+
+// validateStepType checks that a step has the fields its type requires. It
+// returns the key the problem should be reported at.
+func validateStepType(step Step) (string, error) {
+	switch step.Type {
+	case "terraform":
+		if step.Command == "" && len(step.Commands) == 0 {
+			return "type", fmt.Errorf("terraform steps require command or commands")
+		}
+		if step.Command != "" && len(step.Commands) > 0 {
+			return "commands", fmt.Errorf("use either command or commands, not both")
+		}
+	case "terraform-plan", "terraform-idempotency":
+	case "terraform-output":
+		if len(step.Outputs) == 0 {
+			return "type", fmt.Errorf("terraform-output steps require outputs")
+		}
+	case "terraform-inventory":
+		if step.Expected == nil && len(step.ExpectedResources) == 0 {
+			return "type", fmt.Errorf("terraform-inventory steps require expected or expected_resources")
+		}
+	case "http":
+		if step.URL == "" {
+			return "type", fmt.Errorf("http steps require url")
+		}
+		if step.ExpectedStatus != 0 && (step.ExpectedStatus < 100 || step.ExpectedStatus > 599) {
+			return "expected_status", fmt.Errorf("invalid expected_status %d", step.ExpectedStatus)
+		}
+	case "exec":
+		if step.Command == "" {
+			return "type", fmt.Errorf("exec steps require command")
+		}
+	case "wait":
+		if step.Until == nil {
+			return "type", fmt.Errorf("wait steps require until")
+		}
+	case "tcp", "udp":
+		if step.Address == "" {
+			return "type", fmt.Errorf("%s steps require address", step.Type)
+		}
+	case "dns":
+		if step.Hostname == "" {
+			return "type", fmt.Errorf("dns steps require hostname")
+		}
+	case "":
+		return "type", fmt.Errorf("type is required")
+	default:
+		return "type", fmt.Errorf("unknown step type %q (expected terraform, terraform-plan, terraform-idempotency, terraform-output, terraform-inventory, http, exec, wait, tcp, udp or dns)", step.Type)
+	}
+	return "", nil
+}
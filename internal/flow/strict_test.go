@@ -0,0 +1,137 @@
+package flow
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseFlowStrict(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  []string // errors as line:column: message
+	}{
+		{
+			name: "valid",
+			files: map[string]string{"flow.yaml": `name: ok
+working_dir: .
+steps:
+  - name: apply
+    type: terraform
+    command: apply -auto-approve
+  - name: smoke
+    type: http
+    url: http://example.com
+    after: apply
+    timeout: 1m
+`},
+		},
+		{
+			name: "misspelled field",
+			files: map[string]string{"flow.yaml": `name: typo
+working_dir: .
+steps:
+  - name: smoke
+    type: http
+    url: http://example.com
+    expected_stauts: 200
+`},
+			want: []string{`7:5: unknown field "expected_stauts" (did you mean "expected_status"?)`},
+		},
+		{
+			name: "wrong value type",
+			files: map[string]string{"flow.yaml": `name: types
+working_dir: .
+max_parallel: many
+steps:
+  - name: smoke
+    type: http
+    url: http://example.com
+`},
+			want: []string{"3:15: cannot unmarshal !!str `many` into int"},
+		},
+		{
+			name: "step types and references",
+			files: map[string]string{"flow.yaml": `name: steps
+working_dir: .
+steps:
+  - name: apply
+    type: terraform
+  - name: smoke
+    type: http
+    url: http://example.com
+    after: [verify]
+    delay: soon
+  - name: verify
+    type: terraform-inventory
+    expected_resources:
+      aws_vpc.main: {count: 1}
+  - name: smoke
+    type: htp
+`},
+			want: []string{
+				"5:11: step apply: terraform steps require command or commands",
+				"9:12: step smoke: after references step verify, which is defined later",
+				`10:12: step smoke: invalid delay: time: invalid duration "soon"`,
+				"15:11: step smoke: duplicate step name (first defined at line 6)",
+				`16:11: step smoke: unknown step type "htp" (expected terraform, terraform-plan, terraform-idempotency, terraform-output, terraform-inventory, http, exec, wait, tcp, udp or dns)`,
+			},
+		},
+		{
+			name: "included steps report their own file",
+			files: map[string]string{
+				"flow.yaml": "name: inc\nworking_dir: .\nsteps:\n  - include: common.yaml\n",
+				"common.yaml": `steps:
+  - name: smoke
+    type: http
+    uri: http://example.com
+`,
+			},
+			want: []string{
+				"common.yaml:3:11: step smoke: http steps require url",
+				`common.yaml:4:5: unknown field "uri" (did you mean "url"?)`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files)
+			path := filepath.Join(dir, "flow.yaml")
+
+			_, err := ParseFlowStrict(path)
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("ParseFlowStrict() error: %v", err)
+				}
+				return
+			}
+
+			var errs ValidationErrors
+			if !errors.As(err, &errs) {
+				t.Fatalf("ParseFlowStrict() error = %v, want ValidationErrors", err)
+			}
+			var got []string
+			for _, e := range errs {
+				msg := strings.TrimPrefix(e.Error(), dir+string(filepath.Separator))
+				got = append(got, strings.TrimPrefix(msg, "flow.yaml:"))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseFlowStrict() errors:\n%q\nwant:\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFlowLenient(t *testing.T) {
+	// Without strict mode, unknown fields keep being ignored
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"flow.yaml": "name: lenient\nworking_dir: .\nsteps:\n  - name: smoke\n    type: http\n    url: http://example.com\n    expected_stauts: 200\n"})
+	if _, err := ParseFlow(filepath.Join(dir, "flow.yaml")); err != nil {
+		t.Errorf("ParseFlow() error: %v", err)
+	}
+}
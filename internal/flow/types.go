@@ -1,6 +1,12 @@
 package flow
 
-import "time"
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
 
 // Flow represents the complete test flow configuration
 type Flow struct {
@@ -9,7 +15,23 @@ type Flow struct {
 	WorkingDir  string      `yaml:"working_dir"`
 	Environment Environment `yaml:"environment"`
 	Steps       []Step      `yaml:"steps"`
+	MaxParallel int         `yaml:"max_parallel,omitempty"` // Maximum number of steps running at once (default: 1)
+	Timeout     string      `yaml:"timeout,omitempty"`      // Deadline for the whole flow, e.g. "45m"
+	Matrix      *Matrix     `yaml:"matrix,omitempty"`       // Run the flow once per combination of values
+	Isolation   string      `yaml:"isolation,omitempty"`    // none (default) or workspace: run in a dedicated terraform workspace
+	Variables   map[string]interface{} `yaml:"variables,omitempty"` // Terraform input variables for every step
+	VarFiles    []string    `yaml:"var_files,omitempty"`    // Terraform variable files, relative to the flow file
 	Reporting   Reporting   `yaml:"reporting"`
+
+	// Path is the absolute path of the flow file (set by ParseFlow)
+	Path string `yaml:"-"`
+
+	// MatrixValues holds the variables of a single matrix run (see ExpandMatrix)
+	MatrixValues map[string]interface{} `yaml:"-"`
+	matrixOrder  []string
+
+	// VarOverrides holds variables set with --var; they take precedence over all others
+	VarOverrides map[string]interface{} `yaml:"-"`
 }
 
 // Environment configuration
@@ -22,24 +44,153 @@ type Environment struct {
 type Step struct {
 	Name    string            `yaml:"name"`
 	Type    string            `yaml:"type"`
-	After   string            `yaml:"after,omitempty"`
-	When    string            `yaml:"when,omitempty"` // always, on-success, on-failure
+	After   StringList        `yaml:"after,omitempty"` // step name or list of step names
+	When    string            `yaml:"when,omitempty"` // always, on-success, on-failure or a boolean expression
 	Command string            `yaml:"command,omitempty"`
 	Commands []string         `yaml:"commands,omitempty"`
+	Timeout string            `yaml:"timeout,omitempty"` // Deadline for this step, e.g. "10m"
+	Retry   *RetryPolicy      `yaml:"retry,omitempty"`   // Retry policy for any step type
+	Capture map[string]CaptureSpec `yaml:"capture,omitempty"` // Values to capture into ${steps.<name>.<var>}
+	Variables map[string]interface{} `yaml:"variables,omitempty"` // Terraform input variables for this step
+	VarFiles  []string               `yaml:"var_files,omitempty"` // Terraform variable files for this step
 	
 	// Terraform inventory step fields (legacy format)
 	Expected       *ExpectedResources `yaml:"expected,omitempty"`
 	FailOnExtra    bool               `yaml:"fail_on_extra,omitempty"`
 	FailOnMissing  bool               `yaml:"fail_on_missing,omitempty"`
 	
-	// Advanced inventory format (new)
+	// Advanced inventory format (new); on terraform-plan steps, matched against planned values
 	ExpectedResources map[string]ResourceMatchConfig `yaml:"expected_resources,omitempty"`
+
+	// Terraform plan step fields (command defaults to terraform plan)
+	Changes *PlanChanges `yaml:"changes,omitempty"`
+
+	// Terraform idempotency step fields (command defaults to terraform plan)
+	IgnoreAttributes map[string]StringList `yaml:"ignore_attributes,omitempty"` // resource address pattern -> attributes allowed to drift, "*" for all
+
+	// Terraform output step fields
+	Outputs map[string]OutputAssertion `yaml:"outputs,omitempty"` // output path, e.g. subnets[0].cidr -> assertions
 	
 	// HTTP step fields
 	URL            string        `yaml:"url,omitempty"`
 	ExpectedStatus int          `yaml:"expected_status,omitempty"`
 	Retries        int          `yaml:"retries,omitempty"`
 	Delay          string       `yaml:"delay,omitempty"`
+
+	// Exec step fields (the program and its arguments are in command)
+	Args    []string          `yaml:"args,omitempty"`    // extra arguments, passed as-is after interpolation
+	Env     map[string]string `yaml:"env,omitempty"`     // extra environment variables
+	Workdir string            `yaml:"workdir,omitempty"` // relative to the flow file (default: working_dir)
+	Expect  *ExecExpect       `yaml:"expect,omitempty"`
+
+	// Wait step fields (the deadline is the step timeout, 5m by default)
+	Until    *WaitCondition `yaml:"until,omitempty"`
+	Interval string         `yaml:"interval,omitempty"` // time between checks (default: 5s)
+
+	// TCP and UDP step fields (retries and delay work like on http steps)
+	Address   string `yaml:"address,omitempty"`   // host:port to connect to
+	Reachable *bool  `yaml:"reachable,omitempty"` // default: true; false asserts the port is closed
+	Send      string `yaml:"send,omitempty"`      // payload written after connecting
+	Banner    string `yaml:"banner,omitempty"`    // substring the server must send after connecting (or reply, for udp)
+
+	// DNS step fields (retries and delay work like on http steps)
+	Hostname        string     `yaml:"hostname,omitempty"`          // name to resolve
+	RecordType      string     `yaml:"record_type,omitempty"`       // A (default), AAAA, CNAME or TXT
+	Resolver        string     `yaml:"resolver,omitempty"`          // host:port of the DNS server (default: system resolver)
+	Records         StringList `yaml:"records,omitempty"`           // values that must all be resolved
+	RecordsInOutput string     `yaml:"records_in_output,omitempty"` // output list every resolved value must be in, e.g. instance_ips
+}
+
+// StringList is a list of strings that also accepts a single scalar in YAML,
+// so both `after: apply` and `after: [apply, smoke]` decode the same way
+type StringList []string
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (l *StringList) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		if value.Value == "" {
+			*l = nil
+			return nil
+		}
+		*l = StringList{value.Value}
+		return nil
+	case yaml.SequenceNode:
+		var items []string
+		if err := value.Decode(&items); err != nil {
+			return err
+		}
+		*l = items
+		return nil
+	default:
+		return fmt.Errorf("line %d: expected a string or a list of strings", value.Line)
+	}
+}
+
+// RetryPolicy configures how a failed step is retried
+type RetryPolicy struct {
+	Attempts int      `yaml:"attempts"`            // Total attempts, including the first
+	Backoff  string   `yaml:"backoff,omitempty"`   // constant (default) or exponential
+	Delay    string   `yaml:"delay,omitempty"`     // Delay before the first retry (default: 5s)
+	MaxDelay string   `yaml:"max_delay,omitempty"` // Upper bound for the delay between attempts
+	Jitter   string   `yaml:"jitter,omitempty"`    // Random extra delay of up to this duration
+	RetryOn  []string `yaml:"retry_on,omitempty"`  // Only retry errors matching one of these regexes
+
+	retryOn []*regexp.Regexp // RetryOn, compiled by validate (shouldRetry compiles it if validate didn't run)
+}
+
+// CaptureSpec describes how to extract a value from a step result.
+// Exactly one source is used: jsonpath, regex or resource/attribute.
+type CaptureSpec struct {
+	JSONPath  string `yaml:"jsonpath,omitempty"`  // JSONPath over the HTTP response body (or JSON step output)
+	Regex     string `yaml:"regex,omitempty"`     // Regex over the step output; the first group is captured if present
+	Resource  string `yaml:"resource,omitempty"`  // Inventory resource pattern, e.g. aws_subnet.public or aws_subnet.*
+	Attribute string `yaml:"attribute,omitempty"` // Attribute path on the matched inventory resources, e.g. tags.Name
+	Sensitive bool   `yaml:"sensitive,omitempty"` // Mask the value in logs and reports
+}
+
+// ExecExpect lists the assertions on the result of an exec step
+type ExecExpect struct {
+	ExitCode       *int                   `yaml:"exit_code,omitempty"`       // default: 0
+	StdoutContains StringList             `yaml:"stdout_contains,omitempty"` // substrings stdout must contain
+	StderrContains StringList             `yaml:"stderr_contains,omitempty"`
+	StdoutMatches  StringList             `yaml:"stdout_matches,omitempty"` // regexes stdout must match
+	StderrMatches  StringList             `yaml:"stderr_matches,omitempty"`
+	JSON           map[string]interface{} `yaml:"json,omitempty"` // JSONPath over stdout -> expected value
+}
+
+// PlanChanges lists the assertions on the resource changes of a terraform-plan step
+type PlanChanges struct {
+	Creates   *int                  `yaml:"creates,omitempty"`    // resources to create, replacements included
+	Updates   *int                  `yaml:"updates,omitempty"`    // resources to update in place
+	Deletes   *int                  `yaml:"deletes,omitempty"`    // resources to destroy, replacements included
+	Replaces  *int                  `yaml:"replaces,omitempty"`   // resources to destroy and create again
+	NoDestroy bool                  `yaml:"no_destroy,omitempty"` // fail if any resource would be destroyed or replaced
+	Actions   map[string]StringList `yaml:"actions,omitempty"`    // resource address -> expected action(s)
+}
+
+// OutputAssertion lists the assertions on a terraform output value. Nested
+// values are addressed like in interpolation, e.g. subnets[0].cidr.
+type OutputAssertion struct {
+	Exists    *bool       `yaml:"exists,omitempty"`    // default: true; false asserts the output (or path) is missing
+	Type      string      `yaml:"type,omitempty"`      // string, number, bool, list or map
+	Equals    interface{} `yaml:"equals,omitempty"`    // exact value
+	Matches   string      `yaml:"matches,omitempty"`   // regex a string, number or bool value must match
+	Length    *int        `yaml:"length,omitempty"`    // number of list elements or map keys
+	Sensitive *bool       `yaml:"sensitive,omitempty"` // the sensitive flag of the output
+}
+
+// WaitCondition is what a wait step polls for. Exactly one source is used:
+// output, resource (optionally with attribute), url or tcp.
+type WaitCondition struct {
+	Output    string      `yaml:"output,omitempty"`    // terraform output path, e.g. endpoints[0]
+	Resource  string      `yaml:"resource,omitempty"`  // resource address in state, e.g. aws_instance.web
+	Attribute string      `yaml:"attribute,omitempty"` // attribute path on the resource, e.g. instance_state
+	URL       string      `yaml:"url,omitempty"`       // HTTP endpoint, polled with GET
+	Status    int         `yaml:"status,omitempty"`    // expected HTTP status (default: 200)
+	TCP       string      `yaml:"tcp,omitempty"`       // host:port that must accept connections
+	Equals    interface{} `yaml:"equals,omitempty"`    // expected output or attribute value
+	Matches   string      `yaml:"matches,omitempty"`   // regex the value or response body must match
 }
 
 // ExpectedResources defines what resources should exist
@@ -60,6 +211,7 @@ type ResourceMatchConfig struct {
 	MinCount   *int                  `yaml:"min_count,omitempty"`
 	MaxCount   *int                  `yaml:"max_count,omitempty"`
 	Attributes map[string]interface{} `yaml:"attributes,omitempty"`
+	References map[string]interface{} `yaml:"references,omitempty"` // attribute path -> other resource's attribute, e.g. vpc_id: aws_vpc.main.id
 }
 
 // Reporting configuration
@@ -78,11 +230,34 @@ type StepResult struct {
 	Duration   time.Duration
 	Resources  []Resource
 	HTTPStatus int
+	Latency    time.Duration // tcp/udp connect latency
+	Records    []string      // records resolved by a dns step
+	Attempts   []AttemptResult
+	TimedOut   bool   // step was stopped because its deadline expired
+	Skipped    bool   // step was not run (condition not met or a prerequisite failed)
+	SkipReason string // why the step was skipped
+	Body       []byte                 // HTTP response body, exec stdout, tcp/udp banner or plan JSON, used for captures
+	Captured   map[string]interface{} // values captured by the step's capture block
+
+	restored bool // loaded from a checkpoint rather than run by this process
+}
+
+// AttemptResult records a single try of a step
+type AttemptResult struct {
+	Number   int
+	Success  bool
+	Error    error
+	Duration time.Duration
 }
 
 // Resource represents a Terraform resource
 type Resource struct {
-	Type string
-	ID   string
+	Type       string
+	ID         string
+	Name       string
+	Address    string
+	Module     string // module path, e.g. module.network; empty for the root module
+	Mode       string // "managed" or "data"
+	Attributes map[string]interface{}
 }
 
@@ -0,0 +1,158 @@
+package flow
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/infratest/infratest/internal/terraform"
+)
+
+// varNameRegex matches valid terraform input variable names
+var varNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// validateVariables checks the names of terraform variables set on a flow or step
+func validateVariables(vars map[string]interface{}) error {
+	for name := range vars {
+		if !varNameRegex.MatchString(name) {
+			return fmt.Errorf("invalid variable name %q", name)
+		}
+	}
+	return nil
+}
+
+// ParseVarOverride parses a --var flag of the form key=value. Values that are
+// JSON lists or objects, e.g. azs=["a","b"], become lists and maps; anything
+// else is a string, which terraform converts to the variable's type.
+func ParseVarOverride(s string) (string, interface{}, error) {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok || !varNameRegex.MatchString(name) {
+		return "", nil, fmt.Errorf("invalid --var %q (expected name=value)", s)
+	}
+	if trimmed := strings.TrimSpace(value); strings.HasPrefix(trimmed, "[") || strings.HasPrefix(trimmed, "{") {
+		var literal interface{}
+		if err := json.Unmarshal([]byte(trimmed), &literal); err == nil {
+			return name, literal, nil
+		}
+	}
+	return name, value, nil
+}
+
+// resolveVarFiles makes var file paths relative to the flow file's directory
+func resolveVarFiles(files []string, dir string) {
+	for i, f := range files {
+		if !filepath.IsAbs(f) {
+			files[i] = filepath.Clean(filepath.Join(dir, f))
+		}
+	}
+}
+
+// runsTerraform reports whether a step runs terraform commands, which take
+// variables and var files
+func runsTerraform(step Step) bool {
+	return step.Type == "terraform" || step.Type == "terraform-plan" || step.Type == "terraform-idempotency"
+}
+
+// stepExecutor returns the terraform executor for a step, with the step's
+// variables and var files
+func (e *Executor) stepExecutor(step Step) (*terraform.Executor, error) {
+	executor, err := e.executor.With(e.stepVariables(step), e.stepVarFiles(step))
+	if err != nil {
+		return nil, fmt.Errorf("step %s: %w", step.Name, err)
+	}
+	return executor, nil
+}
+
+// stepVariables returns the terraform variables of a step. Variables are
+// layered in increasing order of precedence: flow variables, matrix values,
+// step variables and --var overrides. String values are interpolated.
+func (e *Executor) stepVariables(step Step) map[string]interface{} {
+	vars := make(map[string]interface{})
+	for _, layer := range []map[string]interface{}{e.flow.Variables, e.flow.MatrixValues, step.Variables} {
+		for name, value := range layer {
+			vars[name] = e.interpolateValue(value)
+		}
+	}
+	for name, value := range e.flow.VarOverrides {
+		vars[name] = value
+	}
+	return vars
+}
+
+// flowVariables returns the flow's variables with --var overrides applied, as
+// seen by `when` expressions. String values are interpolated.
+func (e *Executor) flowVariables() map[string]interface{} {
+	vars := make(map[string]interface{}, len(e.flow.Variables)+len(e.flow.VarOverrides))
+	for name, value := range e.flow.Variables {
+		vars[name] = e.interpolateValue(value)
+	}
+	for name, value := range e.flow.VarOverrides {
+		vars[name] = value
+	}
+	return vars
+}
+
+// stepVarFiles returns the flow's var files followed by the step's
+func (e *Executor) stepVarFiles(step Step) []string {
+	return append(append([]string(nil), e.flow.VarFiles...), step.VarFiles...)
+}
+
+// interpolateValue interpolates the strings inside a variable value
+func (e *Executor) interpolateValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return e.interpolate(v)
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = e.interpolateValue(item)
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			out[k] = e.interpolateValue(item)
+		}
+		return out
+	default:
+		return value
+	}
+}
+
+// stringValues returns the strings inside variable values, in a stable order
+func stringValues(vars map[string]interface{}) []string {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var out []string
+	var walk func(interface{})
+	walk = func(value interface{}) {
+		switch v := value.(type) {
+		case string:
+			out = append(out, v)
+		case []interface{}:
+			for _, item := range v {
+				walk(item)
+			}
+		case map[string]interface{}:
+			keys := make([]string, 0, len(v))
+			for k := range v {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				walk(v[k])
+			}
+		}
+	}
+	for _, name := range names {
+		walk(vars[name])
+	}
+	return out
+}
@@ -0,0 +1,173 @@
+package flow
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestParseFlowWithVariables(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"flow.yaml": `name: vars
+working_dir: ./terraform
+variables:
+  environment: test
+  az_count: 2
+  azs: [us-east-1a, us-east-1b]
+  tags:
+    owner: platform
+var_files:
+  - ./test.tfvars
+steps:
+  - name: probe
+    type: http
+    url: http://localhost/
+    expected_status: 200
+    capture:
+      id:
+        jsonpath: $.id
+  - name: apply
+    type: terraform
+    command: terraform apply -auto-approve
+    variables:
+      probe_id: ${steps.probe.id}
+    var_files:
+      - /abs/extra.tfvars
+`,
+	})
+
+	f, err := ParseFlow(filepath.Join(dir, "flow.yaml"))
+	if err != nil {
+		t.Fatalf("ParseFlow() error: %v", err)
+	}
+
+	want := map[string]interface{}{
+		"environment": "test",
+		"az_count":    2,
+		"azs":         []interface{}{"us-east-1a", "us-east-1b"},
+		"tags":        map[string]interface{}{"owner": "platform"},
+	}
+	if !reflect.DeepEqual(f.Variables, want) {
+		t.Errorf("Variables = %#v, want %#v", f.Variables, want)
+	}
+	if want := filepath.Join(dir, "test.tfvars"); len(f.VarFiles) != 1 || f.VarFiles[0] != want {
+		t.Errorf("VarFiles = %v, want [%s]", f.VarFiles, want)
+	}
+	if got := f.Steps[1].VarFiles; len(got) != 1 || got[0] != "/abs/extra.tfvars" {
+		t.Errorf("Step VarFiles = %v, want [/abs/extra.tfvars]", got)
+	}
+	if deps := dependencies(f.Steps)["apply"]; !reflect.DeepEqual(deps, []string{"probe"}) {
+		t.Errorf("Expected apply to depend on probe through its variables, got %v", deps)
+	}
+}
+
+func TestParseFlowVariableErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		flow    string
+		wantErr string
+	}{
+		{
+			name:    "invalid flow variable name",
+			flow:    "variables:\n  \"bad name\": x\nsteps:\n  - name: apply\n    type: terraform\n    command: apply\n",
+			wantErr: `invalid variable name "bad name"`,
+		},
+		{
+			name:    "variables on http step",
+			flow:    "steps:\n  - name: check\n    type: http\n    url: http://localhost/\n    expected_status: 200\n    variables:\n      x: 1\n",
+			wantErr: "only supported on terraform steps",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, map[string]string{"flow.yaml": "name: vars\nworking_dir: ./terraform\n" + tt.flow})
+
+			_, err := ParseFlow(filepath.Join(dir, "flow.yaml"))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseFlow() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseVarOverride(t *testing.T) {
+	tests := []struct {
+		flag      string
+		wantName  string
+		wantValue interface{}
+	}{
+		{"vpc_name=my vpc=1", "vpc_name", "my vpc=1"},
+		{"az_count=2", "az_count", "2"},
+		{`azs=["a", "b"]`, "azs", []interface{}{"a", "b"}},
+		{`tags={"owner": "platform"}`, "tags", map[string]interface{}{"owner": "platform"}},
+		{"banner=[prod] hello", "banner", "[prod] hello"},
+	}
+	for _, tt := range tests {
+		name, value, err := ParseVarOverride(tt.flag)
+		if err != nil || name != tt.wantName || !reflect.DeepEqual(value, tt.wantValue) {
+			t.Errorf("ParseVarOverride(%q) = %q, %#v, %v", tt.flag, name, value, err)
+		}
+	}
+	for _, bad := range []string{"novalue", "=x", "bad name=x"} {
+		if _, _, err := ParseVarOverride(bad); err == nil {
+			t.Errorf("ParseVarOverride(%q) expected error", bad)
+		}
+	}
+}
+
+func TestVarOverridesReachTerraformTyped(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake terraform script requires a POSIX shell")
+	}
+
+	// The fake terraform prints the generated var file
+	bin := t.TempDir()
+	script := `#!/bin/sh
+for arg in "$@"; do
+	case "$arg" in
+	-var-file=*.tfvars.json) cat "${arg#-var-file=}" ;;
+	esac
+done
+`
+	if err := os.WriteFile(filepath.Join(bin, "terraform"), []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake terraform: %v", err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	f := &Flow{
+		WorkingDir: t.TempDir(),
+		Variables:  map[string]interface{}{"azs": []interface{}{"x"}},
+	}
+	for _, flag := range []string{`azs=["a","b"]`, `tags={"owner":"platform"}`} {
+		name, value, err := ParseVarOverride(flag)
+		if err != nil {
+			t.Fatalf("ParseVarOverride(%q) error: %v", flag, err)
+		}
+		if f.VarOverrides == nil {
+			f.VarOverrides = make(map[string]interface{})
+		}
+		f.VarOverrides[name] = value
+	}
+	e, err := NewExecutor(f, false)
+	if err != nil {
+		t.Fatalf("NewExecutor() error: %v", err)
+	}
+
+	executor, err := e.stepExecutor(Step{Name: "apply", Type: "terraform"})
+	if err != nil {
+		t.Fatalf("stepExecutor() error: %v", err)
+	}
+	out, err := executor.Execute("terraform plan")
+	if err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+	if want := `{"azs":["a","b"],"tags":{"owner":"platform"}}`; strings.TrimSpace(out) != want {
+		t.Errorf("Generated var file = %s, want %s", out, want)
+	}
+}
@@ -0,0 +1,286 @@
+package flow
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/infratest/infratest/internal/http"
+	"github.com/infratest/infratest/internal/jsonpath"
+	"github.com/infratest/infratest/internal/network"
+	"github.com/infratest/infratest/internal/terraform"
+	"github.com/infratest/infratest/internal/ui"
+)
+
+const (
+	// defaultWaitInterval is the time between two checks of a wait step
+	defaultWaitInterval = 5 * time.Second
+	// defaultWaitTimeout bounds wait steps without a timeout of their own
+	defaultWaitTimeout = 5 * time.Minute
+)
+
+// validateWait checks the condition of a wait step, and that other steps don't use wait fields
+func validateWait(step Step) error {
+	if step.Type != "wait" {
+		if step.Until != nil || step.Interval != "" {
+			return fmt.Errorf("until and interval are only supported on wait steps")
+		}
+		return nil
+	}
+
+	if step.Interval != "" {
+		interval, err := time.ParseDuration(step.Interval)
+		if err != nil {
+			return fmt.Errorf("invalid interval: %w", err)
+		}
+		if interval <= 0 {
+			return fmt.Errorf("interval must be positive")
+		}
+	}
+	if step.Until == nil {
+		return nil
+	}
+	return step.Until.validate()
+}
+
+// validate checks that a wait condition uses exactly one source and that its checks fit the source
+func (c WaitCondition) validate() error {
+	sources := 0
+	for _, set := range []bool{c.Output != "", c.Resource != "", c.URL != "", c.TCP != ""} {
+		if set {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return fmt.Errorf("until: exactly one of output, resource, url or tcp is required")
+	}
+
+	if c.Attribute != "" {
+		if c.Resource == "" {
+			return fmt.Errorf("until: attribute requires resource")
+		}
+		if _, err := jsonpath.Parse(c.Attribute); err != nil {
+			return fmt.Errorf("until: %w", err)
+		}
+	}
+	if c.Resource != "" && !strings.Contains(c.Resource, ".") {
+		return fmt.Errorf("until: invalid resource address: %s (expected format: type.name)", c.Resource)
+	}
+	if c.Status != 0 {
+		if c.URL == "" {
+			return fmt.Errorf("until: status requires url")
+		}
+		if c.Status < 100 || c.Status > 599 {
+			return fmt.Errorf("until: invalid status %d", c.Status)
+		}
+	}
+	if c.Equals != nil && c.Output == "" && c.Resource == "" {
+		return fmt.Errorf("until: equals requires output or resource")
+	}
+	if c.Matches != "" {
+		if c.TCP != "" {
+			return fmt.Errorf("until: matches is not supported with tcp")
+		}
+		if _, err := regexp.Compile(c.Matches); err != nil {
+			return fmt.Errorf("until: invalid regex %q: %w", c.Matches, err)
+		}
+	}
+	if c.TCP != "" && !strings.Contains(c.TCP, "${") {
+		if _, _, err := net.SplitHostPort(c.TCP); err != nil {
+			return fmt.Errorf("until: invalid tcp address: %w", err)
+		}
+	}
+	return nil
+}
+
+// String describes what the condition waits for, e.g. in results and previews
+func (c WaitCondition) String() string {
+	var target string
+	switch {
+	case c.Output != "":
+		target = "output " + c.Output
+	case c.Resource != "" && c.Attribute != "":
+		target = c.Resource + "." + c.Attribute
+	case c.Resource != "":
+		target = c.Resource + " exists"
+	case c.URL != "":
+		target = fmt.Sprintf("GET %s -> %d", c.URL, c.status())
+	case c.TCP != "":
+		target = "tcp " + c.TCP + " open"
+	}
+
+	switch {
+	case c.Equals != nil:
+		target += fmt.Sprintf(" == %v", c.Equals)
+	case c.Matches != "":
+		target += fmt.Sprintf(" =~ %q", c.Matches)
+	case c.Output != "" || c.Attribute != "":
+		target += " is set"
+	}
+	return target
+}
+
+// status returns the HTTP status a url condition waits for
+func (c WaitCondition) status() int {
+	if c.Status == 0 {
+		return 200
+	}
+	return c.Status
+}
+
+// templates returns the condition fields that are interpolated before each check
+func (c *WaitCondition) templates() []string {
+	if c == nil {
+		return nil
+	}
+	templates := []string{c.Output, c.Resource, c.URL, c.TCP, c.Matches}
+	if s, ok := c.Equals.(string); ok {
+		templates = append(templates, s)
+	}
+	return templates
+}
+
+// waitCondition returns a copy of a wait step's condition with its fields interpolated
+func (e *Executor) waitCondition(step Step) WaitCondition {
+	c := *step.Until
+	c.Output = e.interpolate(c.Output)
+	c.Resource = e.interpolate(c.Resource)
+	c.URL = e.interpolate(c.URL)
+	c.TCP = e.interpolate(c.TCP)
+	c.Matches = e.interpolate(c.Matches)
+	if s, ok := c.Equals.(string); ok {
+		c.Equals = e.interpolate(s)
+	}
+	return c
+}
+
+// waitInterval returns the time between two checks of a wait step
+func waitInterval(step Step) (time.Duration, error) {
+	if step.Interval == "" {
+		return defaultWaitInterval, nil
+	}
+	return time.ParseDuration(step.Interval)
+}
+
+// executeWaitStep polls the condition of a wait step until it holds or the
+// step's deadline expires, and reports how long it took to converge
+func (e *Executor) executeWaitStep(ctx context.Context, step Step) (string, error) {
+	if step.Until == nil {
+		return "", fmt.Errorf("wait steps require until")
+	}
+	interval, err := waitInterval(step)
+	if err != nil {
+		return "", fmt.Errorf("invalid interval: %w", err)
+	}
+
+	// Refresh outputs so the condition sees the latest values
+	if err := e.refreshOutputs(ctx); err != nil {
+		ui.PrintDebug(e.debug, "Warning: failed to refresh outputs: %v", err)
+	}
+
+	start := time.Now()
+	var lastErr error
+	checks := 0
+	for {
+		cond := e.waitCondition(step)
+		err := e.checkWait(ctx, cond)
+		if ctx.Err() == nil {
+			checks++
+			if err == nil {
+				return fmt.Sprintf("%s: met after %s (%d check(s))", cond, time.Since(start).Round(time.Millisecond), checks), nil
+			}
+			lastErr = err
+			ui.PrintDebug(e.debug, "Wait check %d: %v", checks, err)
+		}
+		if ctx.Err() != nil || sleepContext(ctx, interval) != nil {
+			if lastErr == nil {
+				lastErr = ctx.Err()
+			}
+			return "", fmt.Errorf("%s: not met after %s (%d check(s)): %w", cond, time.Since(start).Round(time.Second), checks, lastErr)
+		}
+	}
+}
+
+// checkWait checks a wait condition once; it returns why the condition doesn't hold yet
+func (e *Executor) checkWait(ctx context.Context, c WaitCondition) error {
+	switch {
+	case c.Output != "":
+		if err := e.refreshOutputs(ctx); err != nil {
+			return fmt.Errorf("failed to read outputs: %w", err)
+		}
+		val, err := terraform.GetOutputValue(e.currentOutputs(), c.Output)
+		if err != nil {
+			return err
+		}
+		return c.checkValue("output "+c.Output, val)
+
+	case c.Resource != "":
+		state, err := e.readState(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get terraform state: %w", err)
+		}
+		for _, r := range state.GetResources() {
+			if r.Address != c.Resource {
+				continue
+			}
+			if c.Attribute == "" {
+				return nil
+			}
+			val, err := jsonpath.Get(r.Attributes, c.Attribute)
+			if err != nil {
+				return fmt.Errorf("resource %s: %w", c.Resource, err)
+			}
+			return c.checkValue(c.Resource+"."+c.Attribute, val)
+		}
+		return fmt.Errorf("resource %s not found in state", c.Resource)
+
+	case c.URL != "":
+		resp, err := http.FetchContext(ctx, c.URL)
+		if err != nil {
+			return err
+		}
+		if resp.Status != c.status() {
+			return fmt.Errorf("expected status %d, got %d", c.status(), resp.Status)
+		}
+		if c.Matches == "" {
+			return nil
+		}
+		return c.checkValue("response body", string(resp.Body))
+
+	case c.TCP != "":
+		res, err := network.DialContext(ctx, network.Check{Network: "tcp", Address: c.TCP})
+		if err != nil {
+			return err
+		}
+		if !res.Reachable {
+			return fmt.Errorf("tcp %s is not reachable: %s", c.TCP, res.Reason)
+		}
+		return nil
+	}
+	return fmt.Errorf("until: exactly one of output, resource, url or tcp is required")
+}
+
+// checkValue checks a polled value against equals or matches. Without either,
+// the value only needs to be set.
+func (c WaitCondition) checkValue(name string, val interface{}) error {
+	switch {
+	case c.Equals != nil:
+		if !jsonValuesEqual(c.Equals, val) {
+			return fmt.Errorf("%s is %v, expected %v", name, val, c.Equals)
+		}
+	case c.Matches != "":
+		re, err := regexp.Compile(c.Matches)
+		if err != nil {
+			return err
+		}
+		if !re.MatchString(fmt.Sprintf("%v", val)) {
+			return fmt.Errorf("%s does not match %q", name, c.Matches)
+		}
+	case val == nil || val == "":
+		return fmt.Errorf("%s is not set", name)
+	}
+	return nil
+}
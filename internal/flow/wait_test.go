@@ -0,0 +1,151 @@
+package flow
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestValidateWait(t *testing.T) {
+	tests := []struct {
+		name    string
+		step    Step
+		wantErr string
+	}{
+		{name: "output", step: Step{Type: "wait", Until: &WaitCondition{Output: "api_status", Equals: "ready"}, Interval: "2s"}},
+		{name: "resource attribute", step: Step{Type: "wait", Until: &WaitCondition{Resource: "aws_instance.web", Attribute: "instance_state", Equals: "running"}}},
+		{name: "url", step: Step{Type: "wait", Until: &WaitCondition{URL: "http://${output.alb_dns}/health", Status: 204, Matches: "ok"}}},
+		{name: "tcp placeholder", step: Step{Type: "wait", Until: &WaitCondition{TCP: "${output.db_host}"}}},
+		{name: "no source", step: Step{Type: "wait", Until: &WaitCondition{Equals: "ready"}}, wantErr: "exactly one of output, resource, url or tcp"},
+		{name: "two sources", step: Step{Type: "wait", Until: &WaitCondition{Output: "a", TCP: "localhost:80"}}, wantErr: "exactly one of"},
+		{name: "attribute without resource", step: Step{Type: "wait", Until: &WaitCondition{Output: "a", Attribute: "b"}}, wantErr: "attribute requires resource"},
+		{name: "bad resource", step: Step{Type: "wait", Until: &WaitCondition{Resource: "web"}}, wantErr: "invalid resource address"},
+		{name: "status without url", step: Step{Type: "wait", Until: &WaitCondition{Output: "a", Status: 200}}, wantErr: "status requires url"},
+		{name: "equals on url", step: Step{Type: "wait", Until: &WaitCondition{URL: "http://x", Equals: "ok"}}, wantErr: "equals requires output or resource"},
+		{name: "bad regex", step: Step{Type: "wait", Until: &WaitCondition{Output: "a", Matches: "("}}, wantErr: "invalid regex"},
+		{name: "bad tcp address", step: Step{Type: "wait", Until: &WaitCondition{TCP: "localhost"}}, wantErr: "invalid tcp address"},
+		{name: "bad interval", step: Step{Type: "wait", Until: &WaitCondition{Output: "a"}, Interval: "0s"}, wantErr: "interval must be positive"},
+		{name: "until on other step", step: Step{Type: "http", URL: "http://x", Interval: "1s"}, wantErr: "only supported on wait steps"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateWait(tt.step)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateWait() error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateWait() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExecuteWithContextWait(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"status": "ready"}`))
+	}))
+	defer server.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error: %v", err)
+	}
+	defer listener.Close()
+
+	f := &Flow{
+		Name:       "wait",
+		WorkingDir: t.TempDir(),
+		Steps: []Step{
+			{Name: "api", Type: "wait", Until: &WaitCondition{URL: server.URL, Matches: "ready"}, Interval: "10ms"},
+			{Name: "db", Type: "wait", Until: &WaitCondition{TCP: listener.Addr().String()}, Interval: "10ms"},
+		},
+	}
+	if err := validateFlow(f); err != nil {
+		t.Fatalf("validateFlow() error: %v", err)
+	}
+
+	e := &Executor{flow: f, outputs: map[string]interface{}{}}
+	if err := e.ExecuteWithContext(context.Background()); err != nil {
+		t.Fatalf("ExecuteWithContext() error: %v", err)
+	}
+	results := e.GetResults()
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %+v", results)
+	}
+	if !strings.Contains(results[0].Output, "met after") || !strings.Contains(results[0].Output, "(3 check(s))") {
+		t.Errorf("Output = %q, want convergence after 3 checks", results[0].Output)
+	}
+	if !strings.Contains(results[1].Output, "(1 check(s))") {
+		t.Errorf("Output = %q, want convergence after 1 check", results[1].Output)
+	}
+}
+
+func TestExecuteWithContextWaitTimeout(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error: %v", err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	f := &Flow{
+		Name:       "wait-timeout",
+		WorkingDir: t.TempDir(),
+		Steps: []Step{
+			{Name: "db", Type: "wait", Until: &WaitCondition{TCP: addr}, Interval: "20ms", Timeout: "200ms"},
+		},
+	}
+
+	e := &Executor{flow: f, outputs: map[string]interface{}{}}
+	err = e.ExecuteWithContext(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "timed out after 200ms") || !strings.Contains(err.Error(), "not met after") {
+		t.Fatalf("Expected wait timeout error, got %v", err)
+	}
+	if results := e.GetResults(); len(results) != 1 || !results[0].TimedOut {
+		t.Errorf("Expected a single timed-out result, got %+v", results)
+	}
+}
+
+func TestWaitConditionCheckValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		cond    WaitCondition
+		val     interface{}
+		wantErr string
+	}{
+		{name: "set", cond: WaitCondition{Output: "ip"}, val: "10.0.0.1"},
+		{name: "empty", cond: WaitCondition{Output: "ip"}, val: "", wantErr: "output ip is not set"},
+		{name: "equals", cond: WaitCondition{Output: "count", Equals: 3}, val: float64(3)},
+		{name: "equals string form", cond: WaitCondition{Output: "count", Equals: "3"}, val: float64(3)},
+		{name: "not equal", cond: WaitCondition{Output: "state", Equals: "running"}, val: "pending", wantErr: "output state is pending, expected running"},
+		{name: "matches", cond: WaitCondition{Output: "state", Matches: "^run"}, val: "running"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cond.checkValue("output "+tt.cond.Output, tt.val)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("checkValue() error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("checkValue() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
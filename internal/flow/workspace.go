@@ -0,0 +1,88 @@
+package flow
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/infratest/infratest/internal/terraform"
+	"github.com/infratest/infratest/internal/ui"
+)
+
+// IsolationWorkspace runs the flow in its own terraform workspace
+const IsolationWorkspace = "workspace"
+
+// workspaceNameRegex matches characters that are replaced in workspace names
+var workspaceNameRegex = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// validateIsolation checks the flow's isolation mode
+func validateIsolation(f *Flow) error {
+	switch f.Isolation {
+	case "", "none", IsolationWorkspace:
+		return nil
+	default:
+		return fmt.Errorf("invalid isolation %q (expected none or workspace)", f.Isolation)
+	}
+}
+
+// workspaceName returns a unique workspace name for a run of the flow,
+// e.g. infratest-simple-vpc-20240101-120000-1a2b3c
+func workspaceName(flowName string) string {
+	suffix := make([]byte, 3)
+	rand.Read(suffix)
+	name := strings.Trim(workspaceNameRegex.ReplaceAllString(flowName, "-"), "-")
+	return fmt.Sprintf("infratest-%s-%s-%s", name, time.Now().Format("20060102-150405"), hex.EncodeToString(suffix))
+}
+
+// readOutputDetails reads terraform outputs with their sensitive flags, from
+// the isolated workspace if there is one
+func (e *Executor) readOutputDetails(ctx context.Context) (map[string]terraform.Output, error) {
+	if e.Workspace() != "" {
+		return e.executor.OutputDetailsWithContext(ctx)
+	}
+	return terraform.ParseOutputDetailsWithContext(ctx, e.flow.WorkingDir)
+}
+
+// readState reads terraform state, from the isolated workspace if there is one
+func (e *Executor) readState(ctx context.Context) (*terraform.State, error) {
+	if e.Workspace() != "" {
+		return e.executor.StateWithContext(ctx)
+	}
+	return terraform.GetStateWithContext(ctx, e.flow.WorkingDir)
+}
+
+// Workspace returns the name of the flow's isolated workspace, or "" without isolation
+func (e *Executor) Workspace() string {
+	if e.executor == nil {
+		return ""
+	}
+	return e.executor.Workspace()
+}
+
+// SetKeepWorkspace keeps the isolated workspace after the run, e.g. for debugging
+func (e *Executor) SetKeepWorkspace(keep bool) {
+	e.keepWorkspace = keep
+}
+
+// ReleaseWorkspace selects the previous workspace again and deletes the
+// isolated one, unless it should be kept. Call it after cleanup.
+func (e *Executor) ReleaseWorkspace(ctx context.Context) error {
+	name := e.Workspace()
+	if name == "" {
+		return nil
+	}
+	if e.keepWorkspace {
+		ui.PrintInfo(fmt.Sprintf("🗂  Keeping workspace %s (inspect it with TF_WORKSPACE=%s)", name, name))
+		return nil
+	}
+
+	if err := e.executor.DeleteWorkspace(ctx); err != nil {
+		return fmt.Errorf("%w\nThe workspace may still hold resources; destroy them with:\n  cd %s\n  TF_WORKSPACE=%s terraform destroy -auto-approve", err, e.flow.WorkingDir, name)
+	}
+	ui.PrintDebug(e.debug, "Deleted workspace %s", name)
+	return nil
+}
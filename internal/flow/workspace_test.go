@@ -0,0 +1,17 @@
+package flow
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestWorkspaceName(t *testing.T) {
+	a := workspaceName("simple vpc/us-east-1")
+	b := workspaceName("simple vpc/us-east-1")
+	if a == b {
+		t.Errorf("Expected unique workspace names, got %s twice", a)
+	}
+	if !regexp.MustCompile(`^infratest-simple-vpc-us-east-1-\d{8}-\d{6}-[0-9a-f]{6}$`).MatchString(a) {
+		t.Errorf("Unexpected workspace name %s", a)
+	}
+}
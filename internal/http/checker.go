@@ -1,49 +1,119 @@
 package http
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"time"
 )
 
-// CheckWithRetry performs HTTP check with retries
+// maxBodySize caps how much of a response body is kept for captures
+const maxBodySize = 10 << 20
+
+// Response is the final response of an HTTP check
+type Response struct {
+	Status int
+	Body   []byte
+}
+
+// CheckWithRetry performs HTTP check with retries (without context, for backward compatibility)
 func CheckWithRetry(url string, expectedStatus int, retries int, delay time.Duration, debug bool) (int, error) {
+	return CheckWithRetryContext(context.Background(), url, expectedStatus, retries, delay, debug)
+}
+
+// CheckWithRetryContext performs HTTP check with retries, giving up when the context is done
+func CheckWithRetryContext(ctx context.Context, url string, expectedStatus int, retries int, delay time.Duration, debug bool) (int, error) {
+	resp, err := FetchWithRetryContext(ctx, url, expectedStatus, retries, delay, debug)
+	return resp.Status, err
+}
+
+// FetchWithRetryContext performs HTTP check with retries like CheckWithRetryContext
+// and also returns the body of the last response
+func FetchWithRetryContext(ctx context.Context, url string, expectedStatus int, retries int, delay time.Duration, debug bool) (Response, error) {
 	client := &http.Client{
 		Timeout: 30 * time.Second,
 	}
 
 	var lastErr error
-	var lastStatus int
+	var last Response
 
 	for i := 0; i <= retries; i++ {
 		if debug && i > 0 {
 			fmt.Printf("[DEBUG] HTTP check retry %d/%d for %s\n", i, retries, url)
 		}
 
-		resp, err := client.Get(url)
+		resp, err := check(ctx, client, url)
 		if err != nil {
+			if ctx.Err() != nil {
+				return last, fmt.Errorf("HTTP check cancelled: %w", ctx.Err())
+			}
 			lastErr = err
 			if i < retries {
-				time.Sleep(delay)
+				if err := sleep(ctx, delay); err != nil {
+					return last, err
+				}
 				continue
 			}
-			return 0, fmt.Errorf("HTTP check failed after %d retries: %w", retries, err)
+			return Response{}, fmt.Errorf("HTTP check failed after %d retries: %w", retries, err)
 		}
-		defer resp.Body.Close()
 
-		lastStatus = resp.StatusCode
+		last = resp
 
-		if expectedStatus > 0 && resp.StatusCode != expectedStatus {
+		if expectedStatus > 0 && resp.Status != expectedStatus {
 			if i < retries {
-				time.Sleep(delay)
+				if err := sleep(ctx, delay); err != nil {
+					return last, err
+				}
 				continue
 			}
-			return resp.StatusCode, fmt.Errorf("expected status %d, got %d", expectedStatus, resp.StatusCode)
+			return resp, fmt.Errorf("expected status %d, got %d", expectedStatus, resp.Status)
 		}
 
-		return resp.StatusCode, nil
+		return resp, nil
+	}
+
+	return last, lastErr
+}
+
+// FetchContext performs a single GET request, without checking the status
+func FetchContext(ctx context.Context, url string) (Response, error) {
+	client := &http.Client{
+		Timeout: 30 * time.Second,
+	}
+	return check(ctx, client, url)
+}
+
+// check performs a single GET request and returns the status code and body
+func check(ctx context.Context, client *http.Client, url string) (Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return Response{}, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return Response{}, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return Response{Status: resp.StatusCode}, fmt.Errorf("failed to read response body: %w", err)
 	}
 
-	return lastStatus, lastErr
+	return Response{Status: resp.StatusCode, Body: body}, nil
 }
 
+// sleep waits for the retry delay unless the context is done first
+func sleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("HTTP check cancelled: %w", ctx.Err())
+	case <-timer.C:
+		return nil
+	}
+}
@@ -4,16 +4,20 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+
+	"github.com/infratest/infratest/internal/jsonpath"
 )
 
 // ResourceMatch represents a resource match pattern
 type ResourceMatch struct {
+	Module    string                 // e.g., "module.network" or "module.*"; empty for the root module
 	Type      string                 // e.g., "aws_vpc"
 	Name      string                 // e.g., "main" or ".*" for wildcard
 	Count     *int                   // exact count
 	MinCount  *int                   // minimum count
 	MaxCount  *int                   // maximum count
 	Attributes map[string]interface{} // attribute assertions
+	References map[string]Reference   // attribute path -> attribute of other resources it must point at
 }
 
 // MatchResult represents the result of matching resources
@@ -31,6 +35,8 @@ type MatchedResource struct {
 	Name      string
 	ID        string
 	Address   string
+	Module    string
+	Mode      string
 	Attributes map[string]interface{}
 }
 
@@ -40,6 +46,8 @@ type AttributeMismatch struct {
 	Attribute string
 	Expected  interface{}
 	Actual    interface{}
+	Operator  string // the operator that failed, "equals" for plain values
+	Reason    string // why the operator failed
 }
 
 // Matcher matches resources against expected patterns
@@ -52,10 +60,48 @@ type Resource struct {
 	Type      string
 	Name      string
 	Address   string
+	Module    string // module path, e.g. module.network; empty for the root module
+	Mode      string // "managed" or "data"
 	ID        string
 	Attributes map[string]interface{}
 }
 
+// ParsePattern splits a resource pattern like aws_subnet.* or
+// module.network.aws_subnet.* into its module path, type and name. The type
+// of a data source pattern like data.aws_ami.ubuntu keeps its data. prefix.
+func ParsePattern(pattern string) (module, resourceType, name string, err error) {
+	rest := pattern
+	var modules []string
+	for strings.HasPrefix(rest, "module.") {
+		moduleName, tail, ok := strings.Cut(strings.TrimPrefix(rest, "module."), ".")
+		if !ok || moduleName == "" {
+			return "", "", "", fmt.Errorf("invalid resource pattern: %s (expected format: [module.<name>.][data.]type.name)", pattern)
+		}
+		modules = append(modules, "module."+moduleName)
+		rest = tail
+	}
+
+	prefix := ""
+	if strings.HasPrefix(rest, "data.") {
+		prefix, rest = "data.", strings.TrimPrefix(rest, "data.")
+	}
+
+	resourceType, name, ok := strings.Cut(rest, ".")
+	if !ok || resourceType == "" || name == "" {
+		return "", "", "", fmt.Errorf("invalid resource pattern: %s (expected format: [module.<name>.][data.]type.name)", pattern)
+	}
+	return strings.Join(modules, "."), prefix + resourceType, name, nil
+}
+
+// patternType returns the type a pattern names the resource by, with a
+// data. prefix for data sources
+func (r Resource) patternType() string {
+	if r.Mode == "data" {
+		return "data." + r.Type
+	}
+	return r.Type
+}
+
 // NewMatcher creates a new matcher
 func NewMatcher(resources []Resource) *Matcher {
 	return &Matcher{
@@ -94,26 +140,15 @@ func (m *Matcher) matchPattern(pattern string, match ResourceMatch) MatchResult
 		namePattern = ".*"
 	}
 	
-	// Convert wildcard pattern to regex
-	// If pattern is exactly ".*", use it directly
-	// Otherwise, if it contains *, replace * with .* and escape other chars
-	var nameRegexStr string
-	if namePattern == ".*" {
-		nameRegexStr = "^.*$"
-	} else if strings.Contains(namePattern, "*") {
-		// Escape everything first, then replace escaped \* with .*
-		escaped := regexp.QuoteMeta(namePattern)
-		nameRegexStr = "^" + strings.ReplaceAll(escaped, "\\*", ".*") + "$"
-	} else {
-		// Exact match - escape all special chars
-		nameRegexStr = "^" + regexp.QuoteMeta(namePattern) + "$"
-	}
-	nameRegex := regexp.MustCompile(nameRegexStr)
+	nameRegex := wildcardRegex(namePattern)
+
+	// Unqualified patterns only match resources of the root module
+	moduleRegex := wildcardRegex(match.Module)
 
 	// Find matching resources
 	var matched []Resource
 	for _, res := range m.resources {
-		if typeRegex.MatchString(res.Type) && nameRegex.MatchString(res.Name) {
+		if moduleRegex.MatchString(res.Module) && typeRegex.MatchString(res.patternType()) && nameRegex.MatchString(res.Name) {
 			matched = append(matched, res)
 		}
 	}
@@ -140,32 +175,62 @@ func (m *Matcher) matchPattern(pattern string, match ResourceMatch) MatchResult
 			Name:       res.Name,
 			ID:         res.ID,
 			Address:    res.Address,
+			Module:     res.Module,
+			Mode:       res.Mode,
 			Attributes: res.Attributes,
 		}
 
 		// Validate attributes
 		for attrPath, expectedVal := range match.Attributes {
-			actualVal, err := m.getNestedAttribute(res.Attributes, attrPath)
-			if err != nil {
-				result.Mismatches = append(result.Mismatches, AttributeMismatch{
-					Resource:  res.Address,
-					Attribute: attrPath,
-					Expected:  expectedVal,
-					Actual:    nil,
-				})
-				result.Issues = append(result.Issues, fmt.Sprintf("%s: attribute %s not found", res.Address, attrPath))
-				continue
+			actualVal, found, wildcard := m.getAttribute(res.Attributes, attrPath)
+
+			// Wildcard paths select a list of values, each of which must
+			// match unless the matcher says otherwise
+			expected := expectedVal
+			if wildcard && found && !isQuantifier(expectedVal) {
+				expected = map[string]interface{}{"all": expectedVal}
 			}
 
-			if !m.valuesEqual(expectedVal, actualVal) {
-				result.Mismatches = append(result.Mismatches, AttributeMismatch{
-					Resource:  res.Address,
-					Attribute: attrPath,
-					Expected:  expectedVal,
-					Actual:    actualVal,
-				})
+			operator, reason := m.matchValue(expected, actualVal, found)
+			if reason == "" {
+				continue
+			}
+			result.Mismatches = append(result.Mismatches, AttributeMismatch{
+				Resource:  res.Address,
+				Attribute: attrPath,
+				Expected:  expectedVal,
+				Actual:    actualVal,
+				Operator:  operator,
+				Reason:    reason,
+			})
+
+			switch {
+			case operator == "equals" && !found:
+				result.Issues = append(result.Issues, fmt.Sprintf("%s: attribute %s not found", res.Address, attrPath))
+			case operator == "equals":
 				result.Issues = append(result.Issues, fmt.Sprintf("%s: attribute %s mismatch - expected %v, got %v", res.Address, attrPath, expectedVal, actualVal))
+			default:
+				result.Issues = append(result.Issues, fmt.Sprintf("%s: attribute %s: %s failed: %s", res.Address, attrPath, operator, reason))
+			}
+		}
+
+		// Validate references to other resources
+		for _, attrPath := range sortedReferences(match.References) {
+			ref := match.References[attrPath]
+			actualVal, found, wildcard := m.getAttribute(res.Attributes, attrPath)
+			reason := m.checkReference(ref, actualVal, found, wildcard)
+			if reason == "" {
+				continue
 			}
+			result.Mismatches = append(result.Mismatches, AttributeMismatch{
+				Resource:  res.Address,
+				Attribute: attrPath,
+				Expected:  ref.String(),
+				Actual:    actualVal,
+				Operator:  "references",
+				Reason:    reason,
+			})
+			result.Issues = append(result.Issues, fmt.Sprintf("%s: attribute %s: references failed: %s", res.Address, attrPath, reason))
 		}
 
 		result.Resources = append(result.Resources, matchedRes)
@@ -179,29 +244,35 @@ func (m *Matcher) matchPattern(pattern string, match ResourceMatch) MatchResult
 	return result
 }
 
-// getNestedAttribute gets a nested attribute using dot notation (e.g., "tags.Name")
-func (m *Matcher) getNestedAttribute(attrs map[string]interface{}, path string) (interface{}, error) {
-	parts := strings.Split(path, ".")
-	current := attrs
-
-	for i, part := range parts {
-		val, exists := current[part]
-		if !exists {
-			return nil, fmt.Errorf("attribute not found: %s", path)
-		}
-
-		if i == len(parts)-1 {
-			return val, nil
-		}
-
-		next, ok := val.(map[string]interface{})
-		if !ok {
-			return nil, fmt.Errorf("attribute %s is not a map", strings.Join(parts[:i+1], "."))
-		}
-		current = next
+// wildcardRegex converts a wildcard pattern to a regex: ".*" is used
+// directly, * matches any characters and everything else matches exactly
+func wildcardRegex(pattern string) *regexp.Regexp {
+	if pattern == ".*" {
+		return regexp.MustCompile("^.*$")
 	}
+	// Escape everything first, then replace escaped \* with .*
+	escaped := regexp.QuoteMeta(pattern)
+	return regexp.MustCompile("^" + strings.ReplaceAll(escaped, "\\*", ".*") + "$")
+}
 
-	return nil, fmt.Errorf("unexpected error")
+// getAttribute gets the value of an attribute path using dot notation (e.g.,
+// "tags.Name"), list indexes (e.g., "ingress[0].from_port") or quoted keys
+// (e.g., `tags["kubernetes.io/role"]`). Paths with a wildcard, like
+// "ingress[*].from_port", return the list of selected values and are
+// only found if they select at least one.
+func (m *Matcher) getAttribute(attrs map[string]interface{}, path string) (value interface{}, found, wildcard bool) {
+	segments, err := jsonpath.Parse(path)
+	if err != nil {
+		return nil, false, false
+	}
+	values, err := jsonpath.Select(attrs, segments)
+	if err != nil {
+		return nil, false, false
+	}
+	if jsonpath.HasWildcard(segments) {
+		return values, len(values) > 0, true
+	}
+	return values[0], true, false
 }
 
 // valuesEqual compares two values for equality
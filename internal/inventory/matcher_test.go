@@ -114,7 +114,7 @@ func TestMatcher_Match(t *testing.T) {
 	}
 }
 
-func TestMatcher_getNestedAttribute(t *testing.T) {
+func TestMatcher_getAttribute(t *testing.T) {
 	attrs := map[string]interface{}{
 		"tags": map[string]interface{}{
 			"Name": "test-vpc",
@@ -165,13 +165,13 @@ func TestMatcher_getNestedAttribute(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := matcher.getNestedAttribute(attrs, tt.path)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("getNestedAttribute() error = %v, wantErr %v", err, tt.wantErr)
+			got, found, _ := matcher.getAttribute(attrs, tt.path)
+			if found == tt.wantErr {
+				t.Errorf("getAttribute() found = %v, wantErr %v", found, tt.wantErr)
 				return
 			}
 			if !tt.wantErr && got != tt.want {
-				t.Errorf("getNestedAttribute() = %v, want %v", got, tt.want)
+				t.Errorf("getAttribute() = %v, want %v", got, tt.want)
 			}
 		})
 	}
@@ -181,3 +181,80 @@ func intPtr(i int) *int {
 	return &i
 }
 
+
+func TestParsePattern(t *testing.T) {
+	tests := []struct {
+		pattern    string
+		wantModule string
+		wantType   string
+		wantName   string
+		wantErr    bool
+	}{
+		{pattern: "aws_vpc.main", wantType: "aws_vpc", wantName: "main"},
+		{pattern: "aws_subnet.*", wantType: "aws_subnet", wantName: "*"},
+		{pattern: "module.network.aws_subnet.*", wantModule: "module.network", wantType: "aws_subnet", wantName: "*"},
+		{pattern: "module.*.aws_vpc.main", wantModule: "module.*", wantType: "aws_vpc", wantName: "main"},
+		{pattern: "module.app.module.db.aws_db_instance.main", wantModule: "module.app.module.db", wantType: "aws_db_instance", wantName: "main"},
+		{pattern: "data.aws_ami.ubuntu", wantType: "data.aws_ami", wantName: "ubuntu"},
+		{pattern: "module.network.data.aws_availability_zones.*", wantModule: "module.network", wantType: "data.aws_availability_zones", wantName: "*"},
+		{pattern: "aws_vpc", wantErr: true},
+		{pattern: "module.network", wantErr: true},
+		{pattern: "module.network.aws_vpc", wantErr: true},
+		{pattern: "data.aws_ami", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			module, resourceType, name, err := ParsePattern(tt.pattern)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePattern() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if module != tt.wantModule || resourceType != tt.wantType || name != tt.wantName {
+				t.Errorf("ParsePattern() = %q, %q, %q, want %q, %q, %q", module, resourceType, name, tt.wantModule, tt.wantType, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestMatcher_MatchModules(t *testing.T) {
+	matcher := NewMatcher([]Resource{
+		{Type: "aws_vpc", Name: "main", Address: "aws_vpc.main"},
+		{Type: "aws_vpc", Name: "main", Address: "module.network.aws_vpc.main", Module: "module.network"},
+		{Type: "aws_subnet", Name: "a", Address: "module.network.aws_subnet.a", Module: "module.network"},
+		{Type: "aws_subnet", Name: "b", Address: "module.network.aws_subnet.b", Module: "module.network"},
+		{Type: "aws_vpc", Name: "main", Address: "module.peer.module.network.aws_vpc.main", Module: "module.peer.module.network"},
+		{Type: "aws_vpc", Name: "main", Address: "data.aws_vpc.main", Mode: "data"},
+		{Type: "aws_availability_zones", Name: "available", Address: "module.network.data.aws_availability_zones.available", Module: "module.network", Mode: "data"},
+	})
+
+	tests := []struct {
+		pattern string
+		want    int
+	}{
+		{pattern: "aws_vpc.main", want: 1}, // root module only
+		{pattern: "module.network.aws_subnet.*", want: 2},
+		{pattern: "module.network.aws_vpc.main", want: 1},
+		{pattern: "module.*.aws_vpc.main", want: 2},
+		{pattern: "module.peer.module.*.aws_vpc.*", want: 1},
+		{pattern: "module.storage.aws_vpc.main", want: 0},
+		{pattern: "data.aws_vpc.main", want: 1}, // data sources only match data. patterns
+		{pattern: "data.aws_vpc.*", want: 1},
+		{pattern: "module.network.data.aws_availability_zones.*", want: 1},
+		{pattern: "data.aws_availability_zones.available", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			module, resourceType, name, err := ParsePattern(tt.pattern)
+			if err != nil {
+				t.Fatalf("ParsePattern() error: %v", err)
+			}
+			results, _ := matcher.Match(map[string]ResourceMatch{
+				tt.pattern: {Module: module, Type: resourceType, Name: name},
+			})
+			if got := results[tt.pattern].Count; got != tt.want {
+				t.Errorf("Match() count = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,309 @@
+package inventory
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// operators are the keys of an operator-style attribute matcher, like
+// {regex: "^tf-"} or {gte: 1, lte: 5}
+var operators = map[string]bool{
+	"regex": true, "contains": true, "in": true,
+	"gt": true, "gte": true, "lt": true, "lte": true,
+	"exists": true, "not": true, "cidr_within": true, "length": true, "any_of": true,
+	"any": true, "all": true,
+}
+
+// comparisons maps the numeric operators to their symbols
+var comparisons = map[string]string{"gt": ">", "gte": ">=", "lt": "<", "lte": "<="}
+
+// operatorMatcher returns the operators of an expected value, or false if
+// it is a plain value compared for equality. A map is a matcher only if all
+// its keys are operators.
+func operatorMatcher(expected interface{}) (map[string]interface{}, bool) {
+	ops, ok := expected.(map[string]interface{})
+	if !ok || len(ops) == 0 {
+		return nil, false
+	}
+	for key := range ops {
+		if !operators[key] {
+			return nil, false
+		}
+	}
+	return ops, true
+}
+
+// isQuantifier reports whether a matcher says itself how a list of values
+// must match, with any or all
+func isQuantifier(expected interface{}) bool {
+	ops, ok := operatorMatcher(expected)
+	if !ok {
+		return false
+	}
+	_, hasAny := ops["any"]
+	_, hasAll := ops["all"]
+	return hasAny || hasAll
+}
+
+// ValidateMatcher checks the operators of an expected attribute value, so
+// mistakes like an invalid regex are reported before anything runs
+func ValidateMatcher(expected interface{}) error {
+	ops, ok := operatorMatcher(expected)
+	if !ok {
+		return nil
+	}
+
+	for _, op := range sortedOperators(ops) {
+		arg := ops[op]
+		switch op {
+		case "regex":
+			s, ok := arg.(string)
+			if !ok {
+				return fmt.Errorf("regex requires a string, got %v", arg)
+			}
+			if _, err := regexp.Compile(s); err != nil {
+				return fmt.Errorf("invalid regex %q: %w", s, err)
+			}
+		case "in", "any_of":
+			list, ok := arg.([]interface{})
+			if !ok || len(list) == 0 {
+				return fmt.Errorf("%s requires a non-empty list, got %v", op, arg)
+			}
+			if op == "any_of" {
+				for _, alt := range list {
+					if err := ValidateMatcher(alt); err != nil {
+						return fmt.Errorf("any_of: %w", err)
+					}
+				}
+			}
+		case "gt", "gte", "lt", "lte":
+			if _, ok := toNumber(arg); !ok {
+				return fmt.Errorf("%s requires a number, got %v", op, arg)
+			}
+		case "exists":
+			if _, ok := arg.(bool); !ok {
+				return fmt.Errorf("exists requires true or false, got %v", arg)
+			}
+		case "not", "any", "all":
+			if err := ValidateMatcher(arg); err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+		case "cidr_within":
+			if _, _, err := net.ParseCIDR(fmt.Sprintf("%v", arg)); err != nil {
+				return fmt.Errorf("cidr_within requires a CIDR block, got %v", arg)
+			}
+		case "length":
+			if _, ok := operatorMatcher(arg); ok {
+				if err := ValidateMatcher(arg); err != nil {
+					return fmt.Errorf("length: %w", err)
+				}
+			} else if n, ok := toNumber(arg); !ok || n < 0 {
+				return fmt.Errorf("length requires a non-negative number or a matcher, got %v", arg)
+			}
+		}
+	}
+	return nil
+}
+
+// matchValue checks an attribute value against an expected value. A plain
+// value means equality; every operator of a matcher must hold. found
+// reports whether the attribute exists. It returns the operator that failed
+// ("equals" for plain values) and why, or empty strings on a match.
+func (m *Matcher) matchValue(expected, actual interface{}, found bool) (string, string) {
+	ops, ok := operatorMatcher(expected)
+	if !ok {
+		if !found {
+			return "equals", "attribute not found"
+		}
+		if !m.valuesEqual(expected, actual) {
+			return "equals", fmt.Sprintf("expected %v, got %v", expected, actual)
+		}
+		return "", ""
+	}
+
+	for _, op := range sortedOperators(ops) {
+		if reason := m.checkOperator(op, ops[op], actual, found); reason != "" {
+			return op, reason
+		}
+	}
+	return "", ""
+}
+
+// checkOperator checks a single operator, returning why it doesn't hold
+func (m *Matcher) checkOperator(op string, arg, actual interface{}, found bool) string {
+	switch op {
+	case "exists":
+		want, _ := arg.(bool)
+		if want && !found {
+			return "attribute not found"
+		}
+		if !want && found {
+			return fmt.Sprintf("expected no attribute, got %v", actual)
+		}
+		return ""
+	case "not":
+		if _, reason := m.matchValue(arg, actual, found); reason == "" {
+			return fmt.Sprintf("%v matches %v", actual, arg)
+		}
+		return ""
+	case "any_of":
+		list, _ := arg.([]interface{})
+		reasons := make([]string, 0, len(list))
+		for _, alt := range list {
+			failed, reason := m.matchValue(alt, actual, found)
+			if reason == "" {
+				return ""
+			}
+			reasons = append(reasons, failed+": "+reason)
+		}
+		return fmt.Sprintf("no alternative matched (%s)", strings.Join(reasons, "; "))
+	}
+
+	if !found {
+		return "attribute not found"
+	}
+
+	switch op {
+	case "any", "all":
+		list, ok := actual.([]interface{})
+		if !ok {
+			return fmt.Sprintf("%v is not a list", actual)
+		}
+		for i, item := range list {
+			failed, reason := m.matchValue(arg, item, true)
+			if op == "any" && reason == "" {
+				return ""
+			}
+			if op == "all" && reason != "" {
+				return fmt.Sprintf("value %d: %s: %s", i, failed, reason)
+			}
+		}
+		if op == "any" {
+			return fmt.Sprintf("none of %d value(s) matches %v", len(list), arg)
+		}
+
+	case "regex":
+		re, err := regexp.Compile(fmt.Sprintf("%v", arg))
+		if err != nil {
+			return fmt.Sprintf("invalid regex: %v", err)
+		}
+		if s := fmt.Sprintf("%v", actual); !re.MatchString(s) {
+			return fmt.Sprintf("%q doesn't match %q", s, arg)
+		}
+
+	case "contains":
+		switch v := actual.(type) {
+		case []interface{}:
+			if !m.containsValue(v, arg) {
+				return fmt.Sprintf("%v doesn't contain %v", actual, arg)
+			}
+		case map[string]interface{}:
+			if _, ok := v[fmt.Sprintf("%v", arg)]; !ok {
+				return fmt.Sprintf("no key %v", arg)
+			}
+		default:
+			if !strings.Contains(fmt.Sprintf("%v", actual), fmt.Sprintf("%v", arg)) {
+				return fmt.Sprintf("%q doesn't contain %q", fmt.Sprintf("%v", actual), fmt.Sprintf("%v", arg))
+			}
+		}
+
+	case "in":
+		list, _ := arg.([]interface{})
+		if !m.containsValue(list, actual) {
+			return fmt.Sprintf("%v is not one of %v", actual, arg)
+		}
+
+	case "gt", "gte", "lt", "lte":
+		want, ok := toNumber(arg)
+		if !ok {
+			return fmt.Sprintf("invalid number %v", arg)
+		}
+		got, ok := toNumber(actual)
+		if !ok {
+			return fmt.Sprintf("%v is not a number", actual)
+		}
+		var holds bool
+		switch op {
+		case "gt":
+			holds = got > want
+		case "gte":
+			holds = got >= want
+		case "lt":
+			holds = got < want
+		case "lte":
+			holds = got <= want
+		}
+		if !holds {
+			return fmt.Sprintf("expected %s %v, got %v", comparisons[op], arg, actual)
+		}
+
+	case "cidr_within":
+		_, network, err := net.ParseCIDR(fmt.Sprintf("%v", arg))
+		if err != nil {
+			return fmt.Sprintf("invalid CIDR block %v", arg)
+		}
+		s := fmt.Sprintf("%v", actual)
+		ip, ones := net.ParseIP(s), -1
+		if _, block, err := net.ParseCIDR(s); err == nil {
+			ip = block.IP
+			ones, _ = block.Mask.Size()
+		}
+		if ip == nil {
+			return fmt.Sprintf("%q is not an IP address or CIDR block", s)
+		}
+		networkOnes, _ := network.Mask.Size()
+		if !network.Contains(ip) || ones >= 0 && ones < networkOnes {
+			return fmt.Sprintf("%s is not within %s", s, network)
+		}
+
+	case "length":
+		var n int
+		switch v := actual.(type) {
+		case []interface{}:
+			n = len(v)
+		case map[string]interface{}:
+			n = len(v)
+		case string:
+			n = len(v)
+		default:
+			return fmt.Sprintf("%v has no length", actual)
+		}
+		if failed, reason := m.matchValue(arg, float64(n), true); reason != "" {
+			if failed == "equals" {
+				return fmt.Sprintf("expected length %v, got %d", arg, n)
+			}
+			return fmt.Sprintf("length: %s", reason)
+		}
+	}
+	return ""
+}
+
+// toNumber converts a YAML or JSON number, or a numeric string, to float64
+func toNumber(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// sortedOperators returns the operators of a matcher in a stable order
+func sortedOperators(ops map[string]interface{}) []string {
+	names := make([]string, 0, len(ops))
+	for name := range ops {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,197 @@
+package inventory
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestMatcher_matchValue(t *testing.T) {
+	attrs := map[string]interface{}{
+		"name":       "tf-web-1",
+		"cidr_block": "10.1.2.0/24",
+		"private_ip": "10.1.2.15",
+		"count":      float64(3),
+		"zones":      []interface{}{"eu-west-1a", "eu-west-1b"},
+		"tags":       map[string]interface{}{"Name": "web", "Env": "dev"},
+	}
+	matcher := &Matcher{}
+
+	tests := []struct {
+		attribute    string
+		expected     string // YAML
+		wantOperator string
+		wantReason   string
+	}{
+		{attribute: "name", expected: `tf-web-1`},
+		{attribute: "name", expected: `other`, wantOperator: "equals", wantReason: "expected other, got tf-web-1"},
+		{attribute: "count", expected: `3`},
+		{attribute: "name", expected: `{regex: "^tf-"}`},
+		{attribute: "name", expected: `{regex: "^prod-"}`, wantOperator: "regex", wantReason: `"tf-web-1" doesn't match "^prod-"`},
+		{attribute: "name", expected: `{contains: web}`},
+		{attribute: "zones", expected: `{contains: eu-west-1b}`},
+		{attribute: "zones", expected: `{contains: eu-west-1c}`, wantOperator: "contains", wantReason: "doesn't contain eu-west-1c"},
+		{attribute: "tags", expected: `{contains: Env}`},
+		{attribute: "tags.Env", expected: `{in: [dev, staging]}`},
+		{attribute: "tags.Env", expected: `{in: [prod]}`, wantOperator: "in", wantReason: "dev is not one of [prod]"},
+		{attribute: "count", expected: `{gt: 1, lte: 5}`},
+		{attribute: "count", expected: `{gte: 4}`, wantOperator: "gte", wantReason: "expected >= 4, got 3"},
+		{attribute: "name", expected: `{lt: 4}`, wantOperator: "lt", wantReason: "tf-web-1 is not a number"},
+		{attribute: "name", expected: `{exists: true}`},
+		{attribute: "missing", expected: `{exists: false}`},
+		{attribute: "name", expected: `{exists: false}`, wantOperator: "exists", wantReason: "expected no attribute"},
+		{attribute: "missing", expected: `{regex: x}`, wantOperator: "regex", wantReason: "attribute not found"},
+		{attribute: "tags.Env", expected: `{not: prod}`},
+		{attribute: "tags.Env", expected: `{not: {in: [dev, test]}}`, wantOperator: "not", wantReason: "dev matches"},
+		{attribute: "missing", expected: `{not: {exists: true}}`},
+		{attribute: "cidr_block", expected: `{cidr_within: 10.0.0.0/8}`},
+		{attribute: "private_ip", expected: `{cidr_within: 10.1.2.0/24}`},
+		{attribute: "cidr_block", expected: `{cidr_within: 10.1.2.0/25}`, wantOperator: "cidr_within", wantReason: "10.1.2.0/24 is not within 10.1.2.0/25"},
+		{attribute: "name", expected: `{cidr_within: 10.0.0.0/8}`, wantOperator: "cidr_within", wantReason: "not an IP address or CIDR block"},
+		{attribute: "zones", expected: `{length: 2}`},
+		{attribute: "tags", expected: `{length: {gte: 1}}`},
+		{attribute: "zones", expected: `{length: 3}`, wantOperator: "length", wantReason: "expected length 3, got 2"},
+		{attribute: "zones", expected: `{length: {gt: 2}}`, wantOperator: "length", wantReason: "length: expected > 2, got 2"},
+		{attribute: "tags.Env", expected: `{any_of: [prod, {regex: "^de"}]}`},
+		{attribute: "tags.Env", expected: `{any_of: [prod, {regex: "^st"}]}`, wantOperator: "any_of", wantReason: "no alternative matched (equals: expected prod, got dev; regex:"},
+		{attribute: "tags", expected: `{Name: web, Env: dev}`}, // not operators: compared as a plain value
+		{attribute: "tags", expected: `{Name: web, regex: dev}`, wantOperator: "equals", wantReason: "expected map[Name:web regex:dev]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.attribute+" "+tt.expected, func(t *testing.T) {
+			var expected interface{}
+			if err := yaml.Unmarshal([]byte(tt.expected), &expected); err != nil {
+				t.Fatalf("yaml.Unmarshal() error: %v", err)
+			}
+			if err := ValidateMatcher(expected); err != nil {
+				t.Fatalf("ValidateMatcher() error: %v", err)
+			}
+			actual, found, _ := matcher.getAttribute(attrs, tt.attribute)
+			operator, reason := matcher.matchValue(expected, actual, found)
+			if operator != tt.wantOperator || !strings.Contains(reason, tt.wantReason) || (tt.wantOperator == "") != (reason == "") {
+				t.Errorf("matchValue() = %q, %q, want %q, %q", operator, reason, tt.wantOperator, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestValidateMatcher(t *testing.T) {
+	tests := []struct {
+		expected string // YAML
+		wantErr  string
+	}{
+		{expected: `plain`},
+		{expected: `{Name: web}`},
+		{expected: `{regex: "("}`, wantErr: "invalid regex"},
+		{expected: `{gt: many}`, wantErr: "gt requires a number"},
+		{expected: `{in: []}`, wantErr: "in requires a non-empty list"},
+		{expected: `{exists: yes please}`, wantErr: "exists requires true or false"},
+		{expected: `{cidr_within: 10.0.0.0}`, wantErr: "cidr_within requires a CIDR block"},
+		{expected: `{length: -1}`, wantErr: "length requires a non-negative number"},
+		{expected: `{not: {regex: "["}}`, wantErr: "not: invalid regex"},
+		{expected: `{any_of: [a, {lte: x}]}`, wantErr: "any_of: lte requires a number"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			var expected interface{}
+			if err := yaml.Unmarshal([]byte(tt.expected), &expected); err != nil {
+				t.Fatalf("yaml.Unmarshal() error: %v", err)
+			}
+			err := ValidateMatcher(expected)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateMatcher() error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateMatcher() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMatcher_MatchOperatorMismatch(t *testing.T) {
+	matcher := NewMatcher([]Resource{
+		{Type: "aws_instance", Name: "web", Address: "aws_instance.web", Attributes: map[string]interface{}{"instance_type": "m5.large"}},
+	})
+	results, _ := matcher.Match(map[string]ResourceMatch{
+		"aws_instance.web": {Type: "aws_instance", Name: "web", Attributes: map[string]interface{}{
+			"instance_type": map[string]interface{}{"regex": "^t3\\."},
+		}},
+	})
+
+	result := results["aws_instance.web"]
+	if result.Matched || len(result.Mismatches) != 1 {
+		t.Fatalf("Match() = %+v, want one mismatch", result)
+	}
+	if m := result.Mismatches[0]; m.Operator != "regex" || m.Reason != `"m5.large" doesn't match "^t3\\."` {
+		t.Errorf("Mismatch = %+v, want the failed regex", m)
+	}
+	if want := `aws_instance.web: attribute instance_type: regex failed: "m5.large" doesn't match "^t3\\."`; result.Issues[0] != want {
+		t.Errorf("Issue = %q, want %q", result.Issues[0], want)
+	}
+}
+
+func TestMatcher_MatchAttributePaths(t *testing.T) {
+	var attrs map[string]interface{}
+	err := yaml.Unmarshal([]byte(`
+ingress:
+  - {from_port: 443, to_port: 443, cidr_blocks: [10.0.0.0/8]}
+  - {from_port: 80, to_port: 80, cidr_blocks: [10.0.0.0/8, 0.0.0.0/0]}
+root_block_device: [{volume_size: 50, encrypted: true}]
+tags:
+  Name: eks-node
+  kubernetes.io/cluster/main: owned
+`), &attrs)
+	if err != nil {
+		t.Fatalf("yaml.Unmarshal() error: %v", err)
+	}
+	matcher := NewMatcher([]Resource{{Type: "aws_security_group", Name: "web", Address: "aws_security_group.web", Attributes: attrs}})
+
+	tests := []struct {
+		path     string
+		expected string // YAML
+		wantErr  string
+	}{
+		{path: "ingress[0].from_port", expected: `443`},
+		{path: "ingress[-1].from_port", expected: `80`},
+		{path: "ingress[2].from_port", expected: `443`, wantErr: "attribute ingress[2].from_port not found"},
+		{path: "root_block_device[0].encrypted", expected: `true`},
+		{path: `tags["kubernetes.io/cluster/main"]`, expected: `owned`},
+		{path: `tags['kubernetes.io/cluster/main']`, expected: `{in: [owned, shared]}`},
+		{path: "ingress[*].to_port", expected: `{gte: 80}`},
+		{path: "ingress[*].from_port", expected: `443`, wantErr: "all failed: value 1: equals: expected 443, got 80"},
+		{path: "ingress[*].from_port", expected: `{any: 443}`},
+		{path: "ingress[*].cidr_blocks", expected: `{any: {contains: 0.0.0.0/0}}`},
+		{path: "ingress[*].cidr_blocks", expected: `{all: {contains: 10.0.0.0/8}}`},
+		{path: "ingress[*].cidr_blocks[*]", expected: `{all: {cidr_within: 10.0.0.0/8}}`, wantErr: "value 2: cidr_within: 0.0.0.0/0 is not within 10.0.0.0/8"},
+		{path: "ingress[*].protocol", expected: `{exists: false}`},
+		{path: "ingress[*].protocol", expected: `tcp`, wantErr: "attribute ingress[*].protocol not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path+" "+tt.expected, func(t *testing.T) {
+			var expected interface{}
+			if err := yaml.Unmarshal([]byte(tt.expected), &expected); err != nil {
+				t.Fatalf("yaml.Unmarshal() error: %v", err)
+			}
+			results, _ := matcher.Match(map[string]ResourceMatch{
+				"aws_security_group.web": {Type: "aws_security_group", Name: "web", Attributes: map[string]interface{}{tt.path: expected}},
+			})
+			issues := strings.Join(results["aws_security_group.web"].Issues, "\n")
+			if tt.wantErr == "" {
+				if issues != "" {
+					t.Errorf("Match() issues: %s", issues)
+				}
+				return
+			}
+			if !strings.Contains(issues, tt.wantErr) {
+				t.Errorf("Match() issues = %q, want %q", issues, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package inventory
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Reference points at an attribute of other resources, like aws_vpc.main.id
+type Reference struct {
+	Pattern   string // resource pattern, e.g. aws_vpc.main or module.network.aws_subnet.public*
+	Attribute string // attribute path on the referenced resources, e.g. id
+	Contains  bool   // the attribute is a list that must contain every referenced value
+}
+
+// String renders the reference like it is written, e.g. aws_vpc.main.id
+func (r Reference) String() string {
+	return r.Pattern + "." + r.Attribute
+}
+
+// ParseReference parses a reference expectation: a string like
+// aws_vpc.main.id or data.aws_ami.ubuntu.id, or {contains: aws_security_group.web.id} for list attributes
+func ParseReference(expected interface{}) (Reference, error) {
+	var ref Reference
+	s, ok := expected.(string)
+	if m, isMap := expected.(map[string]interface{}); isMap && len(m) == 1 {
+		s, ok = m["contains"].(string)
+		ref.Contains = true
+	}
+	if !ok {
+		return Reference{}, fmt.Errorf("invalid reference %v (expected type.name.attribute or {contains: type.name.attribute})", expected)
+	}
+
+	// The pattern is the module path, type and name; the rest is the attribute
+	rest := s
+	var parts []string
+	for strings.HasPrefix(rest, "module.") {
+		moduleName, tail, _ := strings.Cut(strings.TrimPrefix(rest, "module."), ".")
+		parts = append(parts, "module", moduleName)
+		rest = tail
+	}
+	if strings.HasPrefix(rest, "data.") {
+		parts = append(parts, "data")
+		rest = strings.TrimPrefix(rest, "data.")
+	}
+	resourceType, tail, _ := strings.Cut(rest, ".")
+	name, attribute, _ := strings.Cut(tail, ".")
+	parts = append(parts, resourceType, name)
+
+	ref.Pattern = strings.Join(parts, ".")
+	ref.Attribute = attribute
+	if _, _, _, err := ParsePattern(ref.Pattern); err != nil || attribute == "" {
+		return Reference{}, fmt.Errorf("invalid reference %s (expected type.name.attribute, e.g. aws_vpc.main.id)", s)
+	}
+	return ref, nil
+}
+
+// resolve returns the values a reference points at, from every resource
+// its pattern matches
+func (m *Matcher) resolve(ref Reference) ([]interface{}, error) {
+	module, resourceType, name, err := ParsePattern(ref.Pattern)
+	if err != nil {
+		return nil, err
+	}
+	matched := m.matchPattern(ref.Pattern, ResourceMatch{Module: module, Type: resourceType, Name: name})
+	if matched.Count == 0 {
+		return nil, fmt.Errorf("no resource matches %s", ref.Pattern)
+	}
+
+	var values []interface{}
+	for _, res := range matched.Resources {
+		value, found, wildcard := m.getAttribute(res.Attributes, ref.Attribute)
+		if !found {
+			return nil, fmt.Errorf("%s has no attribute %s", res.Address, ref.Attribute)
+		}
+		if wildcard {
+			values = append(values, value.([]interface{})...)
+		} else {
+			values = append(values, value)
+		}
+	}
+	return values, nil
+}
+
+// checkReference checks an attribute value against a reference, returning
+// why it doesn't hold. Wildcard attribute paths check every selected value.
+func (m *Matcher) checkReference(ref Reference, actual interface{}, found, wildcard bool) string {
+	if !found {
+		return "attribute not found"
+	}
+	values, err := m.resolve(ref)
+	if err != nil {
+		return err.Error()
+	}
+
+	if ref.Contains {
+		list, ok := actual.([]interface{})
+		if !ok {
+			return fmt.Sprintf("%v is not a list", actual)
+		}
+		for _, v := range values {
+			if !m.containsValue(list, v) {
+				return fmt.Sprintf("%v doesn't contain %s (%v)", actual, ref, v)
+			}
+		}
+		return ""
+	}
+
+	candidates := []interface{}{actual}
+	if wildcard {
+		candidates = actual.([]interface{})
+	}
+	for _, c := range candidates {
+		if m.containsValue(values, c) {
+			continue
+		}
+		if len(values) == 1 {
+			return fmt.Sprintf("%v is not %s (%v)", c, ref, values[0])
+		}
+		return fmt.Sprintf("%v is not one of %s (%v)", c, ref, values)
+	}
+	return ""
+}
+
+// sortedReferences returns the attribute paths of references in order
+func sortedReferences(refs map[string]Reference) []string {
+	paths := make([]string, 0, len(refs))
+	for path := range refs {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// containsValue reports whether a list has an element equal to the value
+func (m *Matcher) containsValue(list []interface{}, value interface{}) bool {
+	for _, item := range list {
+		if m.valuesEqual(value, item) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package inventory
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		expected interface{}
+		want     Reference
+		wantErr  bool
+	}{
+		{expected: "aws_vpc.main.id", want: Reference{Pattern: "aws_vpc.main", Attribute: "id"}},
+		{expected: "aws_subnet.public*.id", want: Reference{Pattern: "aws_subnet.public*", Attribute: "id"}},
+		{expected: "module.network.aws_vpc.main.tags.Name", want: Reference{Pattern: "module.network.aws_vpc.main", Attribute: "tags.Name"}},
+		{expected: "data.aws_ami.ubuntu.id", want: Reference{Pattern: "data.aws_ami.ubuntu", Attribute: "id"}},
+		{expected: "module.network.data.aws_availability_zones.available.names", want: Reference{Pattern: "module.network.data.aws_availability_zones.available", Attribute: "names"}},
+		{expected: map[string]interface{}{"contains": "aws_security_group.web.id"}, want: Reference{Pattern: "aws_security_group.web", Attribute: "id", Contains: true}},
+		{expected: "aws_vpc.main", wantErr: true},
+		{expected: "module.network.aws_vpc", wantErr: true},
+		{expected: map[string]interface{}{"in": "aws_vpc.main.id"}, wantErr: true},
+		{expected: 42, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseReference(tt.expected)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseReference(%v) error = %v, wantErr %v", tt.expected, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseReference(%v) = %+v, want %+v", tt.expected, got, tt.want)
+		}
+	}
+}
+
+func TestMatcher_MatchReferences(t *testing.T) {
+	matcher := NewMatcher([]Resource{
+		{Type: "aws_vpc", Name: "main", Address: "aws_vpc.main", Attributes: map[string]interface{}{"id": "vpc-1"}},
+		{Type: "aws_subnet", Name: "public_a", Address: "aws_subnet.public_a", Attributes: map[string]interface{}{"id": "subnet-1", "vpc_id": "vpc-1"}},
+		{Type: "aws_subnet", Name: "public_b", Address: "aws_subnet.public_b", Attributes: map[string]interface{}{"id": "subnet-2", "vpc_id": "vpc-1"}},
+		{Type: "aws_subnet", Name: "private_a", Address: "aws_subnet.private_a", Attributes: map[string]interface{}{"id": "subnet-3", "vpc_id": "vpc-9"}},
+		{Type: "aws_security_group", Name: "web", Address: "aws_security_group.web", Attributes: map[string]interface{}{"id": "sg-1"}},
+		{Type: "aws_ami", Name: "ubuntu", Address: "data.aws_ami.ubuntu", Mode: "data", Attributes: map[string]interface{}{"id": "ami-1"}},
+		{Type: "aws_instance", Name: "web", Address: "aws_instance.web", Attributes: map[string]interface{}{
+			"ami":                    "ami-1",
+			"subnet_id":              "subnet-3",
+			"vpc_security_group_ids": []interface{}{"sg-1"},
+			"network_interface":      []interface{}{map[string]interface{}{"subnet_id": "subnet-1"}},
+		}},
+	})
+
+	tests := []struct {
+		name       string
+		pattern    string
+		references map[string]interface{}
+		wantIssue  string
+	}{
+		{name: "equal", pattern: "aws_subnet.public*", references: map[string]interface{}{"vpc_id": "aws_vpc.main.id"}},
+		{name: "not equal", pattern: "aws_subnet.*", references: map[string]interface{}{"vpc_id": "aws_vpc.main.id"},
+			wantIssue: "aws_subnet.private_a: attribute vpc_id: references failed: vpc-9 is not aws_vpc.main.id (vpc-1)"},
+		{name: "one of", pattern: "aws_instance.web", references: map[string]interface{}{"subnet_id": "aws_subnet.public*.id"},
+			wantIssue: "subnet-3 is not one of aws_subnet.public*.id ([subnet-1 subnet-2])"},
+		{name: "data source", pattern: "aws_instance.web", references: map[string]interface{}{"ami": "data.aws_ami.ubuntu.id"}},
+		{name: "wildcard path", pattern: "aws_instance.web", references: map[string]interface{}{"network_interface[*].subnet_id": "aws_subnet.public*.id"}},
+		{name: "contains", pattern: "aws_instance.web", references: map[string]interface{}{"vpc_security_group_ids": map[string]interface{}{"contains": "aws_security_group.web.id"}}},
+		{name: "contains every", pattern: "aws_instance.web", references: map[string]interface{}{"vpc_security_group_ids": map[string]interface{}{"contains": "aws_subnet.public_a.id"}},
+			wantIssue: "[sg-1] doesn't contain aws_subnet.public_a.id (subnet-1)"},
+		{name: "no referenced resource", pattern: "aws_subnet.public_a", references: map[string]interface{}{"vpc_id": "aws_vpc.other.id"},
+			wantIssue: "no resource matches aws_vpc.other"},
+		{name: "referenced attribute missing", pattern: "aws_subnet.public_a", references: map[string]interface{}{"vpc_id": "aws_vpc.main.arn"},
+			wantIssue: "aws_vpc.main has no attribute arn"},
+		{name: "attribute missing", pattern: "aws_vpc.main", references: map[string]interface{}{"vpc_id": "aws_vpc.main.id"},
+			wantIssue: "aws_vpc.main: attribute vpc_id: references failed: attribute not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module, resourceType, name, err := ParsePattern(tt.pattern)
+			if err != nil {
+				t.Fatalf("ParsePattern() error: %v", err)
+			}
+			refs := make(map[string]Reference)
+			for path, expected := range tt.references {
+				if refs[path], err = ParseReference(expected); err != nil {
+					t.Fatalf("ParseReference() error: %v", err)
+				}
+			}
+			results, _ := matcher.Match(map[string]ResourceMatch{
+				tt.pattern: {Module: module, Type: resourceType, Name: name, References: refs},
+			})
+			result := results[tt.pattern]
+			issues := strings.Join(result.Issues, "\n")
+			if tt.wantIssue == "" {
+				if !result.Matched {
+					t.Errorf("Match() issues: %s", issues)
+				}
+				return
+			}
+			if !strings.Contains(issues, tt.wantIssue) {
+				t.Errorf("Match() issues = %q, want %q", issues, tt.wantIssue)
+			}
+			if len(result.Mismatches) == 0 || result.Mismatches[0].Operator != "references" {
+				t.Errorf("Mismatches = %+v, want a references mismatch", result.Mismatches)
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+// Package jsonpath evaluates a small JSONPath dialect against decoded JSON
+// (map[string]interface{} / []interface{} trees).
+//
+// Supported syntax:
+//   - "$" root, optional: "$.token" and "token" are equivalent
+//   - ".key" and bare "key" for map keys
+//   - "['key']" or "[\"key\"]" for keys containing dots or other special characters
+//   - "[0]" for list indexes, "[-1]" counting from the end
+//   - "[*]" or ".*" to select every element of a list or map
+package jsonpath
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SegmentKind identifies the type of a path segment
+type SegmentKind int
+
+const (
+	// Key selects a map key
+	Key SegmentKind = iota
+	// Index selects a list element
+	Index
+	// Wildcard selects every element of a list or map
+	Wildcard
+)
+
+// Segment is a single step of a parsed path
+type Segment struct {
+	Kind  SegmentKind
+	Key   string
+	Index int
+}
+
+// String renders the segment in path syntax
+func (s Segment) String() string {
+	switch s.Kind {
+	case Index:
+		return fmt.Sprintf("[%d]", s.Index)
+	case Wildcard:
+		return "[*]"
+	default:
+		if strings.ContainsAny(s.Key, ".[]'\" ") {
+			return fmt.Sprintf("[%q]", s.Key)
+		}
+		return "." + s.Key
+	}
+}
+
+// Parse splits a path into segments
+func Parse(path string) ([]Segment, error) {
+	p := strings.TrimSpace(path)
+	p = strings.TrimPrefix(p, "$")
+
+	var segments []Segment
+	i := 0
+	for i < len(p) {
+		switch p[i] {
+		case '.':
+			i++
+			if i < len(p) && p[i] == '*' {
+				segments = append(segments, Segment{Kind: Wildcard})
+				i++
+				continue
+			}
+			key, n := readKey(p[i:])
+			if key == "" {
+				return nil, fmt.Errorf("invalid path %q: empty key at position %d", path, i)
+			}
+			segments = append(segments, Segment{Kind: Key, Key: key})
+			i += n
+
+		case '[':
+			end, seg, err := readBracket(p, i)
+			if err != nil {
+				return nil, fmt.Errorf("invalid path %q: %w", path, err)
+			}
+			segments = append(segments, seg)
+			i = end
+
+		default:
+			// A bare key at the start of the path, e.g. "tags.Name"
+			if len(segments) > 0 {
+				return nil, fmt.Errorf("invalid path %q: unexpected %q at position %d", path, p[i], i)
+			}
+			key, n := readKey(p[i:])
+			if key == "*" {
+				segments = append(segments, Segment{Kind: Wildcard})
+			} else {
+				segments = append(segments, Segment{Kind: Key, Key: key})
+			}
+			i += n
+		}
+	}
+
+	return segments, nil
+}
+
+// readKey reads an unquoted key up to the next '.' or '['
+func readKey(s string) (string, int) {
+	n := strings.IndexAny(s, ".[")
+	if n < 0 {
+		n = len(s)
+	}
+	return s[:n], n
+}
+
+// readBracket parses a [..] segment starting at p[start]
+func readBracket(p string, start int) (int, Segment, error) {
+	i := start + 1
+	if i >= len(p) {
+		return 0, Segment{}, fmt.Errorf("unterminated '[' at position %d", start)
+	}
+
+	// Quoted key: ['key'] or ["key"]
+	if p[i] == '\'' || p[i] == '"' {
+		quote := p[i]
+		var key strings.Builder
+		j := i + 1
+		for ; j < len(p) && p[j] != quote; j++ {
+			if p[j] == '\\' && j+1 < len(p) {
+				j++
+			}
+			key.WriteByte(p[j])
+		}
+		if j+1 >= len(p) || p[j+1] != ']' {
+			return 0, Segment{}, fmt.Errorf("unterminated quoted key at position %d", start)
+		}
+		return j + 2, Segment{Kind: Key, Key: key.String()}, nil
+	}
+
+	end := strings.IndexByte(p[i:], ']')
+	if end < 0 {
+		return 0, Segment{}, fmt.Errorf("unterminated '[' at position %d", start)
+	}
+	inner := strings.TrimSpace(p[i : i+end])
+	if inner == "*" {
+		return i + end + 1, Segment{Kind: Wildcard}, nil
+	}
+	idx, err := strconv.Atoi(inner)
+	if err != nil {
+		return 0, Segment{}, fmt.Errorf("invalid index %q at position %d", inner, start)
+	}
+	return i + end + 1, Segment{Kind: Index, Index: idx}, nil
+}
+
+// HasWildcard reports whether the path selects multiple values
+func HasWildcard(segments []Segment) bool {
+	for _, s := range segments {
+		if s.Kind == Wildcard {
+			return true
+		}
+	}
+	return false
+}
+
+// Get evaluates a path against a document. Paths containing a wildcard return
+// a []interface{} of every matched value; other paths return the single value.
+func Get(doc interface{}, path string) (interface{}, error) {
+	segments, err := Parse(path)
+	if err != nil {
+		return nil, err
+	}
+	values, err := Select(doc, segments)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if HasWildcard(segments) {
+		return values, nil
+	}
+	return values[0], nil
+}
+
+// Select evaluates parsed segments against a document and returns every
+// matched value. Without wildcards a missing key or index is an error; under
+// a wildcard, elements that lack the rest of the path are left out.
+func Select(doc interface{}, segments []Segment) ([]interface{}, error) {
+	current := []interface{}{doc}
+	wildcard := false
+
+	for _, seg := range segments {
+		var next []interface{}
+		for _, val := range current {
+			matched, err := step(val, seg)
+			if err != nil {
+				if wildcard {
+					continue
+				}
+				return nil, err
+			}
+			next = append(next, matched...)
+		}
+		if seg.Kind == Wildcard {
+			wildcard = true
+		}
+		current = next
+	}
+
+	return current, nil
+}
+
+// step applies a single segment to a value
+func step(val interface{}, seg Segment) ([]interface{}, error) {
+	switch seg.Kind {
+	case Key:
+		m, ok := val.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("cannot read key %q from %s", seg.Key, describe(val))
+		}
+		v, exists := m[seg.Key]
+		if !exists {
+			return nil, fmt.Errorf("key %q not found", seg.Key)
+		}
+		return []interface{}{v}, nil
+
+	case Index:
+		list, ok := val.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("cannot index %s", describe(val))
+		}
+		idx := seg.Index
+		if idx < 0 {
+			idx += len(list)
+		}
+		if idx < 0 || idx >= len(list) {
+			return nil, fmt.Errorf("index %d out of bounds (length: %d)", seg.Index, len(list))
+		}
+		return []interface{}{list[idx]}, nil
+
+	default:
+		switch v := val.(type) {
+		case []interface{}:
+			return v, nil
+		case map[string]interface{}:
+			// Sort keys so wildcard results are deterministic
+			keys := make([]string, 0, len(v))
+			for k := range v {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			out := make([]interface{}, len(keys))
+			for i, k := range keys {
+				out[i] = v[k]
+			}
+			return out, nil
+		default:
+			return nil, fmt.Errorf("cannot expand %s with a wildcard", describe(val))
+		}
+	}
+}
+
+// describe returns a short description of a value's type for error messages
+func describe(val interface{}) string {
+	switch val.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "an object"
+	case []interface{}:
+		return "a list"
+	case string:
+		return "a string"
+	case float64, int, int64:
+		return "a number"
+	case bool:
+		return "a boolean"
+	default:
+		return fmt.Sprintf("%T", val)
+	}
+}
@@ -0,0 +1,73 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	var doc interface{}
+	if err := json.Unmarshal([]byte(`{
+		"token": "abc123",
+		"user": {"id": 7, "roles": ["admin", "dev"]},
+		"items": [{"name": "a", "port": 80}, {"name": "b", "port": 443}, {"name": "c"}],
+		"tags": {"kubernetes.io/cluster/x": "owned", "Name": "web"}
+	}`), &doc); err != nil {
+		t.Fatalf("Failed to decode fixture: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want interface{}
+	}{
+		{"root key", "$.token", "abc123"},
+		{"without root", "token", "abc123"},
+		{"nested", "$.user.id", float64(7)},
+		{"index", "$.user.roles[1]", "dev"},
+		{"negative index", "user.roles[-1]", "dev"},
+		{"index then key", "items[0].name", "a"},
+		{"quoted key", `tags["kubernetes.io/cluster/x"]`, "owned"},
+		{"single quoted key", `$['tags']['Name']`, "web"},
+		{"wildcard", "items[*].name", []interface{}{"a", "b", "c"}},
+		{"wildcard skips missing", "$.items[*].port", []interface{}{float64(80), float64(443)}},
+		{"dot wildcard", "tags.*", []interface{}{"web", "owned"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Get(doc, tt.path)
+			if err != nil {
+				t.Fatalf("Get(%q) error: %v", tt.path, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Get(%q) = %#v, want %#v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetErrors(t *testing.T) {
+	doc := map[string]interface{}{
+		"list":  []interface{}{"a"},
+		"value": "x",
+	}
+
+	tests := []string{
+		"$.missing",
+		"list[3]",
+		"value.nested",
+		"value[0]",
+		"list[abc]",
+		"list[0",
+		`list["unterminated]`,
+		"$.",
+	}
+
+	for _, path := range tests {
+		if _, err := Get(doc, path); err == nil {
+			t.Errorf("Get(%q) expected error", path)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package network
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"time"
+)
+
+const (
+	// dialTimeout bounds a single connection attempt
+	dialTimeout = 5 * time.Second
+	// maxBannerSize caps how much of what the server sends is kept
+	maxBannerSize = 64 << 10
+)
+
+// readTimeout bounds how long to wait for a banner or UDP reply
+var readTimeout = 5 * time.Second
+
+// Check describes a connectivity check against a single port
+type Check struct {
+	Network   string // tcp or udp
+	Address   string // host:port
+	Reachable bool   // whether the port should accept connections
+	Send      []byte // payload written after connecting
+	Banner    string // substring the server must send after connecting (or reply, for udp)
+}
+
+// Result is the outcome of a single connection attempt
+type Result struct {
+	Reachable bool
+	Latency   time.Duration // time to connect (tcp) or to the first reply (udp)
+	Banner    []byte        // what the server sent after connecting
+	Reason    string        // why the port is unreachable
+}
+
+// CheckWithRetryContext dials the address until the check's expectations hold,
+// retrying like http.CheckWithRetryContext, and returns the last result
+func CheckWithRetryContext(ctx context.Context, check Check, retries int, delay time.Duration, debug bool) (Result, error) {
+	var last Result
+	var lastErr error
+
+	for i := 0; i <= retries; i++ {
+		if debug && i > 0 {
+			fmt.Printf("[DEBUG] %s check retry %d/%d for %s\n", check.Network, i, retries, check.Address)
+		}
+
+		res, err := DialContext(ctx, check)
+		if ctx.Err() != nil {
+			return last, fmt.Errorf("%s check cancelled: %w", check.Network, ctx.Err())
+		}
+		if err == nil {
+			last = res
+			err = check.verify(res)
+		}
+		if err == nil {
+			return res, nil
+		}
+		lastErr = err
+
+		if i < retries {
+			if err := sleep(ctx, check.Network, delay); err != nil {
+				return last, err
+			}
+		}
+	}
+
+	if retries > 0 {
+		return last, fmt.Errorf("%s check failed after %d retries: %w", check.Network, retries, lastErr)
+	}
+	return last, lastErr
+}
+
+// verify checks a result against the check's expectations
+func (c Check) verify(res Result) error {
+	if !c.Reachable {
+		if res.Reachable {
+			return fmt.Errorf("%s %s is reachable, expected it not to be", c.Network, c.Address)
+		}
+		return nil
+	}
+	if !res.Reachable {
+		return fmt.Errorf("%s %s is not reachable: %s", c.Network, c.Address, res.Reason)
+	}
+	if c.Banner != "" && !bytes.Contains(res.Banner, []byte(c.Banner)) {
+		return fmt.Errorf("%s %s: banner does not contain %q (got %q)", c.Network, c.Address, c.Banner, truncate(res.Banner))
+	}
+	return nil
+}
+
+// DialContext makes a single connection attempt. A closed or filtered port is
+// reported as unreachable in the result; errors are for attempts that couldn't
+// be made, e.g. because the host doesn't resolve.
+func DialContext(ctx context.Context, check Check) (Result, error) {
+	dialer := net.Dialer{Timeout: dialTimeout}
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, check.Network, check.Address)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) || ctx.Err() != nil {
+			return Result{}, err
+		}
+		return Result{Reason: err.Error()}, nil
+	}
+	defer conn.Close()
+
+	if check.Network == "udp" {
+		return exchangeUDP(conn, check)
+	}
+
+	res := Result{Reachable: true, Latency: time.Since(start)}
+	if len(check.Send) > 0 {
+		if _, err := conn.Write(check.Send); err != nil {
+			return res, fmt.Errorf("failed to send payload: %w", err)
+		}
+	}
+	if check.Banner != "" {
+		res.Banner = readBanner(conn, check.Banner)
+	}
+	return res, nil
+}
+
+// exchangeUDP sends the payload and waits for a reply. UDP has no handshake,
+// so a silent port may be open or filtered: it counts as reachable when the
+// check expects a reachable port, unless a banner is expected, and as
+// unreachable when the check expects it not to be.
+func exchangeUDP(conn net.Conn, check Check) (Result, error) {
+	start := time.Now()
+	if _, err := conn.Write(check.Send); err != nil {
+		if isRefused(err) {
+			return Result{Reason: err.Error()}, nil
+		}
+		return Result{}, fmt.Errorf("failed to send payload: %w", err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(readTimeout))
+	buf := make([]byte, maxBannerSize)
+	n, err := conn.Read(buf)
+	switch {
+	case err == nil:
+		return Result{Reachable: true, Latency: time.Since(start), Banner: buf[:n]}, nil
+	case isRefused(err):
+		return Result{Reason: err.Error()}, nil
+	case errors.Is(err, os.ErrDeadlineExceeded):
+		if !check.Reachable {
+			return Result{Reason: "no reply (closed or filtered)"}, nil
+		}
+		return Result{Reachable: true}, nil
+	default:
+		return Result{}, err
+	}
+}
+
+// readBanner reads what the server sends until it contains the expected
+// substring, the server stops sending or the read deadline passes
+func readBanner(conn net.Conn, want string) []byte {
+	conn.SetReadDeadline(time.Now().Add(readTimeout))
+	var banner []byte
+	buf := make([]byte, 4096)
+	for len(banner) < maxBannerSize && !bytes.Contains(banner, []byte(want)) {
+		n, err := conn.Read(buf)
+		banner = append(banner, buf[:n]...)
+		if err != nil {
+			break
+		}
+	}
+	return banner
+}
+
+// isRefused reports whether the peer rejected the connection or packet
+func isRefused(err error) bool {
+	return errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// truncate shortens a banner for error messages
+func truncate(banner []byte) []byte {
+	if len(banner) > 100 {
+		return append(banner[:100:100], "..."...)
+	}
+	return banner
+}
+
+// sleep waits for the retry delay unless the context is done first
+func sleep(ctx context.Context, network string, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("%s check cancelled: %w", network, ctx.Err())
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,147 @@
+package network
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// closedAddress returns an address nothing is listening on
+func closedAddress(t *testing.T, network string) string {
+	t.Helper()
+	if network == "udp" {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("net.ListenPacket() error: %v", err)
+		}
+		defer conn.Close()
+		return conn.LocalAddr().String()
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error: %v", err)
+	}
+	defer listener.Close()
+	return listener.Addr().String()
+}
+
+func TestCheckWithRetryContextTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write([]byte("SSH-2.0-OpenSSH_9.6\r\n"))
+			conn.Close()
+		}
+	}()
+	open := listener.Addr().String()
+	closed := closedAddress(t, "tcp")
+
+	tests := []struct {
+		name    string
+		check   Check
+		wantErr string
+	}{
+		{name: "reachable", check: Check{Network: "tcp", Address: open, Reachable: true}},
+		{name: "banner", check: Check{Network: "tcp", Address: open, Reachable: true, Banner: "SSH-2.0"}},
+		{name: "banner mismatch", check: Check{Network: "tcp", Address: open, Reachable: true, Banner: "220 smtp"}, wantErr: `banner does not contain "220 smtp"`},
+		{name: "unreachable", check: Check{Network: "tcp", Address: closed, Reachable: true}, wantErr: "is not reachable"},
+		{name: "expected unreachable", check: Check{Network: "tcp", Address: closed}},
+		{name: "unexpectedly reachable", check: Check{Network: "tcp", Address: open}, wantErr: "is reachable, expected it not to be"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := CheckWithRetryContext(context.Background(), tt.check, 1, time.Millisecond, false)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("CheckWithRetryContext() error: %v", err)
+				}
+				if tt.check.Reachable && res.Latency <= 0 {
+					t.Errorf("Expected connect latency to be recorded, got %s", res.Latency)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("CheckWithRetryContext() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckWithRetryContextUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket() error: %v", err)
+	}
+	defer conn.Close()
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			conn.WriteTo(append([]byte("PONG "), buf[:n]...), addr)
+		}
+	}()
+	echo := conn.LocalAddr().String()
+
+	res, err := CheckWithRetryContext(context.Background(), Check{Network: "udp", Address: echo, Reachable: true, Send: []byte("PING"), Banner: "PONG"}, 0, 0, false)
+	if err != nil {
+		t.Fatalf("CheckWithRetryContext() error: %v", err)
+	}
+	if string(res.Banner) != "PONG PING" || res.Latency <= 0 {
+		t.Errorf("Unexpected result: %+v", res)
+	}
+
+	if _, err := CheckWithRetryContext(context.Background(), Check{Network: "udp", Address: closedAddress(t, "udp"), Send: []byte("PING")}, 0, 0, false); err != nil {
+		t.Errorf("Expected closed UDP port to be unreachable, got %v", err)
+	}
+}
+
+func TestCheckWithRetryContextUDPSilent(t *testing.T) {
+	defer func(d time.Duration) { readTimeout = d }(readTimeout)
+	readTimeout = 50 * time.Millisecond
+
+	// A port that drops packets, like a firewall, never replies
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket() error: %v", err)
+	}
+	defer conn.Close()
+	silent := conn.LocalAddr().String()
+
+	res, err := CheckWithRetryContext(context.Background(), Check{Network: "udp", Address: silent, Send: []byte("PING")}, 0, 0, false)
+	if err != nil || res.Reason != "no reply (closed or filtered)" {
+		t.Errorf("Expected silent UDP port to be unreachable, got %+v, %v", res, err)
+	}
+
+	if _, err := CheckWithRetryContext(context.Background(), Check{Network: "udp", Address: silent, Reachable: true, Send: []byte("PING")}, 0, 0, false); err != nil {
+		t.Errorf("Expected silent UDP port to pass a reachable check without banner, got %v", err)
+	}
+
+	_, err = CheckWithRetryContext(context.Background(), Check{Network: "udp", Address: silent, Reachable: true, Send: []byte("PING"), Banner: "PONG"}, 0, 0, false)
+	if err == nil || !strings.Contains(err.Error(), "banner does not contain") {
+		t.Errorf("Expected missing reply to fail the banner check, got %v", err)
+	}
+}
+
+func TestCheckWithRetryContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := CheckWithRetryContext(ctx, Check{Network: "tcp", Address: closedAddress(t, "tcp"), Reachable: true}, 3, time.Second, false)
+	if err == nil || !strings.Contains(err.Error(), "cancelled") {
+		t.Errorf("Expected cancellation error, got %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package reporting
 import (
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 	"time"
 )
@@ -12,6 +13,7 @@ type FlowInfo struct {
 	Name        string
 	Description string
 	WorkingDir  string
+	Matrix      string `json:",omitempty"` // matrix values of this run, e.g. "region=us-east-1"
 }
 
 // StepResultInfo contains step result data for reporting
@@ -24,6 +26,21 @@ type StepResultInfo struct {
 	Duration   time.Duration
 	Resources  []ResourceInfo
 	HTTPStatus int
+	Latency    time.Duration // tcp/udp connect latency
+	Records    []string      // records resolved by a dns step
+	Attempts   []AttemptInfo
+	TimedOut   bool
+	Skipped    bool
+	SkipReason string
+	Captured   map[string]interface{} // captured values, with sensitive ones already masked
+}
+
+// AttemptInfo contains data about a single try of a step
+type AttemptInfo struct {
+	Number   int
+	Success  bool
+	Error    error
+	Duration time.Duration
 }
 
 // ResourceInfo contains resource data for reporting
@@ -47,16 +64,21 @@ func GenerateHTMLReport(f FlowInfo, results []StepResultInfo, outputPath string,
         .step { margin: 15px 0; padding: 15px; border-left: 4px solid #ddd; background: #fafafa; border-radius: 4px; }
         .step.success { border-left-color: #4CAF50; }
         .step.failure { border-left-color: #f44336; }
+        .step.skipped { border-left-color: #9e9e9e; }
+        .step.timeout { border-left-color: #ff9800; }
         .step-header { font-weight: bold; font-size: 1.1em; margin-bottom: 10px; }
         .step-type { color: #666; font-size: 0.9em; }
         .step-duration { color: #888; font-size: 0.85em; }
         .error { color: #f44336; background: #ffebee; padding: 10px; border-radius: 4px; margin-top: 10px; }
         .output { background: #263238; color: #aed581; padding: 10px; border-radius: 4px; font-family: monospace; font-size: 0.9em; overflow-x: auto; margin-top: 10px; }
         .resources { margin-top: 10px; }
+        .attempts, .captured { margin-top: 10px; font-size: 0.9em; color: #555; }
         .resource { display: inline-block; background: #e3f2fd; padding: 5px 10px; margin: 5px; border-radius: 3px; font-size: 0.9em; }
         .status-badge { display: inline-block; padding: 3px 8px; border-radius: 3px; font-size: 0.85em; font-weight: bold; margin-left: 10px; }
         .status-success { background: #4CAF50; color: white; }
         .status-failure { background: #f44336; color: white; }
+        .status-skipped { background: #9e9e9e; color: white; }
+        .status-timeout { background: #ff9800; color: white; }
     </style>
 </head>
 <body>
@@ -65,16 +87,27 @@ func GenerateHTMLReport(f FlowInfo, results []StepResultInfo, outputPath string,
         <div class="summary">
             <p><strong>Description:</strong> ` + escapeHTML(f.Description) + `</p>
             <p><strong>Working Directory:</strong> ` + escapeHTML(f.WorkingDir) + `</p>
-            <p><strong>Generated:</strong> ` + time.Now().Format(time.RFC3339) + `</p>
+`
+	if f.Matrix != "" {
+		html += `            <p><strong>Matrix:</strong> ` + escapeHTML(f.Matrix) + `</p>
+`
+	}
+	html += `            <p><strong>Generated:</strong> ` + time.Now().Format(time.RFC3339) + `</p>
         </div>
 `
 
 	// Calculate summary
 	successCount := 0
 	failureCount := 0
+	skippedCount := 0
+	timedOutCount := 0
 	totalDuration := time.Duration(0)
 	for _, r := range results {
-		if r.Success {
+		if r.Skipped {
+			skippedCount++
+		} else if r.TimedOut {
+			timedOutCount++
+		} else if r.Success {
 			successCount++
 		} else {
 			failureCount++
@@ -88,10 +121,12 @@ func GenerateHTMLReport(f FlowInfo, results []StepResultInfo, outputPath string,
             <p><strong>Total Steps:</strong> %d</p>
             <p><strong>Successful:</strong> <span style="color: #4CAF50;">%d</span></p>
             <p><strong>Failed:</strong> <span style="color: #f44336;">%d</span></p>
+            <p><strong>Timed Out:</strong> <span style="color: #ff9800;">%d</span></p>
+            <p><strong>Skipped:</strong> <span style="color: #9e9e9e;">%d</span></p>
             <p><strong>Total Duration:</strong> %s</p>
         </div>
         <h2>Terraform Outputs</h2>
-`, len(results), successCount, failureCount, totalDuration.Round(time.Millisecond))
+`, len(results), successCount, failureCount, timedOutCount, skippedCount, totalDuration.Round(time.Millisecond))
 	
 	// Add outputs table if available
 	if outputs != nil && len(outputs) > 0 {
@@ -132,7 +167,13 @@ func GenerateHTMLReport(f FlowInfo, results []StepResultInfo, outputPath string,
 	for _, result := range results {
 		statusClass := "success"
 		statusBadge := `<span class="status-badge status-success">SUCCESS</span>`
-		if !result.Success {
+		if result.Skipped {
+			statusClass = "skipped"
+			statusBadge = `<span class="status-badge status-skipped">SKIPPED</span>`
+		} else if result.TimedOut {
+			statusClass = "timeout"
+			statusBadge = `<span class="status-badge status-timeout">TIMED OUT</span>`
+		} else if !result.Success {
 			statusClass = "failure"
 			statusBadge = `<span class="status-badge status-failure">FAILED</span>`
 		}
@@ -146,7 +187,9 @@ func GenerateHTMLReport(f FlowInfo, results []StepResultInfo, outputPath string,
             <div class="step-duration">Duration: %s</div>
 `, statusClass, escapeHTML(result.StepName), statusBadge, escapeHTML(result.StepType), result.Duration.Round(time.Millisecond))
 
-		if result.Error != nil {
+		if result.Skipped {
+			html += fmt.Sprintf(`            <div class="step-type">Skipped: %s</div>`, escapeHTML(result.SkipReason))
+		} else if result.Error != nil {
 			html += fmt.Sprintf(`            <div class="error">Error: %s</div>`, escapeHTML(result.Error.Error()))
 		} else if !result.Success {
 			html += fmt.Sprintf(`            <div class="error">Step failed</div>`)
@@ -168,6 +211,39 @@ func GenerateHTMLReport(f FlowInfo, results []StepResultInfo, outputPath string,
 			html += fmt.Sprintf(`            <div>HTTP Status: %d</div>`, result.HTTPStatus)
 		}
 
+		if len(result.Records) > 0 {
+			html += fmt.Sprintf(`            <div>Records: %s</div>`, escapeHTML(strings.Join(result.Records, ", ")))
+		}
+
+		if result.Latency > 0 {
+			html += fmt.Sprintf(`            <div>Latency: %s</div>`, result.Latency.Round(time.Microsecond))
+		}
+
+		if len(result.Captured) > 0 {
+			names := make([]string, 0, len(result.Captured))
+			for name := range result.Captured {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			html += `            <div class="captured"><strong>Captured:</strong><ul>`
+			for _, name := range names {
+				html += fmt.Sprintf(`<li>%s = %s</li>`, escapeHTML(name), escapeHTML(formatOutputValue(result.Captured[name])))
+			}
+			html += `</ul></div>`
+		}
+
+		if len(result.Attempts) > 1 {
+			html += fmt.Sprintf(`            <div class="attempts"><strong>Attempts:</strong> %d<ul>`, len(result.Attempts))
+			for _, a := range result.Attempts {
+				outcome := "succeeded"
+				if a.Error != nil {
+					outcome = "failed: " + a.Error.Error()
+				}
+				html += fmt.Sprintf(`<li>#%d (%s) %s</li>`, a.Number, a.Duration.Round(time.Millisecond), escapeHTML(outcome))
+			}
+			html += `</ul></div>`
+		}
+
 		html += `        </div>`
 	}
 
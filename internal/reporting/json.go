@@ -19,6 +19,8 @@ type Summary struct {
 	TotalSteps    int           `json:"total_steps"`
 	Successful    int           `json:"successful"`
 	Failed        int           `json:"failed"`
+	TimedOut      int           `json:"timed_out"`
+	Skipped       int           `json:"skipped"`
 	TotalDuration time.Duration `json:"total_duration"`
 }
 
@@ -32,6 +34,21 @@ type StepReport struct {
 	Output    string        `json:"output,omitempty"`
 	Resources []Resource    `json:"resources,omitempty"`
 	HTTPStatus int          `json:"http_status,omitempty"`
+	Latency    time.Duration `json:"latency,omitempty"`
+	Records    []string     `json:"records,omitempty"`
+	Attempts   []AttemptReport `json:"attempts,omitempty"`
+	TimedOut   bool         `json:"timed_out,omitempty"`
+	Skipped    bool         `json:"skipped,omitempty"`
+	SkipReason string       `json:"skip_reason,omitempty"`
+	Captured   map[string]interface{} `json:"captured,omitempty"`
+}
+
+// AttemptReport represents a single try of a step in the report
+type AttemptReport struct {
+	Number   int           `json:"number"`
+	Success  bool          `json:"success"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
 }
 
 // Resource represents a resource in the report
@@ -42,12 +59,40 @@ type Resource struct {
 
 // GenerateJSONReport creates a JSON report
 func GenerateJSONReport(f FlowInfo, results []StepResultInfo, outputPath string) error {
+	report := Report{
+		Flow: FlowInfo{
+			Name:        f.Name,
+			Description: f.Description,
+			WorkingDir:  f.WorkingDir,
+			Matrix:      f.Matrix,
+		},
+		Summary:   summarize(results),
+		Steps:     stepReports(results),
+		Generated: time.Now(),
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(outputPath, data, 0644)
+}
+
+// summarize counts step results by outcome
+func summarize(results []StepResultInfo) Summary {
 	// Calculate summary
 	successCount := 0
 	failureCount := 0
+	skippedCount := 0
+	timedOutCount := 0
 	totalDuration := time.Duration(0)
 	for _, r := range results {
-		if r.Success {
+		if r.Skipped {
+			skippedCount++
+		} else if r.TimedOut {
+			timedOutCount++
+		} else if r.Success {
 			successCount++
 		} else {
 			failureCount++
@@ -55,15 +100,31 @@ func GenerateJSONReport(f FlowInfo, results []StepResultInfo, outputPath string)
 		totalDuration += r.Duration
 	}
 
+	return Summary{
+		TotalSteps:    len(results),
+		Successful:    successCount,
+		Failed:        failureCount,
+		TimedOut:      timedOutCount,
+		Skipped:       skippedCount,
+		TotalDuration: totalDuration,
+	}
+}
+
+// stepReports converts step results into their report form
+func stepReports(results []StepResultInfo) []StepReport {
 	// Convert results
-	stepReports := make([]StepReport, len(results))
+	reports := make([]StepReport, len(results))
 	for i, r := range results {
 		sr := StepReport{
-			Name:     r.StepName,
-			Type:     r.StepType,
-			Success:  r.Success,
-			Duration: r.Duration,
-			Output:   r.Output,
+			Name:       r.StepName,
+			Type:       r.StepType,
+			Success:    r.Success,
+			Duration:   r.Duration,
+			Output:     r.Output,
+			TimedOut:   r.TimedOut,
+			Skipped:    r.Skipped,
+			SkipReason: r.SkipReason,
+			Captured:   r.Captured,
 		}
 
 		if r.Error != nil {
@@ -83,31 +144,26 @@ func GenerateJSONReport(f FlowInfo, results []StepResultInfo, outputPath string)
 		if r.HTTPStatus > 0 {
 			sr.HTTPStatus = r.HTTPStatus
 		}
+		sr.Latency = r.Latency
+		sr.Records = r.Records
 
-		stepReports[i] = sr
-	}
-
-	report := Report{
-		Flow: FlowInfo{
-			Name:        f.Name,
-			Description: f.Description,
-			WorkingDir:  f.WorkingDir,
-		},
-		Summary: Summary{
-			TotalSteps:    len(results),
-			Successful:    successCount,
-			Failed:        failureCount,
-			TotalDuration: totalDuration,
-		},
-		Steps:     stepReports,
-		Generated: time.Now(),
-	}
+		// Only list attempts when the step was actually retried
+		if len(r.Attempts) > 1 {
+			sr.Attempts = make([]AttemptReport, len(r.Attempts))
+			for j, a := range r.Attempts {
+				sr.Attempts[j] = AttemptReport{
+					Number:   a.Number,
+					Success:  a.Success,
+					Duration: a.Duration,
+				}
+				if a.Error != nil {
+					sr.Attempts[j].Error = a.Error.Error()
+				}
+			}
+		}
 
-	data, err := json.MarshalIndent(report, "", "  ")
-	if err != nil {
-		return err
+		reports[i] = sr
 	}
 
-	return os.WriteFile(outputPath, data, 0644)
+	return reports
 }
-
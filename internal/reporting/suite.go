@@ -0,0 +1,164 @@
+package reporting
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// SuiteRunInfo contains the result of one flow run in a suite
+type SuiteRunInfo struct {
+	Flow     FlowInfo
+	Path     string // flow file the run came from
+	Success  bool
+	Error    error
+	Duration time.Duration
+	Steps    []StepResultInfo
+}
+
+// SuiteReport represents the combined report of a suite of flows
+type SuiteReport struct {
+	Summary   SuiteSummary     `json:"summary"`
+	Runs      []SuiteRunReport `json:"runs"`
+	Generated time.Time        `json:"generated"`
+}
+
+// SuiteSummary contains the suite totals
+type SuiteSummary struct {
+	TotalRuns     int           `json:"total_runs"`
+	Passed        int           `json:"passed"`
+	Failed        int           `json:"failed"`
+	TotalDuration time.Duration `json:"total_duration"`
+}
+
+// SuiteRunReport represents one flow run in the suite report
+type SuiteRunReport struct {
+	Flow     FlowInfo      `json:"flow"`
+	Path     string        `json:"path"`
+	Success  bool          `json:"success"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+	Summary  Summary       `json:"summary"`
+	Steps    []StepReport  `json:"steps"`
+}
+
+// summarizeSuite counts passed and failed runs
+func summarizeSuite(runs []SuiteRunInfo) SuiteSummary {
+	summary := SuiteSummary{TotalRuns: len(runs)}
+	for _, r := range runs {
+		if r.Success {
+			summary.Passed++
+		} else {
+			summary.Failed++
+		}
+		summary.TotalDuration += r.Duration
+	}
+	return summary
+}
+
+// GenerateSuiteJSONReport creates the combined JSON report of a suite
+func GenerateSuiteJSONReport(runs []SuiteRunInfo, outputPath string) error {
+	report := SuiteReport{
+		Summary:   summarizeSuite(runs),
+		Runs:      make([]SuiteRunReport, len(runs)),
+		Generated: time.Now(),
+	}
+	for i, r := range runs {
+		report.Runs[i] = SuiteRunReport{
+			Flow:     r.Flow,
+			Path:     r.Path,
+			Success:  r.Success,
+			Duration: r.Duration,
+			Summary:  summarize(r.Steps),
+			Steps:    stepReports(r.Steps),
+		}
+		if r.Error != nil {
+			report.Runs[i].Error = r.Error.Error()
+		}
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(outputPath, data, 0644)
+}
+
+// GenerateSuiteHTMLReport creates the combined HTML report of a suite
+func GenerateSuiteHTMLReport(runs []SuiteRunInfo, outputPath string) error {
+	summary := summarizeSuite(runs)
+
+	html := `<!DOCTYPE html>
+<html>
+<head>
+    <title>Infratest Suite Report</title>
+    <style>
+        body { font-family: Arial, sans-serif; margin: 20px; background: #f5f5f5; }
+        .container { max-width: 1200px; margin: 0 auto; background: white; padding: 20px; border-radius: 8px; box-shadow: 0 2px 4px rgba(0,0,0,0.1); }
+        h1 { color: #333; border-bottom: 3px solid #4CAF50; padding-bottom: 10px; }
+        h2 { color: #555; margin-top: 30px; }
+        .summary { background: #f9f9f9; padding: 15px; border-radius: 5px; margin: 20px 0; }
+        .run { margin: 15px 0; padding: 15px; border-left: 4px solid #ddd; background: #fafafa; border-radius: 4px; }
+        .run.success { border-left-color: #4CAF50; }
+        .run.failure { border-left-color: #f44336; }
+        .run-header { font-weight: bold; font-size: 1.1em; margin-bottom: 10px; }
+        .run-path { color: #666; font-size: 0.9em; }
+        .error { color: #f44336; background: #ffebee; padding: 10px; border-radius: 4px; margin-top: 10px; }
+        table { width: 100%; border-collapse: collapse; margin-top: 10px; }
+        th, td { padding: 6px 8px; text-align: left; border-bottom: 1px solid #ddd; }
+        th { background: #f5f5f5; font-weight: bold; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <h1>Infratest Suite Report</h1>
+        <div class="summary">
+`
+	html += fmt.Sprintf(`            <p><strong>Total Runs:</strong> %d</p>
+            <p><strong>Passed:</strong> <span style="color: #4CAF50;">%d</span></p>
+            <p><strong>Failed:</strong> <span style="color: #f44336;">%d</span></p>
+            <p><strong>Total Duration:</strong> %s</p>
+            <p><strong>Generated:</strong> %s</p>
+        </div>
+        <h2>Runs</h2>
+`, summary.TotalRuns, summary.Passed, summary.Failed, summary.TotalDuration.Round(time.Millisecond), time.Now().Format(time.RFC3339))
+
+	for _, r := range runs {
+		class := "success"
+		status := "✓ PASS"
+		if !r.Success {
+			class = "failure"
+			status = "✗ FAIL"
+		}
+		steps := summarize(r.Steps)
+
+		html += fmt.Sprintf(`        <div class="run %s">
+            <div class="run-header">%s %s</div>
+            <div class="run-path">%s</div>
+`, class, status, escapeHTML(r.Flow.Name), escapeHTML(r.Path))
+		if r.Flow.Matrix != "" {
+			html += fmt.Sprintf(`            <p><strong>Matrix:</strong> %s</p>
+`, escapeHTML(r.Flow.Matrix))
+		}
+		html += fmt.Sprintf(`            <table>
+                <tr><th>Steps</th><th>Successful</th><th>Failed</th><th>Timed Out</th><th>Skipped</th><th>Duration</th></tr>
+                <tr><td>%d</td><td>%d</td><td>%d</td><td>%d</td><td>%d</td><td>%s</td></tr>
+            </table>
+`, steps.TotalSteps, steps.Successful, steps.Failed, steps.TimedOut, steps.Skipped, r.Duration.Round(time.Millisecond))
+		if r.Error != nil {
+			html += fmt.Sprintf(`            <div class="error">%s</div>
+`, escapeHTML(r.Error.Error()))
+		}
+		html += `        </div>
+`
+	}
+
+	html += `    </div>
+</body>
+</html>
+`
+
+	return os.WriteFile(outputPath, []byte(html), 0644)
+}
@@ -0,0 +1,75 @@
+package terraform
+
+import (
+	"strings"
+
+	cmdline "github.com/infratest/infratest/internal/command"
+)
+
+// varFileCommands are the terraform subcommands that accept -var-file
+var varFileCommands = map[string]bool{
+	"plan":    true,
+	"apply":   true,
+	"destroy": true,
+	"refresh": true,
+	"import":  true,
+	"console": true,
+}
+
+// SplitCommand splits a command line into arguments like a POSIX shell would
+// (see command.Split)
+func SplitCommand(command string) ([]string, error) {
+	return cmdline.Split(command)
+}
+
+// acceptsVarFiles reports whether -var-file can be added to the given
+// arguments. Applying a saved plan doesn't allow setting variables.
+func acceptsVarFiles(args []string) bool {
+	if len(args) == 0 || !varFileCommands[args[0]] {
+		return false
+	}
+	if args[0] == "apply" {
+		for _, arg := range args[1:] {
+			if !strings.HasPrefix(arg, "-") {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// withVarFiles inserts a -var-file argument per file after the subcommand
+func withVarFiles(args []string, varFiles []string) []string {
+	if len(varFiles) == 0 || !acceptsVarFiles(args) {
+		return args
+	}
+	out := make([]string, 0, len(args)+len(varFiles))
+	out = append(out, args[0])
+	for _, f := range varFiles {
+		out = append(out, "-var-file="+f)
+	}
+	return append(out, args[1:]...)
+}
+
+// GeneratedVarFile stands for the temporary var file holding the variables
+// (see Executor.SetVariable) in previewed command lines
+const GeneratedVarFile = "<generated>.tfvars.json"
+
+// CommandLine returns the terraform command line that runs for a command,
+// including the -var-file arguments, e.g. to preview a flow. With variables,
+// the generated var file comes last, as GeneratedVarFile.
+func CommandLine(command string, varFiles []string, vars map[string]interface{}) (string, error) {
+	parts, err := SplitCommand(command)
+	if err != nil {
+		return "", err
+	}
+	if len(parts) > 0 && parts[0] == "terraform" {
+		parts = parts[1:]
+	}
+	if len(vars) > 0 && acceptsVarFiles(parts) {
+		varFiles = append(append([]string(nil), varFiles...), GeneratedVarFile)
+	}
+	parts = withVarFiles(parts, varFiles)
+
+	return cmdline.Join(append([]string{"terraform"}, parts...)), nil
+}
@@ -0,0 +1,154 @@
+package terraform
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "plain words",
+			command: "terraform  plan -out=plan.tfplan",
+			want:    []string{"terraform", "plan", "-out=plan.tfplan"},
+		},
+		{
+			name:    "single quotes",
+			command: `apply -target='module.vpc["main"]'`,
+			want:    []string{"apply", `-target=module.vpc["main"]`},
+		},
+		{
+			name:    "double quotes with escapes",
+			command: `apply -var "name=my \"test\" vpc"`,
+			want:    []string{"apply", "-var", `name=my "test" vpc`},
+		},
+		{
+			name:    "escaped space",
+			command: `plan -out=my\ plan`,
+			want:    []string{"plan", "-out=my plan"},
+		},
+		{
+			name:    "empty quoted argument",
+			command: `output -raw ''`,
+			want:    []string{"output", "-raw", ""},
+		},
+		{
+			name:    "unterminated quote",
+			command: `apply -var 'name=x`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SplitCommand(tt.command)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("SplitCommand() expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SplitCommand() error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitCommand() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithVarFiles(t *testing.T) {
+	files := []string{"/tmp/a.tfvars", "/tmp/b.tfvars"}
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{
+			name: "plan",
+			args: []string{"plan", "-out=plan.tfplan"},
+			want: []string{"plan", "-var-file=/tmp/a.tfvars", "-var-file=/tmp/b.tfvars", "-out=plan.tfplan"},
+		},
+		{
+			name: "apply without plan file",
+			args: []string{"apply", "-auto-approve"},
+			want: []string{"apply", "-var-file=/tmp/a.tfvars", "-var-file=/tmp/b.tfvars", "-auto-approve"},
+		},
+		{
+			name: "apply saved plan",
+			args: []string{"apply", "-auto-approve", "plan.tfplan"},
+			want: []string{"apply", "-auto-approve", "plan.tfplan"},
+		},
+		{
+			name: "init",
+			args: []string{"init"},
+			want: []string{"init"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := withVarFiles(tt.args, files); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("withVarFiles() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommandLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  string
+		varFiles []string
+		vars     map[string]interface{}
+		want     string
+	}{
+		{"prefix added", "plan -out=plan.tfplan", nil, nil, "terraform plan -out=plan.tfplan"},
+		{"var files", "terraform apply -auto-approve", []string{"/tmp/a.tfvars"}, nil, "terraform apply -var-file=/tmp/a.tfvars -auto-approve"},
+		{"generated var file last", "apply", []string{"/tmp/a.tfvars"}, map[string]interface{}{"x": 1}, "terraform apply -var-file=/tmp/a.tfvars '-var-file=<generated>.tfvars.json'"},
+		{"no var file for saved plan", "apply plan.tfplan", nil, map[string]interface{}{"x": 1}, "terraform apply plan.tfplan"},
+		{"quoted arguments", `apply -target='module.vpc["main"]'`, nil, nil, `terraform apply '-target=module.vpc["main"]'`},
+		{"single quote escaped", `plan -var "name=it's"`, nil, nil, `terraform plan -var 'name=it'\''s'`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CommandLine(tt.command, tt.varFiles, tt.vars)
+			if err != nil {
+				t.Fatalf("CommandLine() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("CommandLine() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatVariable(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{"my vpc", "my vpc"},
+		{2, "2"},
+		{true, "true"},
+		{[]interface{}{"a", "b"}, `["a","b"]`},
+		{map[string]interface{}{"owner": "platform"}, `{"owner":"platform"}`},
+	}
+
+	for _, tt := range tests {
+		got, err := FormatVariable(tt.value)
+		if err != nil {
+			t.Fatalf("FormatVariable(%v) error: %v", tt.value, err)
+		}
+		if got != tt.want {
+			t.Errorf("FormatVariable(%v) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
@@ -2,11 +2,13 @@ package terraform
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/fatih/color"
@@ -17,6 +19,10 @@ import (
 type Executor struct {
 	workingDir string
 	debug      bool
+	env        []string // extra KEY=VALUE environment entries, e.g. TF_VAR_*
+	varFiles   []string // passed as -var-file to commands that accept it
+	vars       map[string]interface{} // input variables, passed after varFiles in a generated var file
+	workspace  *workspace // isolated workspace, if any (see UseWorkspace)
 }
 
 // NewExecutor creates a new Terraform executor
@@ -42,6 +48,133 @@ func NewExecutor(workingDir string, debug bool) (*Executor, error) {
 	}, nil
 }
 
+// SetEnv adds an environment variable to every command the executor runs
+func (e *Executor) SetEnv(key, value string) {
+	e.env = append(e.env, key+"="+value)
+}
+
+// SetVariable passes a terraform input variable to every command. Commands
+// that accept -var-file get it in a generated var file after all others, so
+// it overrides terraform.tfvars, *.auto.tfvars and var files; the others get
+// it as TF_VAR_<name>.
+func (e *Executor) SetVariable(name string, value interface{}) error {
+	formatted, err := FormatVariable(value)
+	if err != nil {
+		return fmt.Errorf("variable %s: %w", name, err)
+	}
+	e.SetEnv("TF_VAR_"+name, formatted)
+	if e.vars == nil {
+		e.vars = make(map[string]interface{})
+	}
+	e.vars[name] = value
+	return nil
+}
+
+// AddVarFile passes a variable definitions file to every command that accepts -var-file
+func (e *Executor) AddVarFile(path string) {
+	e.varFiles = append(e.varFiles, path)
+}
+
+// With returns a copy of the executor with additional variables and var files,
+// e.g. for a single step. Later values take precedence over earlier ones.
+func (e *Executor) With(vars map[string]interface{}, varFiles []string) (*Executor, error) {
+	c := *e
+	c.env = append([]string(nil), e.env...)
+	c.varFiles = append(append([]string(nil), e.varFiles...), varFiles...)
+	c.vars = make(map[string]interface{}, len(e.vars)+len(vars))
+	for name, value := range e.vars {
+		c.vars[name] = value
+	}
+	for _, name := range sortedKeys(vars) {
+		if err := c.SetVariable(name, vars[name]); err != nil {
+			return nil, err
+		}
+	}
+	return &c, nil
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// FormatVariable renders a value the way terraform reads it from a TF_VAR_
+// environment variable: strings as-is, everything else as JSON, which
+// terraform parses as an HCL literal
+func FormatVariable(value interface{}) (string, error) {
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// templateEscaper escapes the template sequences terraform evaluates in JSON strings
+var templateEscaper = strings.NewReplacer("${", "$${", "%{", "%%{")
+
+// escapeTemplates escapes the strings inside a variable value, so that
+// terraform reads them literally from a JSON var file
+func escapeTemplates(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return templateEscaper.Replace(v)
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = escapeTemplates(item)
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			out[k] = escapeTemplates(item)
+		}
+		return out
+	default:
+		return value
+	}
+}
+
+// VarFileValue renders a variable value the way it's written to the generated
+// var file, e.g. to preview a flow
+func VarFileValue(value interface{}) (string, error) {
+	data, err := json.Marshal(escapeTemplates(value))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// writeVarsFile writes variables to a temporary .tfvars.json file, readable
+// only by the current user, and returns its path
+func writeVarsFile(vars map[string]interface{}) (string, error) {
+	data, err := json.Marshal(escapeTemplates(vars))
+	if err != nil {
+		return "", fmt.Errorf("failed to encode variables: %w", err)
+	}
+	f, err := os.CreateTemp("", "infratest-*.tfvars.json")
+	if err != nil {
+		return "", fmt.Errorf("failed to write variables: %w", err)
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("failed to write variables: %w", err)
+	}
+	return f.Name(), nil
+}
+
 // Execute runs a terraform command (without context, for backward compatibility)
 func (e *Executor) Execute(command string) (string, error) {
 	return e.ExecuteWithContext(context.Background(), command)
@@ -49,19 +182,44 @@ func (e *Executor) Execute(command string) (string, error) {
 
 // ExecuteWithContext runs a terraform command with context support
 func (e *Executor) ExecuteWithContext(ctx context.Context, command string) (string, error) {
-	parts := strings.Fields(command)
+	output, _, err := e.execute(ctx, command)
+	return output, err
+}
+
+// execute runs a terraform command and returns its output and exit code.
+// Exit codes listed in ok, e.g. 2 for plan -detailed-exitcode, are not failures.
+func (e *Executor) execute(ctx context.Context, command string, ok ...int) (string, int, error) {
+	parts, err := SplitCommand(command)
+	if err != nil {
+		return "", 0, err
+	}
 	if len(parts) == 0 {
-		return "", fmt.Errorf("empty command")
+		return "", 0, fmt.Errorf("empty command")
 	}
 
 	// Remove 'terraform' prefix if present
 	if parts[0] == "terraform" {
 		parts = parts[1:]
 	}
+	varFiles := e.varFiles
+	if len(e.vars) > 0 && acceptsVarFiles(parts) {
+		path, err := writeVarsFile(e.vars)
+		if err != nil {
+			return "", 0, err
+		}
+		defer os.Remove(path)
+		varFiles = append(append([]string(nil), varFiles...), path)
+	}
+	parts = withVarFiles(parts, varFiles)
+
+	env, err := e.prepare(ctx, parts)
+	if err != nil {
+		return "", 0, err
+	}
 
 	cmd := exec.CommandContext(ctx, "terraform", parts...)
 	cmd.Dir = e.workingDir
-	cmd.Env = os.Environ()
+	cmd.Env = env
 	
 	// Suppress cost warnings if LocalStack is being used
 	if os.Getenv("AWS_ENDPOINT_URL") != "" {
@@ -96,6 +254,12 @@ func (e *Executor) ExecuteWithContext(ctx context.Context, command string) (stri
 				}
 			}
 		}
+		for _, kv := range e.env {
+			color.New(color.FgHiBlack).Printf("  %s\n", kv)
+		}
+		if ws := e.Workspace(); ws != "" {
+			color.New(color.FgHiBlack).Printf("  TF_WORKSPACE=%s\n", ws)
+		}
 		
 		// Show all env vars if specifically requested (very verbose)
 		if os.Getenv("INFRATEST_DEBUG_ENV") == "true" {
@@ -124,16 +288,24 @@ func (e *Executor) ExecuteWithContext(ctx context.Context, command string) (stri
 
 	// Check if context was cancelled
 	if ctx.Err() != nil {
-		return outputStr, fmt.Errorf("command cancelled: %w", ctx.Err())
+		return outputStr, 0, fmt.Errorf("command cancelled: %w", ctx.Err())
 	}
 
+	exitCode := 0
 	if err != nil {
 		// Enhanced error reporting
-		exitError, ok := err.(*exec.ExitError)
-		exitCode := -1
-		if ok {
+		exitCode = -1
+		if exitError, isExit := err.(*exec.ExitError); isExit {
 			exitCode = exitError.ExitCode()
 		}
+		for _, code := range ok {
+			if exitCode == code {
+				err = nil
+			}
+		}
+	}
+
+	if err != nil {
 
 		// Always show colored error output on failure (not just in debug)
 		fmt.Println()
@@ -161,7 +333,7 @@ func (e *Executor) ExecuteWithContext(ctx context.Context, command string) (stri
 		// Show suggested fixes
 		suggestFixes(exitCode, outputStr, e.workingDir)
 
-		return outputStr, fmt.Errorf("terraform command failed (exit code: %d): %w", exitCode, err)
+		return outputStr, exitCode, fmt.Errorf("terraform command failed (exit code: %d): %w", exitCode, err)
 	}
 
 	if e.debug {
@@ -185,7 +357,7 @@ func (e *Executor) ExecuteWithContext(ctx context.Context, command string) (stri
 		}
 	}
 
-	return outputStr, nil
+	return outputStr, exitCode, nil
 }
 
 // printColoredOutput prints terraform output with syntax highlighting for common patterns
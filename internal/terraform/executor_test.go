@@ -0,0 +1,87 @@
+package terraform
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestExecuteVariablesOverrideVarFiles(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake terraform script requires a POSIX shell")
+	}
+
+	// The fake terraform prints every var file it gets, in order
+	bin := t.TempDir()
+	script := `#!/bin/sh
+for arg in "$@"; do
+	case "$arg" in
+	-var-file=*) f="${arg#-var-file=}"; echo "$f: $(cat "$f")" ;;
+	esac
+done
+`
+	if err := os.WriteFile(filepath.Join(bin, "terraform"), []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake terraform: %v", err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	dir := t.TempDir()
+	varFile := filepath.Join(dir, "test.tfvars")
+	if err := os.WriteFile(varFile, []byte(`name = "from-file"`), 0644); err != nil {
+		t.Fatalf("Failed to write var file: %v", err)
+	}
+
+	e := &Executor{workingDir: dir}
+	e.AddVarFile(varFile)
+	step, err := e.With(map[string]interface{}{"name": "from-cli", "azs": []interface{}{"a", "b"}}, nil)
+	if err != nil {
+		t.Fatalf("With() error: %v", err)
+	}
+
+	out, err := step.ExecuteWithContext(context.Background(), "terraform plan")
+	if err != nil {
+		t.Fatalf("ExecuteWithContext() error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 2 || lines[0] != varFile+`: name = "from-file"` {
+		t.Fatalf("Unexpected var files:\n%s", out)
+	}
+
+	// Variables come last, so they override the var file
+	generated, content, _ := strings.Cut(lines[1], ": ")
+	if !strings.HasSuffix(generated, ".tfvars.json") || content != `{"azs":["a","b"],"name":"from-cli"}` {
+		t.Errorf("Expected generated var file with the variables last, got %q", lines[1])
+	}
+	if _, err := os.Stat(generated); !os.IsNotExist(err) {
+		t.Errorf("Expected generated var file to be removed, got %v", err)
+	}
+
+	// A saved plan already holds the variables
+	if out, err := step.ExecuteWithContext(context.Background(), "terraform apply plan.tfplan"); err != nil || out != "" {
+		t.Errorf("Expected no var files when applying a saved plan, got %q, %v", out, err)
+	}
+}
+
+func TestWriteVarsFileEscapesTemplates(t *testing.T) {
+	path, err := writeVarsFile(map[string]interface{}{
+		"name": "${steps.probe.id}",
+		"tags": map[string]interface{}{"note": "100%{ok}"},
+		"azs":  []interface{}{"${a}", 2},
+	})
+	if err != nil {
+		t.Fatalf("writeVarsFile() error: %v", err)
+	}
+	defer os.Remove(path)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read var file: %v", err)
+	}
+	want := `{"azs":["$${a}",2],"name":"$${steps.probe.id}","tags":{"note":"100%%{ok}"}}`
+	if string(data) != want {
+		t.Errorf("Var file = %s, want %s", data, want)
+	}
+}
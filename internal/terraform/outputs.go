@@ -1,6 +1,7 @@
 package terraform
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -10,31 +11,60 @@ import (
 
 // ParseOutputs parses terraform output -json into a map with proper type handling
 func ParseOutputs(workingDir string) (map[string]interface{}, error) {
-	cmd := exec.Command("terraform", "output", "-json")
+	return ParseOutputsWithContext(context.Background(), workingDir)
+}
+
+// ParseOutputsWithContext parses terraform output -json with context support
+func ParseOutputsWithContext(ctx context.Context, workingDir string) (map[string]interface{}, error) {
+	return readOutputs(ctx, workingDir, os.Environ())
+}
+
+// Output is a terraform output as printed by terraform output -json
+type Output struct {
+	Value     interface{} `json:"value"`
+	Type      interface{} `json:"type"` // terraform type, e.g. "string" or ["list", "string"]
+	Sensitive bool        `json:"sensitive"`
+}
+
+// ParseOutputDetailsWithContext parses terraform output -json, keeping the
+// type and sensitive flag of each output
+func ParseOutputDetailsWithContext(ctx context.Context, workingDir string) (map[string]Output, error) {
+	return readOutputDetails(ctx, workingDir, os.Environ())
+}
+
+// readOutputs runs terraform output -json with the given environment
+func readOutputs(ctx context.Context, workingDir string, env []string) (map[string]interface{}, error) {
+	outputs, err := readOutputDetails(ctx, workingDir, env)
+	if err != nil {
+		return nil, err
+	}
+
+	// Extract values from output structure
+	result := make(map[string]interface{})
+	for key, val := range outputs {
+		result[key] = val.Value
+	}
+
+	return result, nil
+}
+
+// readOutputDetails runs terraform output -json with the given environment
+func readOutputDetails(ctx context.Context, workingDir string, env []string) (map[string]Output, error) {
+	cmd := exec.CommandContext(ctx, "terraform", "output", "-json")
 	cmd.Dir = workingDir
-	cmd.Env = os.Environ()
+	cmd.Env = env
 
 	output, err := cmd.Output()
 	if err != nil {
 		return nil, fmt.Errorf("failed to read terraform outputs: %w", err)
 	}
 
-	var outputs map[string]interface{}
+	var outputs map[string]Output
 	if err := json.Unmarshal(output, &outputs); err != nil {
 		return nil, fmt.Errorf("failed to parse terraform outputs: %w", err)
 	}
 
-	// Extract values from output structure
-	result := make(map[string]interface{})
-	for key, val := range outputs {
-		if outputMap, ok := val.(map[string]interface{}); ok {
-			if value, exists := outputMap["value"]; exists {
-				result[key] = value
-			}
-		}
-	}
-
-	return result, nil
+	return outputs, nil
 }
 
 // GetOutputValue retrieves a nested value from outputs using dot notation or array access
@@ -42,60 +72,63 @@ func ParseOutputs(workingDir string) (map[string]interface{}, error) {
 //   - "alb_dns" -> outputs["alb_dns"]
 //   - "instance_ids[0]" -> outputs["instance_ids"].([]interface{})[0]
 //   - "config.database.host" -> outputs["config"].(map[string]interface{})["database"].(map[string]interface{})["host"]
+//   - "subnets[0].cidr" -> outputs["subnets"].([]interface{})[0].(map[string]interface{})["cidr"]
 func GetOutputValue(outputs map[string]interface{}, path string) (interface{}, error) {
 	if outputs == nil {
 		return nil, fmt.Errorf("outputs map is nil")
 	}
 
-	// Handle array access like "key[0]"
-	if idx := findArrayIndex(path); idx >= 0 {
-		key := path[:idx]
-		index := parseArrayIndex(path[idx:])
-		if index < 0 {
-			return nil, fmt.Errorf("invalid array index in path: %s", path)
-		}
+	parts := splitPath(path)
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("unexpected error parsing path: %s", path)
+	}
 
-		val, err := GetOutputValue(outputs, key)
-		if err != nil {
-			return nil, err
+	var current interface{} = outputs
+	for i, part := range parts {
+		// Split array access like "key[0][1]" into the key and its indexes
+		key, indexes := part, ""
+		if idx := findArrayIndex(part); idx >= 0 {
+			key, indexes = part[:idx], part[idx:]
 		}
-
-		arr, ok := val.([]interface{})
-		if !ok {
-			return nil, fmt.Errorf("path %s is not an array", key)
+		if key == "" {
+			return nil, fmt.Errorf("invalid array index in path: %s", path)
 		}
 
-		if index >= len(arr) {
-			return nil, fmt.Errorf("array index %d out of bounds for path %s (length: %d)", index, key, len(arr))
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("path %s is not a map at segment: %s", path, parts[i-1])
 		}
-
-		return arr[index], nil
-	}
-
-	// Handle dot notation for nested maps
-	parts := splitPath(path)
-	current := outputs
-
-	for i, part := range parts {
-		val, exists := current[part]
+		val, exists := m[key]
 		if !exists {
-			return nil, fmt.Errorf("output path not found: %s (missing: %s)", path, part)
-		}
-
-		// If this is the last part, return the value
-		if i == len(parts)-1 {
-			return val, nil
+			return nil, fmt.Errorf("output path not found: %s (missing: %s)", path, key)
 		}
+		current = val
+
+		prefix := strings.Join(append(parts[:i:i], key), ".")
+		for indexes != "" {
+			end := strings.Index(indexes, "]")
+			index := -1
+			if end >= 0 {
+				index = parseArrayIndex(indexes[:end+1])
+			}
+			if index < 0 {
+				return nil, fmt.Errorf("invalid array index in path: %s", path)
+			}
 
-		// Otherwise, continue navigating
-		next, ok := val.(map[string]interface{})
-		if !ok {
-			return nil, fmt.Errorf("path %s is not a map at segment: %s", path, part)
+			arr, ok := current.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("path %s is not an array", prefix)
+			}
+			if index >= len(arr) {
+				return nil, fmt.Errorf("array index %d out of bounds for path %s (length: %d)", index, prefix, len(arr))
+			}
+			current = arr[index]
+			prefix += indexes[:end+1]
+			indexes = indexes[end+1:]
 		}
-		current = next
 	}
 
-	return nil, fmt.Errorf("unexpected error parsing path: %s", path)
+	return current, nil
 }
 
 // findArrayIndex finds the position of '[' in the path
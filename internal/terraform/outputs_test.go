@@ -0,0 +1,60 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestGetOutputValue(t *testing.T) {
+	var outputs map[string]interface{}
+	err := json.Unmarshal([]byte(`{
+		"alb_dns": "alb.example.com",
+		"instance_ids": ["i-1", "i-2"],
+		"config": {"database": {"host": "db.internal", "port": 5432}},
+		"subnets": [{"cidr": "10.0.1.0/24", "zones": ["a", "b"]}],
+		"matrix": [[1, 2], [3, 4]]
+	}`), &outputs)
+	if err != nil {
+		t.Fatalf("json.Unmarshal() error: %v", err)
+	}
+
+	tests := []struct {
+		path    string
+		want    string
+		wantErr string
+	}{
+		{path: "alb_dns", want: "alb.example.com"},
+		{path: "instance_ids[1]", want: "i-2"},
+		{path: "config.database.host", want: "db.internal"},
+		{path: "subnets[0].cidr", want: "10.0.1.0/24"},
+		{path: "subnets[0].zones[1]", want: "b"},
+		{path: "matrix[1][0]", want: "3"},
+		{path: "missing", wantErr: "output path not found: missing"},
+		{path: "instance_ids[2]", wantErr: "array index 2 out of bounds for path instance_ids (length: 2)"},
+		{path: "subnets[0].zones[5]", wantErr: "out of bounds for path subnets[0].zones"},
+		{path: "alb_dns[0]", wantErr: "path alb_dns is not an array"},
+		{path: "alb_dns.host", wantErr: "is not a map at segment: alb_dns"},
+		{path: "instance_ids[x]", wantErr: "invalid array index"},
+		{path: "[0]", wantErr: "invalid array index"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := GetOutputValue(outputs, tt.path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("GetOutputValue() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetOutputValue() error: %v", err)
+			}
+			if fmt.Sprintf("%v", got) != tt.want {
+				t.Errorf("GetOutputValue() = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
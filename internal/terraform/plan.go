@@ -0,0 +1,146 @@
+package terraform
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+
+	cmdline "github.com/infratest/infratest/internal/command"
+)
+
+// Plan is the machine-readable form of a saved plan (terraform show -json)
+type Plan struct {
+	ResourceChanges []ResourceChange `json:"resource_changes"`
+
+	// JSON is the plan as printed by terraform show -json
+	JSON []byte `json:"-"`
+}
+
+// ResourceChange is the planned change of a single resource
+type ResourceChange struct {
+	Address string `json:"address"`
+	Module  string `json:"module_address"` // empty for the root module
+	Mode    string `json:"mode"`           // "managed" or "data"
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Change  Change `json:"change"`
+}
+
+// Change holds the planned actions and the values before and after them
+type Change struct {
+	Actions []string               `json:"actions"` // e.g. ["create"] or ["delete", "create"]
+	Before  map[string]interface{} `json:"before"`
+	After   map[string]interface{} `json:"after"` // unknown values are missing
+
+	// AfterUnknown marks the values only known after apply with true
+	AfterUnknown map[string]interface{} `json:"after_unknown"`
+}
+
+// PlanSummary counts planned changes like `terraform plan` does: a
+// replacement counts as a create and a delete
+type PlanSummary struct {
+	Creates  int
+	Updates  int
+	Deletes  int
+	Replaces int
+}
+
+// Action returns the planned action: create, update, delete, replace, read or no-op
+func (rc ResourceChange) Action() string {
+	switch len(rc.Change.Actions) {
+	case 0:
+		return "no-op"
+	case 1:
+		return rc.Change.Actions[0]
+	default:
+		return "replace"
+	}
+}
+
+// Summary counts the planned changes of managed resources
+func (p *Plan) Summary() PlanSummary {
+	var s PlanSummary
+	for _, rc := range p.ResourceChanges {
+		if rc.Mode != "managed" {
+			continue
+		}
+		switch rc.Action() {
+		case "create":
+			s.Creates++
+		case "update":
+			s.Updates++
+		case "delete":
+			s.Deletes++
+		case "replace":
+			s.Replaces++
+			s.Creates++
+			s.Deletes++
+		}
+	}
+	return s
+}
+
+// String renders the summary like the last line of `terraform plan`
+func (s PlanSummary) String() string {
+	return fmt.Sprintf("Plan: %d to add, %d to change, %d to destroy (%d replaced)", s.Creates, s.Updates, s.Deletes, s.Replaces)
+}
+
+// PlanWithContext runs a terraform plan command, saving the plan to a
+// temporary file, and reads the plan back with terraform show -json. It
+// returns the plan and the output of the plan command.
+func (e *Executor) PlanWithContext(ctx context.Context, command string) (*Plan, string, error) {
+	return e.plan(ctx, command, false)
+}
+
+// DriftWithContext runs a terraform plan command with -detailed-exitcode. If
+// terraform reports pending changes, the plan is read back like with
+// PlanWithContext; otherwise the returned plan is nil.
+func (e *Executor) DriftWithContext(ctx context.Context, command string) (*Plan, string, error) {
+	return e.plan(ctx, command, true)
+}
+
+func (e *Executor) plan(ctx context.Context, command string, detailed bool) (*Plan, string, error) {
+	f, err := os.CreateTemp("", "infratest-*.tfplan")
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create plan file: %w", err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	args := []string{"-out=" + f.Name()}
+	var ok []int
+	if detailed {
+		// Exit code 2 means the plan succeeded with changes pending
+		args = append(args, "-detailed-exitcode")
+		ok = append(ok, 2)
+	}
+	output, code, err := e.execute(ctx, command+" "+cmdline.Join(args), ok...)
+	if err != nil {
+		return nil, output, err
+	}
+	if detailed && code == 0 {
+		return nil, output, nil
+	}
+
+	env, err := e.prepare(ctx, []string{"show"})
+	if err != nil {
+		return nil, output, err
+	}
+	cmd := exec.CommandContext(ctx, "terraform", "show", "-json", f.Name())
+	cmd.Dir = e.workingDir
+	cmd.Env = env
+
+	data, err := cmd.Output()
+	if err != nil {
+		return nil, output, fmt.Errorf("failed to read terraform plan: %w", err)
+	}
+
+	var plan Plan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, output, fmt.Errorf("failed to parse terraform plan: %w", err)
+	}
+	plan.JSON = data
+	return &plan, output, nil
+}
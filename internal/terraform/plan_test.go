@@ -0,0 +1,38 @@
+package terraform
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPlanSummary(t *testing.T) {
+	data := []byte(`{
+		"resource_changes": [
+			{"address": "aws_vpc.main", "mode": "managed", "change": {"actions": ["no-op"]}},
+			{"address": "aws_subnet.a", "mode": "managed", "change": {"actions": ["create"]}},
+			{"address": "aws_instance.web", "mode": "managed", "change": {"actions": ["update"]}},
+			{"address": "aws_db_instance.main", "mode": "managed", "change": {"actions": ["delete", "create"]}},
+			{"address": "aws_s3_bucket.old", "mode": "managed", "change": {"actions": ["delete"]}},
+			{"address": "data.aws_ami.ubuntu", "mode": "data", "change": {"actions": ["read"]}}
+		]
+	}`)
+	var plan Plan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		t.Fatalf("json.Unmarshal() error: %v", err)
+	}
+
+	wantActions := []string{"no-op", "create", "update", "replace", "delete", "read"}
+	for i, rc := range plan.ResourceChanges {
+		if got := rc.Action(); got != wantActions[i] {
+			t.Errorf("%s: Action() = %s, want %s", rc.Address, got, wantActions[i])
+		}
+	}
+
+	want := PlanSummary{Creates: 2, Updates: 1, Deletes: 2, Replaces: 1}
+	if got := plan.Summary(); got != want {
+		t.Errorf("Summary() = %+v, want %+v", got, want)
+	}
+	if got := want.String(); got != "Plan: 2 to add, 1 to change, 2 to destroy (1 replaced)" {
+		t.Errorf("String() = %q", got)
+	}
+}
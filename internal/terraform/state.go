@@ -1,6 +1,7 @@
 package terraform
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -13,6 +14,8 @@ type Resource struct {
 	ID         string
 	Name       string
 	Address    string
+	Module     string // module path, e.g. module.network; empty for the root module
+	Mode       string // "managed" or "data"
 	Attributes map[string]interface{}
 }
 
@@ -27,8 +30,13 @@ type StateValues struct {
 }
 
 // StateRootModule contains resources
-type StateRootModule struct {
-	Resources []StateResource `json:"resources"`
+type StateRootModule = StateModule
+
+// StateModule contains the resources of a module and its child modules
+type StateModule struct {
+	Address      string          `json:"address"` // empty for the root module
+	Resources    []StateResource `json:"resources"`
+	ChildModules []StateModule   `json:"child_modules"`
 }
 
 // StateResource represents a resource in Terraform state
@@ -42,10 +50,20 @@ type StateResource struct {
 
 // GetState reads and parses Terraform state
 func GetState(workingDir string) (*State, error) {
+	return GetStateWithContext(context.Background(), workingDir)
+}
+
+// GetStateWithContext reads and parses Terraform state with context support
+func GetStateWithContext(ctx context.Context, workingDir string) (*State, error) {
+	return readState(ctx, workingDir, os.Environ())
+}
+
+// readState runs terraform show -json with the given environment
+func readState(ctx context.Context, workingDir string, env []string) (*State, error) {
 	// Use terraform show -json to get state
-	cmd := exec.Command("terraform", "show", "-json")
+	cmd := exec.CommandContext(ctx, "terraform", "show", "-json")
 	cmd.Dir = workingDir
-	cmd.Env = os.Environ()
+	cmd.Env = env
 
 	output, err := cmd.Output()
 	if err != nil {
@@ -60,15 +78,15 @@ func GetState(workingDir string) (*State, error) {
 	return &state, nil
 }
 
-// GetResources extracts all resources from state
+// GetResources extracts all resources from state, including data sources and
+// the resources of child modules
 func (s *State) GetResources() []Resource {
-	var resources []Resource
-	for _, sr := range s.Values.RootModule.Resources {
-		// Only include managed resources, skip data sources
-		if sr.Mode != "managed" {
-			continue
-		}
+	return s.Values.RootModule.resources(nil)
+}
 
+// resources appends the resources of a module and its child modules
+func (m StateModule) resources(resources []Resource) []Resource {
+	for _, sr := range m.Resources {
 		id := ""
 		attributes := make(map[string]interface{})
 		if sr.Values != nil {
@@ -86,17 +104,22 @@ func (s *State) GetResources() []Resource {
 			ID:         id,
 			Name:       sr.Name,
 			Address:    sr.Address,
+			Module:     m.Address,
+			Mode:       sr.Mode,
 			Attributes: attributes,
 		})
 	}
+	for _, child := range m.ChildModules {
+		resources = child.resources(resources)
+	}
 	return resources
 }
 
-// GetResourcesByType returns resources filtered by type
+// GetResourcesByType returns managed resources filtered by type
 func (s *State) GetResourcesByType(resourceType string) []Resource {
 	var filtered []Resource
 	for _, r := range s.GetResources() {
-		if r.Type == resourceType {
+		if r.Mode == "managed" && r.Type == resourceType {
 			filtered = append(filtered, r)
 		}
 	}
@@ -108,3 +131,8 @@ func GetOutputs(workingDir string) (map[string]interface{}, error) {
 	return ParseOutputs(workingDir)
 }
 
+// GetOutputsWithContext reads Terraform outputs with context support
+func GetOutputsWithContext(ctx context.Context, workingDir string) (map[string]interface{}, error) {
+	return ParseOutputsWithContext(ctx, workingDir)
+}
+
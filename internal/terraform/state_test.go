@@ -0,0 +1,53 @@
+package terraform
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestGetResourcesChildModules(t *testing.T) {
+	var state State
+	err := json.Unmarshal([]byte(`{"values": {"root_module": {
+		"resources": [
+			{"address": "aws_vpc.main", "mode": "managed", "type": "aws_vpc", "name": "main", "values": {"id": "vpc-1"}},
+			{"address": "data.aws_region.current", "mode": "data", "type": "aws_region", "name": "current", "values": {"id": "eu-west-1"}}
+		],
+		"child_modules": [{
+			"address": "module.network",
+			"resources": [
+				{"address": "module.network.aws_subnet.a", "mode": "managed", "type": "aws_subnet", "name": "a", "values": {"id": "subnet-1"}}
+			],
+			"child_modules": [{
+				"address": "module.network.module.nat",
+				"resources": [
+					{"address": "module.network.module.nat.aws_nat_gateway.this", "mode": "managed", "type": "aws_nat_gateway", "name": "this", "values": {"id": "nat-1"}}
+				]
+			}]
+		}]
+	}}}`), &state)
+	if err != nil {
+		t.Fatalf("json.Unmarshal() error: %v", err)
+	}
+
+	resources := state.GetResources()
+	want := []struct{ address, module, mode, id string }{
+		{"aws_vpc.main", "", "managed", "vpc-1"},
+		{"data.aws_region.current", "", "data", "eu-west-1"},
+		{"module.network.aws_subnet.a", "module.network", "managed", "subnet-1"},
+		{"module.network.module.nat.aws_nat_gateway.this", "module.network.module.nat", "managed", "nat-1"},
+	}
+	if len(resources) != len(want) {
+		t.Fatalf("GetResources() returned %d resources, want %d: %+v", len(resources), len(want), resources)
+	}
+	for i, w := range want {
+		r := resources[i]
+		if r.Address != w.address || r.Module != w.module || r.Mode != w.mode || r.ID != w.id {
+			t.Errorf("GetResources()[%d] = %s (module %q, %s, id %s), want %s (module %q, %s, id %s)", i, r.Address, r.Module, r.Mode, r.ID, w.address, w.module, w.mode, w.id)
+		}
+	}
+
+	// Data sources don't count as resources of their type
+	if got := state.GetResourcesByType("aws_region"); len(got) != 0 {
+		t.Errorf("GetResourcesByType(aws_region) = %+v, want none", got)
+	}
+}
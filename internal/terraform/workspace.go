@@ -0,0 +1,170 @@
+package terraform
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/infratest/infratest/internal/ui"
+)
+
+// noWorkspaceCommands don't touch state, so they can run before the
+// isolated workspace exists
+var noWorkspaceCommands = map[string]bool{
+	"init":      true,
+	"fmt":       true,
+	"validate":  true,
+	"version":   true,
+	"-version":  true,
+	"providers": true,
+	"get":       true,
+	"workspace": true,
+}
+
+// workspace is an isolated terraform workspace, shared by an executor and its copies
+type workspace struct {
+	mu       sync.Mutex
+	name     string
+	previous string // workspace selected before ours was created
+	created  bool
+}
+
+// UseWorkspace makes the executor run every command in a new workspace with
+// the given name. The workspace is created before the first command that
+// reads or writes state.
+func (e *Executor) UseWorkspace(name string) {
+	e.workspace = &workspace{name: name}
+}
+
+// ResumeWorkspace makes the executor run every command in an isolated
+// workspace created by an earlier run, e.g. when resuming from a checkpoint
+func (e *Executor) ResumeWorkspace(name string) {
+	e.workspace = &workspace{name: name, previous: "default", created: true}
+}
+
+// Workspace returns the name of the isolated workspace, or "" if there is none
+func (e *Executor) Workspace() string {
+	if e.workspace == nil {
+		return ""
+	}
+	return e.workspace.name
+}
+
+// ensureWorkspace creates the isolated workspace if it doesn't exist yet
+func (e *Executor) ensureWorkspace(ctx context.Context) error {
+	ws := e.workspace
+	if ws == nil {
+		return nil
+	}
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if ws.created {
+		return nil
+	}
+
+	previous, err := e.workspaceCommand(ctx, "show")
+	ws.previous = strings.TrimSpace(previous)
+	if err != nil || ws.previous == "" {
+		ws.previous = "default"
+	}
+
+	if out, err := e.workspaceCommand(ctx, "new", ws.name); err != nil {
+		return fmt.Errorf("failed to create workspace %s: %w\n%s", ws.name, err, out)
+	}
+	ws.created = true
+	ui.PrintDebug(e.debug, "Created workspace %s", ws.name)
+	return nil
+}
+
+// DeleteWorkspace selects the previously selected workspace again and deletes
+// the isolated one. Terraform refuses to delete a workspace that still
+// manages resources.
+func (e *Executor) DeleteWorkspace(ctx context.Context) error {
+	ws := e.workspace
+	if ws == nil {
+		return nil
+	}
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if !ws.created {
+		return nil
+	}
+
+	if out, err := e.workspaceCommand(ctx, "select", ws.previous); err != nil {
+		return fmt.Errorf("failed to select workspace %s: %w\n%s", ws.previous, err, out)
+	}
+	if out, err := e.workspaceCommand(ctx, "delete", ws.name); err != nil {
+		return fmt.Errorf("failed to delete workspace %s: %w\n%s", ws.name, err, out)
+	}
+	ws.created = false
+	return nil
+}
+
+// workspaceCommand runs `terraform workspace <args>`. TF_WORKSPACE is left
+// out, since terraform won't create or select workspaces while it's set.
+func (e *Executor) workspaceCommand(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "terraform", append([]string{"workspace"}, args...)...)
+	cmd.Dir = e.workingDir
+	for _, kv := range append(os.Environ(), e.env...) {
+		if !strings.HasPrefix(kv, "TF_WORKSPACE=") {
+			cmd.Env = append(cmd.Env, kv)
+		}
+	}
+	output, err := cmd.CombinedOutput()
+	return string(output), err
+}
+
+// commandEnv returns the environment for a terraform command
+func (e *Executor) commandEnv() []string {
+	env := append(os.Environ(), e.env...)
+	if ws := e.workspace; ws != nil {
+		ws.mu.Lock()
+		if ws.created {
+			env = append(env, "TF_WORKSPACE="+ws.name)
+		}
+		ws.mu.Unlock()
+	}
+	return env
+}
+
+// prepare creates the isolated workspace if the given arguments need it and
+// returns the environment to run them with
+func (e *Executor) prepare(ctx context.Context, args []string) ([]string, error) {
+	if len(args) > 0 && !noWorkspaceCommands[args[0]] {
+		if err := e.ensureWorkspace(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return e.commandEnv(), nil
+}
+
+// StateWithContext reads terraform state from the executor's workspace
+func (e *Executor) StateWithContext(ctx context.Context) (*State, error) {
+	env, err := e.prepare(ctx, []string{"show"})
+	if err != nil {
+		return nil, err
+	}
+	return readState(ctx, e.workingDir, env)
+}
+
+// OutputsWithContext reads terraform outputs from the executor's workspace
+func (e *Executor) OutputsWithContext(ctx context.Context) (map[string]interface{}, error) {
+	env, err := e.prepare(ctx, []string{"output"})
+	if err != nil {
+		return nil, err
+	}
+	return readOutputs(ctx, e.workingDir, env)
+}
+
+// OutputDetailsWithContext reads terraform outputs, with their type and
+// sensitive flag, from the executor's workspace
+func (e *Executor) OutputDetailsWithContext(ctx context.Context) (map[string]Output, error) {
+	env, err := e.prepare(ctx, []string{"output"})
+	if err != nil {
+		return nil, err
+	}
+	return readOutputDetails(ctx, e.workingDir, env)
+}
@@ -0,0 +1,81 @@
+package terraform
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeTerraform puts a terraform script on PATH that logs every invocation
+// as "<TF_WORKSPACE>|<args>" and returns the log file path
+func fakeTerraform(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake terraform script requires a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "calls.log")
+	script := `#!/bin/sh
+echo "$TF_WORKSPACE|$*" >> "` + logFile + `"
+if [ "$1 $2" = "workspace show" ]; then echo default; fi
+`
+	if err := os.WriteFile(filepath.Join(dir, "terraform"), []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake terraform: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("TF_WORKSPACE", "")
+	return logFile
+}
+
+func TestWorkspaceLifecycle(t *testing.T) {
+	logFile := fakeTerraform(t)
+	e := &Executor{workingDir: t.TempDir()}
+	e.UseWorkspace("infratest-test")
+	ctx := context.Background()
+
+	if _, err := e.ExecuteWithContext(ctx, "terraform init"); err != nil {
+		t.Fatalf("init error: %v", err)
+	}
+	if _, err := e.ExecuteWithContext(ctx, "terraform apply -auto-approve"); err != nil {
+		t.Fatalf("apply error: %v", err)
+	}
+	step, err := e.With(map[string]interface{}{"name": "x"}, nil)
+	if err != nil {
+		t.Fatalf("With() error: %v", err)
+	}
+	if _, err := step.ExecuteWithContext(ctx, "terraform destroy -auto-approve"); err != nil {
+		t.Fatalf("destroy error: %v", err)
+	}
+	if err := e.DeleteWorkspace(ctx); err != nil {
+		t.Fatalf("DeleteWorkspace() error: %v", err)
+	}
+	if err := e.DeleteWorkspace(ctx); err != nil {
+		t.Fatalf("second DeleteWorkspace() error: %v", err)
+	}
+
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatalf("Failed to read log: %v", err)
+	}
+	// The generated var file has a random name
+	calls := regexp.MustCompile(`-var-file=\S+\.tfvars\.json`).ReplaceAllString(string(data), "-var-file=<vars>")
+	got := strings.Split(strings.TrimSpace(calls), "\n")
+	want := []string{
+		"|init",
+		"|workspace show",
+		"|workspace new infratest-test",
+		"infratest-test|apply -auto-approve",
+		"infratest-test|destroy -var-file=<vars> -auto-approve",
+		"|workspace select default",
+		"|workspace delete infratest-test",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("terraform calls =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -66,8 +66,10 @@ func PrintProgress(stepNum, totalSteps int, stepName, status string, duration st
 	fmt.Print(" [")
 	if status == "OK" {
 		Success.Print(status)
-	} else if status == "FAIL" {
+	} else if status == "FAIL" || status == "TIMEOUT" {
 		Failure.Print(status)
+	} else if status == "SKIP" {
+		Warning.Print(status)
 	} else {
 		fmt.Print(status)
 	}